@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+
+	"github.com/michaeltrip/external-dns-kubevirt/internal/controller"
+)
+
+// Versions of the externaldns.k8s.io API the DNSEndpoint CRD may be served at.
+const (
+	dnsEndpointV1alpha1 = "v1alpha1"
+	dnsEndpointV1beta1  = "v1beta1"
+)
+
+// checkAndMigrateScheme registers the DNSEndpoint types in s under one
+// externaldns.k8s.io version and returns it. A non-empty override is used
+// as is; otherwise v1beta1 is preferred when the cluster behind dc serves
+// DNSEndpoints at it, falling back to v1alpha1. The API server converts
+// between served versions, so DNSEndpoints written as v1alpha1 stay
+// readable and are updated in place after the switch.
+func checkAndMigrateScheme(s *runtime.Scheme, dc discovery.ServerResourcesInterface, override string) (string, error) {
+	version, err := dnsEndpointVersion(dc, override)
+	if err != nil {
+		return "", err
+	}
+	if err := controller.AddDNSEndpointToSchemeVersion(s, version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// dnsEndpointVersion picks the DNSEndpoint version for checkAndMigrateScheme.
+func dnsEndpointVersion(dc discovery.ServerResourcesInterface, override string) (string, error) {
+	switch override {
+	case dnsEndpointV1alpha1, dnsEndpointV1beta1:
+		return override, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported DNSEndpoint API version %q, want %s or %s",
+			override, dnsEndpointV1alpha1, dnsEndpointV1beta1)
+	}
+
+	resources, err := dc.ServerResourcesForGroupVersion(dnsEndpointCRD.group + "/" + dnsEndpointV1beta1)
+	if apierrors.IsNotFound(err) {
+		return dnsEndpointV1alpha1, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("discovering %s/%s: %w", dnsEndpointCRD.group, dnsEndpointV1beta1, err)
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == dnsEndpointCRD.resource {
+			return dnsEndpointV1beta1, nil
+		}
+	}
+	return dnsEndpointV1alpha1, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// newFakeDiscovery serves dnsendpoints at each of versions of externaldns.k8s.io.
+func newFakeDiscovery(versions ...string) *fakediscovery.FakeDiscovery {
+	dc := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	for _, v := range versions {
+		dc.Resources = append(dc.Resources, &metav1.APIResourceList{
+			GroupVersion: "externaldns.k8s.io/" + v,
+			APIResources: []metav1.APIResource{{Name: "dnsendpoints", Kind: "DNSEndpoint", Namespaced: true}},
+		})
+	}
+	return dc
+}
+
+func TestCheckAndMigrateScheme(t *testing.T) {
+	tests := []struct {
+		name     string
+		served   []string
+		override string
+		want     string
+		wantErr  bool
+	}{
+		{name: "v1alpha1 only", served: []string{"v1alpha1"}, want: "v1alpha1"},
+		{name: "both served prefers v1beta1", served: []string{"v1alpha1", "v1beta1"}, want: "v1beta1"},
+		{name: "v1beta1 only", served: []string{"v1beta1"}, want: "v1beta1"},
+		{name: "none served falls back to v1alpha1", want: "v1alpha1"},
+		{name: "override to v1alpha1", served: []string{"v1alpha1", "v1beta1"}, override: "v1alpha1", want: "v1alpha1"},
+		{name: "override to v1beta1", served: []string{"v1alpha1"}, override: "v1beta1", want: "v1beta1"},
+		{name: "unsupported override", served: []string{"v1alpha1"}, override: "v2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := runtime.NewScheme()
+			got, err := checkAndMigrateScheme(s, newFakeDiscovery(tt.served...), tt.override)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got version %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got version %q, want %q", got, tt.want)
+			}
+
+			gvks, _, err := s.ObjectKinds(&dnsendpointv1alpha1.DNSEndpoint{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := schema.GroupVersionKind{Group: "externaldns.k8s.io", Version: tt.want, Kind: "DNSEndpoint"}
+			if len(gvks) != 1 || gvks[0] != want {
+				t.Errorf("DNSEndpoint registered as %v, want only %v", gvks, want)
+			}
+		})
+	}
+}
+
+func TestCheckAndMigrateScheme_DiscoveryError(t *testing.T) {
+	dc := newFakeDiscovery("v1beta1")
+	dc.PrependReactor("get", "resource", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	if _, err := checkAndMigrateScheme(runtime.NewScheme(), dc, ""); err == nil {
+		t.Error("expected a discovery error to be returned instead of falling back to v1alpha1")
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/tools/record"
+)
+
+// eventBurst is the number of Events an object may emit before --event-rate-limit applies.
+const eventBurst = 25
+
+// newEventBroadcaster returns the broadcaster behind the manager's event
+// recorders. Its correlator aggregates repeated Events with the same reason on
+// an object, such as IPsNotYetAvailable while a VMI waits for an address, into
+// a single Event with a count, and drops an object's Events beyond qps per
+// second once eventBurst have been sent. Zero qps uses the client-go default
+// of one Event every five minutes.
+func newEventBroadcaster(qps float64) (record.EventBroadcaster, error) {
+	if qps < 0 {
+		return nil, fmt.Errorf("event rate limit must not be negative, got %v", qps)
+	}
+	return record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+		QPS:       float32(qps),
+		BurstSize: eventBurst,
+	}), nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// countingSink is a record.EventSink that counts the writes it receives.
+type countingSink struct {
+	mu               sync.Mutex
+	creates, patches int
+}
+
+func (s *countingSink) Create(e *corev1.Event) (*corev1.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.creates++
+	return e, nil
+}
+
+func (s *countingSink) Update(e *corev1.Event) (*corev1.Event, error) { return e, nil }
+
+func (s *countingSink) Patch(e *corev1.Event, _ []byte) (*corev1.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patches++
+	return e, nil
+}
+
+func (s *countingSink) writes() (creates, patches int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.creates, s.patches
+}
+
+func TestNewEventBroadcaster_AggregatesAndRateLimits(t *testing.T) {
+	b, err := newEventBroadcaster(0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Shutdown()
+	sink := &countingSink{}
+	b.StartRecordingToSink(sink)
+	recorder := b.NewRecorder(scheme, corev1.EventSource{Component: "test"})
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "vm", Namespace: "default", UID: "vm-uid"}}
+	for range 2 * eventBurst {
+		recorder.Event(pod, corev1.EventTypeWarning, "IPsNotYetAvailable", "no IP addresses yet")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		creates, patches := sink.writes()
+		if creates+patches >= eventBurst || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Give any events past the burst the chance to leak through.
+	time.Sleep(100 * time.Millisecond)
+
+	creates, patches := sink.writes()
+	if creates != 1 {
+		t.Errorf("expected repeated events to be aggregated into one Event, got %d creates", creates)
+	}
+	if creates+patches != eventBurst {
+		t.Errorf("expected %d writes before the rate limit applies, got %d", eventBurst, creates+patches)
+	}
+}
+
+func TestNewEventBroadcaster_RejectsNegativeRate(t *testing.T) {
+	if _, err := newEventBroadcaster(-1); err == nil {
+		t.Error("expected an error for a negative event rate limit")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// defaultLeaderElectionID is the lease name used when --leader-election-id is not set.
+const defaultLeaderElectionID = "external-dns-kubevirt-leader"
+
+// leaderElectionConfig holds the leader election flags.
+type leaderElectionConfig struct {
+	enabled   bool
+	id        string
+	namespace string
+}
+
+// bindFlags registers the leader election flags on fs.
+func (c *leaderElectionConfig) bindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.enabled, "leader-elect", false, "Enable leader election for controller manager.")
+	fs.StringVar(&c.id, "leader-election-id", defaultLeaderElectionID,
+		"Name of the leader election lease. Use distinct IDs to run independent controller instances in one cluster.")
+	fs.StringVar(&c.namespace, "leader-election-namespace", "",
+		"Namespace holding the leader election lease. Defaults to the controller's namespace.")
+}
+
+// apply copies the leader election settings into opts.
+func (c leaderElectionConfig) apply(opts *ctrl.Options) {
+	opts.LeaderElection = c.enabled
+	opts.LeaderElectionID = c.id
+	opts.LeaderElectionNamespace = c.namespace
+}
+
+// validate checks that the configured lease namespace exists. It is a no-op
+// when leader election is disabled or no namespace is set.
+func (c leaderElectionConfig) validate(ctx context.Context, cfg *rest.Config) error {
+	if !c.enabled || c.namespace == "" {
+		return nil
+	}
+	cs, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+	return checkNamespaceExists(ctx, cs, c.namespace)
+}
+
+// checkNamespaceExists returns an error if the namespace cannot be read.
+func checkNamespaceExists(ctx context.Context, cs kubernetes.Interface, namespace string) error {
+	if _, err := cs.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("leader election namespace %q: %w", namespace, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestLeaderElectionConfig_Defaults(t *testing.T) {
+	var c leaderElectionConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.bindFlags(fs)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var opts ctrl.Options
+	c.apply(&opts)
+	if opts.LeaderElection || opts.LeaderElectionID != defaultLeaderElectionID || opts.LeaderElectionNamespace != "" {
+		t.Errorf("unexpected defaults: election=%v id=%q namespace=%q",
+			opts.LeaderElection, opts.LeaderElectionID, opts.LeaderElectionNamespace)
+	}
+}
+
+func TestLeaderElectionConfig_Flags(t *testing.T) {
+	var c leaderElectionConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.bindFlags(fs)
+	err := fs.Parse([]string{"--leader-elect", "--leader-election-id=edk-blue", "--leader-election-namespace=infra"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var opts ctrl.Options
+	c.apply(&opts)
+	if !opts.LeaderElection || opts.LeaderElectionID != "edk-blue" || opts.LeaderElectionNamespace != "infra" {
+		t.Errorf("unexpected options: election=%v id=%q namespace=%q",
+			opts.LeaderElection, opts.LeaderElectionID, opts.LeaderElectionNamespace)
+	}
+}
+
+func TestCheckNamespaceExists(t *testing.T) {
+	cs := fake.NewClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "infra"}})
+
+	if err := checkNamespaceExists(context.Background(), cs, "infra"); err != nil {
+		t.Errorf("expected existing namespace to pass, got %v", err)
+	}
+	if err := checkNamespaceExists(context.Background(), cs, "missing"); err == nil {
+		t.Error("expected an error for a missing namespace")
+	}
+}
+
+func TestLeaderElectionConfig_ValidateSkipped(t *testing.T) {
+	// Neither config needs to reach the API server.
+	for _, c := range []leaderElectionConfig{
+		{enabled: false, namespace: "infra"},
+		{enabled: true},
+	} {
+		if err := c.validate(context.Background(), nil); err != nil {
+			t.Errorf("%+v: expected validation to be skipped, got %v", c, err)
+		}
+	}
+}
@@ -1,80 +1,602 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
 
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"github.com/michaeltrip/external-dns-kubevirt/internal/controller"
+	"github.com/michaeltrip/external-dns-kubevirt/internal/feature"
 )
 
+// Version is the controller version, set at build time with
+// -ldflags "-X main.Version=...". It is stamped on every managed DNSEndpoint.
+var Version = "dev"
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// maxConcurrentReconcilesWarnThreshold is the --max-concurrent-reconciles value above
+// which a warning is logged at startup.
+const maxConcurrentReconcilesWarnThreshold = 50
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(kubevirtv1.AddToScheme(scheme))
-	utilruntime.Must(controller.AddDNSEndpointToScheme(scheme))
+	// The DNSEndpoint types are registered in main, once the served
+	// externaldns.k8s.io version is known.
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == rbacSubcommand {
+		if err := runRBAC(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var probeAddr string
-	var leaderElect bool
+	var leaderElection leaderElectionConfig
+	var retry retryConfig
+	var eventRateLimit float64
+	var maxConcurrentReconciles int
+	var configMapName string
+	var publicIPsOnly bool
+	var ipv4Only bool
+	var ipv6Only bool
+	var ipSourcePriority string
+	var zoneAllowlist string
+	var zoneDenylist string
+	var webhookPort int
+	var webhookCertDir string
+	var watchNamespaces string
+	var allowedTargetNamespaces string
+	var resyncPeriod time.Duration
+	var templateBased bool
+	var splitHorizon bool
+	var rejectWildcards bool
+	var remoteKubeconfig string
+	var dnsEndpointAPIVersion string
+	var expandShortHostnames bool
+	var clusterDomain string
+	var endpointNameFormat string
+	var reconcileTimeout time.Duration
+	var shutdownGracePeriod time.Duration
+	var pprofAddr string
+	var enableVMIRSController bool
+	var noIPRetryInterval time.Duration
+	var noIPRetryMaxInterval time.Duration
+	var phaseRetryInterval time.Duration
+	var phaseWarningThreshold int
+	var debounceWindow time.Duration
+	var coalesceWindow time.Duration
+	var conflictRetryBase time.Duration
+	var dnsPropagationDelay time.Duration
+	var minTTL, maxTTL int
+	var finalizerName string
+	var previousFinalizerNames string
+	var cleanupOrphans bool
+	var auditLogPath string
+	var dryRun bool
+	var printVersion bool
+	var otlpEndpoint string
+	var disablePerVMIMetrics bool
+	var otlpInsecure bool
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&leaderElect, "leader-elect", false, "Enable leader election for controller manager.")
+	leaderElection.bindFlags(flag.CommandLine)
+	retry.bindFlags(flag.CommandLine)
+	flag.Float64Var(&eventRateLimit, "event-rate-limit", 0,
+		"Events per second each object may emit after a burst of 25; repeated events with the same reason are aggregated. "+
+			"0 uses the client-go default of one every five minutes.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Number of VMIs reconciled in parallel. Higher values increase API server pressure.")
+	flag.StringVar(&configMapName, "config-map", controller.DefaultConfigMapName,
+		"Name of the ConfigMap in the controller's namespace holding runtime settings.")
+	flag.BoolVar(&publicIPsOnly, "public-ips-only", false,
+		"Never publish RFC 1918, RFC 4193 (fc00::/7) or loopback addresses.")
+	flag.BoolVar(&ipv4Only, "ipv4-only", false, "Never publish AAAA records, even when IPv6 addresses are available.")
+	flag.BoolVar(&ipv6Only, "ipv6-only", false, "Never publish A records, even when IPv4 addresses are available.")
+	flag.StringVar(&ipSourcePriority, "ip-source-priority", strings.Join(controller.DefaultIPSourcePriority, ","),
+		"Comma-separated infoSource names, tried in order until one yields IP addresses.")
+	flag.StringVar(&zoneAllowlist, "zone-allowlist", "",
+		"Comma-separated DNS zones hostnames must belong to. Empty allows every zone.")
+	flag.StringVar(&zoneDenylist, "zone-denylist", "",
+		"Comma-separated DNS zones hostnames must not belong to.")
+	flag.IntVar(&webhookPort, "webhook-port", 0,
+		"Port for the hostname normalizing and validating admission webhooks (9443 in deploy/webhook.yaml). 0 disables the webhooks.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "",
+		"Directory holding tls.crt and tls.key for the webhook. Defaults to controller-runtime's temp directory.")
+	flag.DurationVar(&reconcileTimeout, "reconcile-timeout", 30*time.Second,
+		"Maximum duration of a single VMI reconcile; timed-out reconciles are retried. 0 disables the timeout.")
+	flag.DurationVar(&shutdownGracePeriod, "shutdown-grace-period", 10*time.Second,
+		"How long to wait for in-flight reconciles to finish on shutdown.")
+	flag.StringVar(&pprofAddr, "pprof-bind-address", "",
+		"Address to serve net/http/pprof profiles on. Empty disables profiling. Never expose it publicly.")
+	flag.BoolVar(&enableVMIRSController, "enable-vmirs-controller", false,
+		"Publish one DNSEndpoint per annotated VirtualMachineInstanceReplicaSet, targeting all ready replicas.")
+	flag.DurationVar(&noIPRetryInterval, "no-ip-retry-interval", 15*time.Second,
+		"Initial requeue delay for an annotated VMI that reports no IPs yet; doubles on each retry. 0 waits for the next watch event instead.")
+	flag.DurationVar(&noIPRetryMaxInterval, "no-ip-retry-max-interval", 5*time.Minute,
+		"Maximum requeue delay for an annotated VMI that reports no IPs yet.")
+	flag.DurationVar(&phaseRetryInterval, "phase-retry-interval", 10*time.Second,
+		"Requeue delay for an annotated VMI that is not Running yet. 0 waits for the next watch event instead.")
+	flag.IntVar(&phaseWarningThreshold, "phase-warning-threshold", 30,
+		"Emit a Warning event after this many consecutive reconciles find a VMI not Running. 0 disables the warning.")
+	flag.DurationVar(&conflictRetryBase, "conflict-retry-base", controller.DefaultConflictRetryBase,
+		"Requeue delay, with ±20% jitter, after a DNSEndpoint update fails with a resource-version conflict.")
+	flag.DurationVar(&debounceWindow, "debounce-window", 2*time.Second,
+		"Minimum time between two reconciles of the same VMI; rapid edits are collapsed into one DNSEndpoint write. 0 disables debouncing.")
+	flag.DurationVar(&coalesceWindow, "coalesce-window", 100*time.Millisecond,
+		"A reconcile starting sooner than this after the previous one of the same VMI completed waits for the rest; events arriving meanwhile are merged into one reconcile. 0 disables coalescing.")
+	flag.StringVar(&finalizerName, "finalizer-name", controller.DefaultFinalizerName,
+		"Finalizer added to annotated VMIs to delete their DNSEndpoints. Give controllers deployed side by side distinct names.")
+	flag.StringVar(&previousFinalizerNames, "previous-finalizer-names", "",
+		"Comma-separated --finalizer-name values this controller used before. VMIs carrying them are moved to the current "+
+			"finalizer, or cleaned up and released if deleted.")
+	flag.DurationVar(&dnsPropagationDelay, "dns-propagation-delay", 0,
+		"Hold back the deletion of a VMI for this long after its DNSEndpoint is removed, so clients stop resolving its name first. 0 disables the wait.")
+	flag.IntVar(&minTTL, "min-ttl", int(controller.DefaultMinTTL),
+		"Lowest TTL, in seconds, a record may get; lower TTL annotations are raised to it.")
+	flag.IntVar(&maxTTL, "max-ttl", int(controller.DefaultMaxTTL),
+		"Highest TTL, in seconds, a record may get; higher TTL annotations are lowered to it.")
+	flag.BoolVar(&cleanupOrphans, "cleanup-orphans", false,
+		"At startup, once the caches have synced and before any VMI is reconciled, delete DNSEndpoints in the watched namespaces whose owning VMI no longer exists.")
+	flag.StringVar(&auditLogPath, "audit-log-path", "",
+		"File to append a JSON line to for every DNS record created, updated or deleted; \"-\" writes to stdout. Empty disables the audit log.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"OTLP gRPC collector (host:port) to send reconcile traces to. Empty disables tracing.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false, "Connect to the --otlp-endpoint collector without TLS.")
+	flag.BoolVar(&disablePerVMIMetrics, "disable-per-vmi-metrics", false,
+		"Do not export externaldns_kubevirt_vmi_reconciles_total, whose cardinality grows with the number of VMIs. "+
+			"Recommended for clusters with more than about 1000 VMIs.")
+	flag.BoolVar(&templateBased, "template-based-hostnames", false,
+		"Reconcile a VMI whenever its labels change, for hostname templates that reference {{ .Labels }}.")
+	flag.BoolVar(&splitHorizon, "split-horizon", false,
+		"Publish the internal-hostname and external-hostname annotations as separate <vmi>-internal and <vmi>-external DNSEndpoints.")
+	flag.BoolVar(&rejectWildcards, "reject-wildcards", false,
+		"Skip wildcard hostnames such as *.example.com, for DNS providers that do not support wildcard records.")
+	flag.BoolVar(&expandShortHostnames, "expand-short-hostnames", false,
+		"Expand single-label hostnames such as myvm to myvm.<namespace>.svc.<cluster-domain>.")
+	flag.StringVar(&endpointNameFormat, "endpoint-name-format", controller.DefaultEndpointNameFormat,
+		"Go template for DNSEndpoint names, over the VMI's {{ .Name }} and {{ .Namespace }}, e.g. {{ .Namespace }}-{{ .Name }}.")
+	flag.StringVar(&clusterDomain, "cluster-domain", controller.DefaultClusterDomain,
+		"Cluster DNS domain used by --expand-short-hostnames.")
+	flag.StringVar(&remoteKubeconfig, "remote-kubeconfig", "",
+		"Kubeconfig of a remote cluster to publish DNSEndpoints to, for example the one External-DNS runs in. "+
+			"VMIs are still read from the local cluster. Empty publishes to the local cluster.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Compute DNSEndpoints as usual but only log the writes (DryRun/WouldCreate, DryRun/WouldUpdate, "+
+			"DryRun/WouldDelete) instead of sending them. VMIs get no finalizers or annotation changes either.")
+	flag.StringVar(&dnsEndpointAPIVersion, "dns-endpoint-api-version", "",
+		"externaldns.k8s.io version used for DNSEndpoints: v1alpha1 or v1beta1. "+
+			"Empty uses v1beta1 when the cluster serves it and v1alpha1 otherwise.")
+	flag.StringVar(&watchNamespaces, "namespace", "",
+		"Comma-separated namespaces to watch for VMIs. Empty watches all namespaces.")
+	flag.StringVar(&allowedTargetNamespaces, "allowed-target-namespaces", "",
+		"Comma-separated namespaces the target-namespace annotation may publish DNSEndpoints into. Empty allows every namespace.")
+	flag.DurationVar(&resyncPeriod, "resync-period", 0,
+		"Interval at which every VMI is re-listed and reconciled, repairing drifted DNSEndpoints. "+
+			"Short periods increase API server load. 0 keeps controller-runtime's default (about 10h).")
+	flag.DurationVar(&resyncPeriod, "informer-resync-period", 0, "Alias for --resync-period.")
+	flag.BoolVar(&printVersion, "version", false, "Print the version embedded at build time and exit.")
 
 	opts := zap.Options{Development: false}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
+	retry.parsed(flag.CommandLine)
+
+	if printVersion {
+		fmt.Println(Version)
+		return
+	}
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	setupLog.Info("starting external-dns-kubevirt", "version", Version)
+
+	if ipv4Only && ipv6Only {
+		setupLog.Error(fmt.Errorf("--ipv4-only and --ipv6-only are mutually exclusive"), "invalid flags")
+		os.Exit(1)
+	}
+
+	if maxConcurrentReconciles > maxConcurrentReconcilesWarnThreshold {
+		setupLog.Info("WARNING: high --max-concurrent-reconciles may overload the API server",
+			"maxConcurrentReconciles", maxConcurrentReconciles, "threshold", maxConcurrentReconcilesWarnThreshold)
+	}
+
+	features, featureWarnings := feature.FromEnv(os.Environ())
+	for _, warning := range featureWarnings {
+		setupLog.Info("WARNING: ignoring feature gate", "reason", warning.Error())
+	}
+	for _, name := range feature.Known() {
+		setupLog.Info("feature gate", "feature", name, "enabled", features.Enabled(name))
+	}
+	// Label changes only matter to hostname templates.
+	templateBased = templateBased && features.Enabled(feature.FeatureTemplateHostnames)
+
+	sourcePriority, err := controller.ParseIPSourcePriority(ipSourcePriority)
+	if err != nil {
+		setupLog.Error(err, "invalid --ip-source-priority")
+		os.Exit(1)
+	}
+	if !features.Enabled(feature.FeatureSRIOVExtraction) {
+		sriov := controller.SRIOVExtractor{}.Name()
+		sourcePriority = slices.DeleteFunc(sourcePriority, func(name string) bool { return name == sriov })
+		if len(sourcePriority) == 0 {
+			setupLog.Error(nil, "--ip-source-priority lists only sriov, but FEATURE_SRIOV_EXTRACTION is false")
+			os.Exit(1)
+		}
+	}
+	for _, name := range sourcePriority {
+		if !controller.IsRegisteredIPExtractor(name) {
+			setupLog.Info("no dedicated extractor for infoSource, reading iface.IPs and iface.IP", "infoSource", name)
+		}
+	}
+
+	var endpointNameTemplate *template.Template
+	if endpointNameFormat != controller.DefaultEndpointNameFormat {
+		endpointNameTemplate, err = controller.ParseEndpointNameFormat(endpointNameFormat)
+		if err != nil {
+			setupLog.Error(err, "invalid --endpoint-name-format")
+			os.Exit(1)
+		}
+	}
+
+	if err := controller.ValidateFinalizerName(finalizerName); err != nil {
+		setupLog.Error(err, "invalid --finalizer-name")
+		os.Exit(1)
+	}
+	var previousFinalizers []string
+	for _, name := range strings.Split(previousFinalizerNames, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if err := controller.ValidateFinalizerName(name); err != nil {
+			setupLog.Error(err, "invalid --previous-finalizer-names")
+			os.Exit(1)
+		}
+		previousFinalizers = append(previousFinalizers, name)
+	}
+	if err := controller.ValidateTTLBounds(dnsendpointv1alpha1.TTL(minTTL), dnsendpointv1alpha1.TTL(maxTTL)); err != nil {
+		setupLog.Error(err, "invalid --min-ttl or --max-ttl")
+		os.Exit(1)
+	}
+
+	endpointVersion := Version
+	if errs := validation.IsValidLabelValue(endpointVersion); len(errs) > 0 {
+		setupLog.Info("version is not a valid label value, not labelling DNSEndpoints with it", "version", Version, "reason", strings.Join(errs, "; "))
+		endpointVersion = ""
+	}
 
 	restConfig := ctrl.GetConfigOrDie()
 
-	if err := checkRequiredCRDs(restConfig); err != nil {
+	// With --remote-kubeconfig, DNSEndpoints live in the remote cluster.
+	dnsEndpointConfig := restConfig
+	var remoteConfig *rest.Config
+	if remoteKubeconfig != "" {
+		remoteConfig, err = loadRemoteConfig(remoteKubeconfig)
+		if err != nil {
+			setupLog.Error(err, "unable to load the remote kubeconfig", "kubeconfig", remoteKubeconfig)
+			os.Exit(1)
+		}
+		dnsEndpointConfig = remoteConfig
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(dnsEndpointConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to create a discovery client")
+		os.Exit(1)
+	}
+	dnsEndpointCRD.version, err = checkAndMigrateScheme(scheme, dc, dnsEndpointAPIVersion)
+	if err != nil {
+		setupLog.Error(err, "unable to select the DNSEndpoint API version")
+		os.Exit(1)
+	}
+	setupLog.Info("using DNSEndpoint API version", "version", dnsEndpointCRD.version)
+
+	localCRDs := []crdRequirement{vmiCRD, dnsEndpointCRD}
+	if remoteKubeconfig != "" {
+		// DNSEndpoints are only needed in the remote cluster.
+		localCRDs = []crdRequirement{vmiCRD}
+		if enableVMIRSController {
+			setupLog.Error(nil, "--enable-vmirs-controller cannot be combined with --remote-kubeconfig")
+			os.Exit(1)
+		}
+	}
+	if err := checkRequiredCRDs(restConfig, localCRDs...); err != nil {
 		setupLog.Error(err, "required CRDs not found — install KubeVirt and External-DNS before starting")
 		os.Exit(1)
 	}
 
-	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+	namespace, err := controllerNamespace()
+	if err != nil {
+		setupLog.Error(err, "unable to determine the controller namespace")
+		os.Exit(1)
+	}
+
+	var syncPeriod *time.Duration
+	if resyncPeriod > 0 {
+		syncPeriod = &resyncPeriod
+		setupLog.Info("informer resync enabled, every VMI is reconciled periodically", "period", resyncPeriod)
+	} else {
+		setupLog.Info("informer resync period not set, using controller-runtime's default")
+	}
+
+	if err := leaderElection.validate(context.Background(), restConfig); err != nil {
+		setupLog.Error(err, "invalid --leader-election-namespace")
+		os.Exit(1)
+	}
+
+	eventBroadcaster, err := newEventBroadcaster(eventRateLimit)
+	if err != nil {
+		setupLog.Error(err, "invalid --event-rate-limit")
+		os.Exit(1)
+	}
+
+	mgrOptions := ctrl.Options{
 		Scheme: scheme,
+		// The manager does not shut a broadcaster it was given down; it lives as
+		// long as the process.
+		EventBroadcaster: eventBroadcaster,
 		Metrics: metricsserver.Options{
 			BindAddress: metricsAddr,
 		},
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         leaderElect,
-		LeaderElectionID:       "external-dns-kubevirt-leader",
-	})
+		HealthProbeBindAddress:  probeAddr,
+		GracefulShutdownTimeout: &shutdownGracePeriod,
+		Cache: cache.Options{
+			SyncPeriod:        syncPeriod,
+			DefaultNamespaces: cacheNamespaces(watchNamespaces),
+			ByObject: map[client.Object]cache.ByObject{
+				// Only cache the config ConfigMaps, not every ConfigMap in the cluster:
+				// the global one in the controller's namespace (even when that is not
+				// watched) and the per-namespace overrides in every watched namespace.
+				&corev1.ConfigMap{}: {
+					Namespaces: configMapNamespaces(namespace, watchNamespaces),
+					Field:      fields.OneTermEqualSelector("metadata.name", configMapName),
+				},
+			},
+		},
+	}
+	leaderElection.apply(&mgrOptions)
+	mgr, err := ctrl.NewManager(restConfig, mgrOptions)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	// With --remote-kubeconfig, DNSEndpoints are read and written through
+	// remoteClient, backed by remoteCache, instead of the manager's client.
+	var remoteClient client.Client
+	var remoteCache cache.Cache
+	if remoteKubeconfig != "" {
+		remote, err := newRemoteCluster(remoteConfig, cacheNamespaces(watchNamespaces))
+		if err != nil {
+			setupLog.Error(err, "unable to connect to the remote cluster", "kubeconfig", remoteKubeconfig)
+			os.Exit(1)
+		}
+		if err := mgr.Add(remote); err != nil {
+			setupLog.Error(err, "unable to set up the remote cluster")
+			os.Exit(1)
+		}
+		checker, err := remoteClusterChecker(remoteConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to set up the remote cluster ready check")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("remote-cluster", checker); err != nil {
+			setupLog.Error(err, "unable to set up the remote cluster ready check")
+			os.Exit(1)
+		}
+		remoteClient = remote.GetClient()
+		remoteCache = remote.GetCache()
+		setupLog.Info("publishing DNSEndpoints to a remote cluster", "host", remoteConfig.Host)
+	}
+
+	auditLog, err := controller.OpenAuditLog(auditLogPath)
+	if err != nil {
+		setupLog.Error(err, "unable to open audit log", "path", auditLogPath)
+		os.Exit(1)
+	}
+
+	var tracerProvider *sdktrace.TracerProvider
+	if otlpEndpoint != "" {
+		tracerProvider, err = newTracerProvider(context.Background(), otlpEndpoint, otlpInsecure, Version)
+		if err != nil {
+			setupLog.Error(err, "unable to set up OTLP tracing", "endpoint", otlpEndpoint)
+			os.Exit(1)
+		}
+		setupLog.Info("tracing enabled", "endpoint", otlpEndpoint)
+	}
+
+	// With --dry-run, the controllers read as usual but only log their writes.
+	writeClient := mgr.GetClient()
+	if dryRun {
+		writeClient = controller.NewDryRunClient(writeClient)
+		if remoteClient != nil {
+			remoteClient = controller.NewDryRunClient(remoteClient)
+		}
+		setupLog.Info("dry-run mode: DNSEndpoint and VMI writes are logged, not sent")
+	}
+
+	inFlight := &controller.InFlightTracker{}
+	config := controller.NewControllerConfig(controller.DefaultSettings())
+	if err = (&controller.ConfigMapReconciler{
+		Client:    mgr.GetClient(),
+		Name:      configMapName,
+		Namespace: namespace,
+		Defaults:  controller.DefaultSettings(),
+		Config:    config,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ConfigMap")
+		os.Exit(1)
+	}
+
+	var namespaceConfig *controller.NamespaceConfigCache
+	if features.Enabled(feature.FeaturePerNamespaceConfig) {
+		namespaceConfig = controller.NewNamespaceConfigCache(configMapName)
+		if err = (&controller.NamespaceConfigMapReconciler{
+			Cache: namespaceConfig,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "NamespaceConfigMap")
+			os.Exit(1)
+		}
+	}
+
+	// With --cleanup-orphans, the VMI controller holds back its reconciles
+	// until the cleanup has finished.
+	var orphanCleanupDone chan struct{}
+	if cleanupOrphans {
+		orphanCleanupDone = make(chan struct{})
+	}
 	if err = (&controller.VirtualMachineInstanceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                  writeClient,
+		Scheme:                  mgr.GetScheme(),
+		Recorder:                mgr.GetEventRecorderFor("external-dns-kubevirt"),
+		RateLimiter:             retry.rateLimiter(),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		Config:                  config,
+		PublicIPsOnly:           publicIPsOnly,
+		IPv4Only:                ipv4Only,
+		IPv6Only:                ipv6Only,
+		IPSourcePriority:        sourcePriority,
+		ZoneAllowlist:           controller.ParseZones(zoneAllowlist),
+		AllowedTargetNamespaces: watchedNamespaceList(allowedTargetNamespaces),
+		ZoneDenylist:            controller.ParseZones(zoneDenylist),
+		ReconcileTimeout:        reconcileTimeout,
+		InFlight:                inFlight,
+		StartAfter:              orphanCleanupDone,
+		NamespaceConfig:         namespaceConfig,
+		NoIPRetryInterval:       noIPRetryInterval,
+		NoIPRetryMaxInterval:    noIPRetryMaxInterval,
+		PhaseRetryInterval:      phaseRetryInterval,
+		PhaseWarningThreshold:   phaseWarningThreshold,
+		Backoff:                 retry.errorBackoff,
+		ConflictRetryBase:       conflictRetryBase,
+		DNSPropagationDelay:     dnsPropagationDelay,
+		MinTTL:                  dnsendpointv1alpha1.TTL(minTTL),
+		MaxTTL:                  dnsendpointv1alpha1.TTL(maxTTL),
+		FinalizerName:           finalizerName,
+		PreviousFinalizerNames:  previousFinalizers,
+		DebounceWindow:          debounceWindow,
+		CoalesceWindow:          coalesceWindow,
+		Version:                 endpointVersion,
+		Audit:                   auditLog,
+		DryRun:                  dryRun,
+		DisablePerVMIMetrics:    disablePerVMIMetrics,
+		TemplateBased:           templateBased,
+		Features:                features,
+		SplitHorizon:            splitHorizon,
+		RejectWildcards:         rejectWildcards,
+		ExpandShortHostnames:    expandShortHostnames,
+		ClusterDomain:           strings.TrimSuffix(clusterDomain, "."),
+		EndpointNameFormat:      endpointNameTemplate,
+		EndpointClient:          remoteClient,
+		EndpointCache:           remoteCache,
+		TracerProvider:          tracerProviderOrNil(tracerProvider),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VirtualMachineInstance")
 		os.Exit(1)
 	}
 
+	if enableVMIRSController {
+		if err = (&controller.VirtualMachineInstanceReplicaSetReconciler{
+			Client:               writeClient,
+			Scheme:               mgr.GetScheme(),
+			Recorder:             mgr.GetEventRecorderFor("external-dns-kubevirt"),
+			Config:               config,
+			PublicIPsOnly:        publicIPsOnly,
+			IPSourcePriority:     sourcePriority,
+			IPv4Only:             ipv4Only,
+			IPv6Only:             ipv6Only,
+			ZoneAllowlist:        controller.ParseZones(zoneAllowlist),
+			ZoneDenylist:         controller.ParseZones(zoneDenylist),
+			MinTTL:               dnsendpointv1alpha1.TTL(minTTL),
+			MaxTTL:               dnsendpointv1alpha1.TTL(maxTTL),
+			RejectWildcards:      rejectWildcards,
+			ExpandShortHostnames: expandShortHostnames,
+			ClusterDomain:        strings.TrimSuffix(clusterDomain, "."),
+			DryRun:               dryRun,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "VirtualMachineInstanceReplicaSet")
+			os.Exit(1)
+		}
+	}
+
+	endpointReader := client.Reader(mgr.GetClient())
+	if remoteClient != nil {
+		endpointReader = remoteClient
+	}
+	if err := mgr.Add(&controller.ManagedEndpointCounter{
+		Reader:   endpointReader,
+		Interval: time.Minute,
+	}); err != nil {
+		setupLog.Error(err, "unable to set up managed endpoint counter")
+		os.Exit(1)
+	}
+
+	if pprofAddr != "" {
+		setupLog.Info("WARNING: pprof endpoints expose process internals and must not be reachable publicly", "address", pprofAddr)
+		pprof, err := newPprofServer(pprofAddr)
+		if err != nil {
+			setupLog.Error(err, "unable to start pprof server")
+			os.Exit(1)
+		}
+		if err := mgr.Add(pprof); err != nil {
+			setupLog.Error(err, "unable to set up pprof server")
+			os.Exit(1)
+		}
+	}
+
+	if webhookPort != 0 {
+		webhookServer := webhook.NewServer(webhook.Options{Port: webhookPort, CertDir: webhookCertDir})
+		webhookServer.Register(controller.HostnameWebhookPath, &webhook.Admission{
+			Handler: controller.NewHostnameNormalizer(mgr.GetScheme(), config),
+		})
+		validator := controller.NewHostnameValidator(mgr.GetScheme(), config, controller.ParseZones(zoneDenylist))
+		validator.Features = features
+		webhookServer.Register(controller.HostnameValidatorWebhookPath, &webhook.Admission{Handler: validator})
+		if err := mgr.Add(webhookServer); err != nil {
+			setupLog.Error(err, "unable to set up webhook server")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("webhook", webhookServer.StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up webhook ready check")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -84,13 +606,110 @@ func main() {
 		os.Exit(1)
 	}
 
+	if cleanupOrphans {
+		// Run only on the leader, once the caches have synced so orphans are
+		// found through the DNSEndpoint owner UID index, and release the VMI
+		// controller when done so no reconcile races the cleanup.
+		endpoints := writeClient
+		if remoteClient != nil {
+			endpoints = remoteClient
+		}
+		cleanup := manager.RunnableFunc(func(ctx context.Context) error {
+			ctx = ctrl.LoggerInto(ctx, setupLog)
+			if !mgr.GetCache().WaitForCacheSync(ctx) || (remoteCache != nil && !remoteCache.WaitForCacheSync(ctx)) {
+				return fmt.Errorf("waiting for caches before the orphaned DNSEndpoint cleanup: %w", ctx.Err())
+			}
+			deleted, err := controller.CleanupOrphanedEndpoints(ctx, mgr.GetClient(), mgr.GetAPIReader(), endpoints, watchedNamespaceList(watchNamespaces))
+			if err != nil {
+				return fmt.Errorf("cleaning up orphaned DNSEndpoints: %w", err)
+			}
+			setupLog.Info("orphaned DNSEndpoint cleanup finished", "deleted", deleted)
+			close(orphanCleanupDone)
+			return nil
+		})
+		if err := mgr.Add(cleanup); err != nil {
+			setupLog.Error(err, "unable to set up orphaned DNSEndpoint cleanup")
+			os.Exit(1)
+		}
+	}
+
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	if remaining := inFlight.Wait(shutdownGracePeriod); len(remaining) > 0 {
+		setupLog.Info("shutdown grace period expired with reconciles still running", "vmis", remaining)
+	}
+	if tracerProvider != nil {
+		// Flush the spans still queued in the batcher.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+		if shutdownErr := tracerProvider.Shutdown(shutdownCtx); shutdownErr != nil {
+			setupLog.Error(shutdownErr, "failed to flush traces")
+		}
+		cancel()
+	}
+	if closeErr := auditLog.Close(); closeErr != nil {
+		setupLog.Error(closeErr, "failed to close audit log")
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
 
+// configMapNamespaces returns the namespaces whose config ConfigMap is cached:
+// the controller's own namespace plus every watched namespace, or all
+// namespaces when --namespace is empty.
+func configMapNamespaces(controllerNamespace, watchNamespaces string) map[string]cache.Config {
+	namespaces := cacheNamespaces(watchNamespaces)
+	if namespaces == nil {
+		return map[string]cache.Config{cache.AllNamespaces: {}}
+	}
+	namespaces[controllerNamespace] = cache.Config{}
+	return namespaces
+}
+
+// cacheNamespaces turns the comma-separated --namespace value into the cache's
+// DefaultNamespaces. It returns nil, meaning all namespaces, when raw is empty.
+func cacheNamespaces(raw string) map[string]cache.Config {
+	var namespaces map[string]cache.Config
+	for _, ns := range strings.Split(raw, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" {
+			continue
+		}
+		if namespaces == nil {
+			namespaces = map[string]cache.Config{}
+		}
+		namespaces[ns] = cache.Config{}
+	}
+	return namespaces
+}
+
+// watchedNamespaceList returns the namespaces named by --namespace, or nil,
+// meaning all namespaces, when raw is empty.
+func watchedNamespaceList(raw string) []string {
+	namespaces := cacheNamespaces(raw)
+	if namespaces == nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(namespaces))
+}
+
+// serviceAccountNamespaceFile holds the pod's namespace when running in-cluster.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// controllerNamespace returns the namespace the controller runs in, taken from
+// the POD_NAMESPACE environment variable or the in-cluster service account.
+func controllerNamespace() (string, error) {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns, nil
+	}
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "", fmt.Errorf("POD_NAMESPACE is not set and %s is unreadable: %w", serviceAccountNamespaceFile, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 // crdRequirement describes a CRD that must be present before the controller starts.
 type crdRequirement struct {
 	group    string
@@ -98,22 +717,23 @@ type crdRequirement struct {
 	resource string
 }
 
-// requiredCRDs lists the API resources that must exist in the cluster.
-var requiredCRDs = []crdRequirement{
-	{group: "kubevirt.io", version: "v1", resource: "virtualmachineinstances"},
-	{group: "externaldns.k8s.io", version: "v1alpha1", resource: "dnsendpoints"},
-}
+// dnsEndpointCRD's version is replaced by checkAndMigrateScheme's choice at
+// startup.
+var (
+	vmiCRD         = crdRequirement{group: "kubevirt.io", version: "v1", resource: "virtualmachineinstances"}
+	dnsEndpointCRD = crdRequirement{group: "externaldns.k8s.io", version: dnsEndpointV1alpha1, resource: "dnsendpoints"}
+)
 
-// checkRequiredCRDs uses the discovery API to verify that all required CRDs are
-// registered in the cluster. It returns an error listing any missing resources.
-func checkRequiredCRDs(cfg *rest.Config) error {
+// checkRequiredCRDs uses the discovery API to verify that all of requirements
+// are registered in the cluster. It returns an error listing any missing resources.
+func checkRequiredCRDs(cfg *rest.Config, requirements ...crdRequirement) error {
 	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create discovery client: %w", err)
 	}
 
 	var missing []string
-	for _, req := range requiredCRDs {
+	for _, req := range requirements {
 		groupVersion := req.group + "/" + req.version
 		resourceList, err := dc.ServerResourcesForGroupVersion(groupVersion)
 		if err != nil {
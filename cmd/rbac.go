@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// rbacSubcommand is the first argument that prints RBAC manifests instead of
+// starting the controller.
+const rbacSubcommand = "rbac"
+
+const defaultRBACName = "external-dns-kubevirt"
+
+// workloadRules and configRules mirror the +kubebuilder:rbac markers in
+// internal/controller; TestRBACRulesCoverMarkers keeps them in sync.
+
+// workloadRules are the permissions on VMIs and DNSEndpoints the controller
+// needs in every namespace it watches.
+var workloadRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{"kubevirt.io"},
+		Resources: []string{"virtualmachineinstances"},
+		Verbs:     []string{"get", "list", "watch", "update", "patch"},
+	},
+	{
+		APIGroups: []string{"kubevirt.io"},
+		Resources: []string{"virtualmachineinstancemigrations"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+	{
+		// Only needed with --enable-vmirs-controller.
+		APIGroups: []string{"kubevirt.io"},
+		Resources: []string{"virtualmachineinstancereplicasets"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+	{
+		APIGroups: []string{"externaldns.k8s.io"},
+		Resources: []string{"dnsendpoints"},
+		Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+	},
+}
+
+// configRules cover the config ConfigMaps and events, needed in every watched
+// namespace and in the controller's own namespace.
+var configRules = []rbacv1.PolicyRule{
+	{
+		APIGroups: []string{""},
+		Resources: []string{"configmaps"},
+		Verbs:     []string{"get", "list", "watch"},
+	},
+	{
+		APIGroups: []string{""},
+		Resources: []string{"events"},
+		Verbs:     []string{"create", "patch"},
+	},
+}
+
+// leaderElectionRules cover the leader election lease.
+var leaderElectionRules = []rbacv1.PolicyRule{{
+	APIGroups: []string{"coordination.k8s.io"},
+	Resources: []string{"leases"},
+	Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+}}
+
+// namespaceRules cover the startup check that --leader-election-namespace exists.
+// Namespaces are cluster-scoped, so these always need a ClusterRole.
+var namespaceRules = []rbacv1.PolicyRule{{
+	APIGroups: []string{""},
+	Resources: []string{"namespaces"},
+	Verbs:     []string{"get"},
+}}
+
+// runRBAC implements the rbac subcommand: it writes the RBAC objects the
+// controller needs to out as a multi-document YAML stream for kubectl apply.
+// Without --namespace it prints a ClusterRole and ClusterRoleBinding. With
+// --namespace it prints a Role and RoleBinding per watched namespace, one for
+// the controller's own namespace, and a ClusterRole limited to namespaces.
+func runRBAC(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet(rbacSubcommand, flag.ContinueOnError)
+	fs.SetOutput(out)
+	name := fs.String("name", defaultRBACName, "Name of the generated roles and bindings.")
+	serviceAccount := fs.String("service-account", defaultRBACName, "Service account the controller runs as.")
+	controllerNS := fs.String("controller-namespace", defaultRBACName, "Namespace the controller runs in.")
+	watchNamespaces := fs.String("namespace", "",
+		"Comma-separated namespaces the controller watches (its --namespace). Empty generates cluster-wide roles.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	objs := rbacObjects(*name, *serviceAccount, *controllerNS, watchedNamespaceList(*watchNamespaces))
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rbacObjects returns the RBAC objects for a controller running as
+// serviceAccount in controllerNS and watching namespaces (nil for all).
+func rbacObjects(name, serviceAccount, controllerNS string, namespaces []string) []runtime.Object {
+	subject := rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: serviceAccount, Namespace: controllerNS}
+	if namespaces == nil {
+		return []runtime.Object{
+			clusterRole(name, slices.Concat(workloadRules, configRules, namespaceRules, leaderElectionRules)),
+			clusterRoleBinding(name, subject),
+		}
+	}
+
+	// The global config ConfigMap and the leader election lease live in the
+	// controller's namespace, whether or not it is watched.
+	controllerRules := slices.Concat(configRules, leaderElectionRules)
+	if slices.Contains(namespaces, controllerNS) {
+		controllerRules = slices.Concat(workloadRules, controllerRules)
+	}
+	objs := []runtime.Object{
+		clusterRole(name, namespaceRules),
+		clusterRoleBinding(name, subject),
+		role(name, controllerNS, controllerRules),
+		roleBinding(name, controllerNS, subject),
+	}
+	for _, ns := range namespaces {
+		if ns != controllerNS {
+			objs = append(objs, role(name, ns, slices.Concat(workloadRules, configRules)), roleBinding(name, ns, subject))
+		}
+	}
+	return objs
+}
+
+func clusterRole(name string, rules []rbacv1.PolicyRule) *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Rules:      rules,
+	}
+}
+
+func clusterRoleBinding(name string, subject rbacv1.Subject) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name},
+		Subjects:   []rbacv1.Subject{subject},
+	}
+}
+
+func role(name, namespace string, rules []rbacv1.PolicyRule) *rbacv1.Role {
+	return &rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Rules:      rules,
+	}
+}
+
+func roleBinding(name, namespace string, subject rbacv1.Subject) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+		Subjects:   []rbacv1.Subject{subject},
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+)
+
+// decodeRBAC runs the rbac subcommand with args and decodes its output.
+func decodeRBAC(t *testing.T, args ...string) []runtime.Object {
+	t.Helper()
+	var out bytes.Buffer
+	if err := runRBAC(args, &out); err != nil {
+		t.Fatal(err)
+	}
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	var objs []runtime.Object
+	for _, doc := range strings.Split(out.String(), "---\n") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		obj, _, err := decoder.Decode([]byte(doc), nil, nil)
+		if err != nil {
+			t.Fatalf("invalid manifest %q: %v", doc, err)
+		}
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
+// kinds returns "Kind namespace/name" for every object.
+func kinds(objs []runtime.Object) []string {
+	var got []string
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *rbacv1.ClusterRole:
+			got = append(got, "ClusterRole "+o.Name)
+		case *rbacv1.ClusterRoleBinding:
+			got = append(got, "ClusterRoleBinding "+o.Name)
+		case *rbacv1.Role:
+			got = append(got, "Role "+o.Namespace+"/"+o.Name)
+		case *rbacv1.RoleBinding:
+			got = append(got, "RoleBinding "+o.Namespace+"/"+o.Name)
+		}
+	}
+	return got
+}
+
+func TestRunRBAC_ClusterWide(t *testing.T) {
+	objs := decodeRBAC(t)
+	want := []string{"ClusterRole external-dns-kubevirt", "ClusterRoleBinding external-dns-kubevirt"}
+	if got := kinds(objs); !slices.Equal(got, want) {
+		t.Fatalf("objects = %v, want %v", got, want)
+	}
+	binding := objs[1].(*rbacv1.ClusterRoleBinding)
+	if s := binding.Subjects[0]; s.Kind != rbacv1.ServiceAccountKind || s.Name != "external-dns-kubevirt" || s.Namespace != "external-dns-kubevirt" {
+		t.Errorf("unexpected subject %+v", s)
+	}
+}
+
+func TestRunRBAC_Namespaced(t *testing.T) {
+	objs := decodeRBAC(t, "--namespace=team-a,team-b", "--controller-namespace=dns", "--name=edk")
+	want := []string{
+		"ClusterRole edk", "ClusterRoleBinding edk",
+		"Role dns/edk", "RoleBinding dns/edk",
+		"Role team-a/edk", "RoleBinding team-a/edk",
+		"Role team-b/edk", "RoleBinding team-b/edk",
+	}
+	if got := kinds(objs); !slices.Equal(got, want) {
+		t.Fatalf("objects = %v, want %v", got, want)
+	}
+	if rules := objs[0].(*rbacv1.ClusterRole).Rules; !slices.EqualFunc(rules, namespaceRules, rulesEqual) {
+		t.Errorf("namespaced mode ClusterRole grants %v, want only %v", rules, namespaceRules)
+	}
+	if hasResource(objs[2].(*rbacv1.Role).Rules, "virtualmachineinstances") {
+		t.Error("the unwatched controller namespace must not grant VMI access")
+	}
+	if !hasResource(objs[4].(*rbacv1.Role).Rules, "dnsendpoints") {
+		t.Error("watched namespaces must grant DNSEndpoint access")
+	}
+}
+
+func TestRunRBAC_ControllerNamespaceWatched(t *testing.T) {
+	objs := decodeRBAC(t, "--namespace=dns", "--controller-namespace=dns")
+	if got := len(objs); got != 4 {
+		t.Fatalf("expected 4 objects, got %v", kinds(objs))
+	}
+	rules := objs[2].(*rbacv1.Role).Rules
+	if !hasResource(rules, "virtualmachineinstances") || !hasResource(rules, "leases") {
+		t.Errorf("expected the controller namespace role to merge workload and lease rules, got %v", rules)
+	}
+}
+
+func TestRunRBAC_RejectsArguments(t *testing.T) {
+	if err := runRBAC([]string{"extra"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for positional arguments")
+	}
+}
+
+func rulesEqual(a, b rbacv1.PolicyRule) bool {
+	return slices.Equal(a.APIGroups, b.APIGroups) && slices.Equal(a.Resources, b.Resources) && slices.Equal(a.Verbs, b.Verbs)
+}
+
+func hasResource(rules []rbacv1.PolicyRule, resource string) bool {
+	for _, r := range rules {
+		if slices.Contains(r.Resources, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+var rbacMarker = regexp.MustCompile(`// \+kubebuilder:rbac:groups=("[^"]*"|[^,]*),resources=([^,]*),verbs=(\S*)`)
+
+// TestRBACRulesCoverMarkers checks that every +kubebuilder:rbac marker in
+// internal/controller is granted by workloadRules or configRules.
+func TestRBACRulesCoverMarkers(t *testing.T) {
+	files, err := filepath.Glob("../internal/controller/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	granted := slices.Concat(workloadRules, configRules)
+	markers := 0
+	for _, f := range files {
+		src, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range rbacMarker.FindAllStringSubmatch(string(src), -1) {
+			markers++
+			group := strings.Trim(m[1], `"`)
+			for _, resource := range strings.Split(m[2], ";") {
+				for _, verb := range strings.Split(m[3], ";") {
+					if !grants(granted, group, resource, verb) {
+						t.Errorf("%s: %s %s.%s is not granted by the rbac subcommand", filepath.Base(f), verb, resource, group)
+					}
+				}
+			}
+		}
+	}
+	if markers == 0 {
+		t.Fatal("no +kubebuilder:rbac markers found")
+	}
+}
+
+func grants(rules []rbacv1.PolicyRule, group, resource, verb string) bool {
+	for _, r := range rules {
+		if slices.Contains(r.APIGroups, group) && slices.Contains(r.Resources, resource) && slices.Contains(r.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
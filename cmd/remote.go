@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// remoteCheckTimeout bounds the remote cluster readiness check.
+const remoteCheckTimeout = 5 * time.Second
+
+// loadRemoteConfig reads the kubeconfig of the cluster DNSEndpoints are
+// published to.
+func loadRemoteConfig(kubeconfig string) (*rest.Config, error) {
+	cfg, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("loading remote kubeconfig: %w", err)
+	}
+	return cfg, nil
+}
+
+// newRemoteCluster connects to the cluster at cfg, where DNSEndpoints are
+// published, after checking that its DNSEndpoint CRD is installed. Its cache
+// is limited to namespaces, like the manager's (nil for all).
+func newRemoteCluster(cfg *rest.Config, namespaces map[string]cache.Config) (cluster.Cluster, error) {
+	if err := checkRequiredCRDs(cfg, dnsEndpointCRD); err != nil {
+		return nil, fmt.Errorf("remote cluster: %w", err)
+	}
+	return cluster.New(cfg, func(o *cluster.Options) {
+		o.Scheme = scheme
+		o.Cache.DefaultNamespaces = namespaces
+	})
+}
+
+// remoteClusterChecker returns a readiness check that fails while the remote
+// cluster's API server cannot be reached. DNSEndpoint writes fail and are
+// retried with backoff in the meantime.
+func remoteClusterChecker(cfg *rest.Config) (healthz.Checker, error) {
+	cfg = rest.CopyConfig(cfg)
+	cfg.Timeout = remoteCheckTimeout
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return func(*http.Request) error {
+		if _, err := dc.ServerVersion(); err != nil {
+			return fmt.Errorf("remote cluster unreachable: %w", err)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestRemoteClusterChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"major":"1","minor":"32","gitVersion":"v1.32.1"}`))
+	}))
+	check, err := remoteClusterChecker(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := check(nil); err != nil {
+		t.Errorf("expected a reachable remote cluster to pass, got %v", err)
+	}
+
+	server.Close()
+	if err := check(nil); err == nil {
+		t.Error("expected an unreachable remote cluster to fail the check")
+	}
+}
+
+func TestLoadRemoteConfig_MissingKubeconfig(t *testing.T) {
+	if _, err := loadRemoteConfig(t.TempDir() + "/missing"); err == nil {
+		t.Error("expected an error for a missing kubeconfig")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/michaeltrip/external-dns-kubevirt/internal/controller"
+)
+
+// retryConfig holds the flags controlling how failed reconciles are retried.
+// Left at their defaults, retries go through controller-runtime's own rate
+// limiter, as they did before these flags existed.
+type retryConfig struct {
+	rateLimitBaseDelay time.Duration
+	rateLimitMaxDelay  time.Duration
+	rateLimitQPS       float64
+	rateLimitBurst     int
+	// rateLimitSet records whether any --rate-limit-* flag was given.
+	rateLimitSet bool
+	errorBackoff controller.BackoffConfig
+}
+
+// bindFlags registers the rate limit and error backoff flags on fs.
+func (c *retryConfig) bindFlags(fs *flag.FlagSet) {
+	// The rate limit defaults match controller-runtime's built-in rate limiter.
+	fs.DurationVar(&c.rateLimitBaseDelay, "rate-limit-base-delay", 5*time.Millisecond, "Initial per-item retry delay after a failed reconcile.")
+	fs.DurationVar(&c.rateLimitMaxDelay, "rate-limit-max-delay", 1000*time.Second, "Maximum per-item retry delay after repeated failed reconciles.")
+	fs.Float64Var(&c.rateLimitQPS, "rate-limit-qps", 10, "Overall rate at which requeued items are processed, in items per second.")
+	fs.IntVar(&c.rateLimitBurst, "rate-limit-burst", 100, "Burst size of the overall requeue rate limiter.")
+	fs.DurationVar(&c.errorBackoff.BaseDelay, "error-backoff-base-delay", 0,
+		"Requeue delay after a failed VMI reconcile; doubles with jitter on each consecutive failure. "+
+			"0 leaves retries to the --rate-limit-* rate limiter.")
+	fs.DurationVar(&c.errorBackoff.MaxDelay, "error-backoff-max-delay", 5*time.Minute,
+		"Maximum requeue delay after failed VMI reconciles.")
+	fs.IntVar(&c.errorBackoff.MaxAttempts, "error-backoff-max-attempts", 10,
+		"Consecutive failures retried with the error backoff before the rate limiter takes over. 0 means no limit.")
+}
+
+// parsed records which flags of fs were given. Call it after fs.Parse.
+func (c *retryConfig) parsed(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, "rate-limit-") {
+			c.rateLimitSet = true
+		}
+	})
+}
+
+// rateLimiter returns the rate limiter built from the --rate-limit-* flags,
+// or nil, meaning controller-runtime's default, when none was given.
+func (c retryConfig) rateLimiter() workqueue.TypedRateLimiter[reconcile.Request] {
+	if !c.rateLimitSet {
+		return nil
+	}
+	return controller.NewRateLimiter(c.rateLimitBaseDelay, c.rateLimitMaxDelay, c.rateLimitQPS, c.rateLimitBurst)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// parseRetryFlags binds a retryConfig to a fresh flag set and parses args.
+func parseRetryFlags(t *testing.T, args ...string) retryConfig {
+	t.Helper()
+	var c retryConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.bindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	c.parsed(fs)
+	return c
+}
+
+func TestRetryConfig_Defaults(t *testing.T) {
+	c := parseRetryFlags(t)
+	if rl := c.rateLimiter(); rl != nil {
+		t.Errorf("expected controller-runtime's default rate limiter without --rate-limit-* flags, got %T", rl)
+	}
+	if c.errorBackoff.BaseDelay != 0 {
+		t.Errorf("expected the error backoff to be off by default, got base delay %s", c.errorBackoff.BaseDelay)
+	}
+}
+
+func TestRetryConfig_Flags(t *testing.T) {
+	c := parseRetryFlags(t, "--rate-limit-base-delay=1s", "--error-backoff-base-delay=2s")
+	rl := c.rateLimiter()
+	if rl == nil {
+		t.Fatal("expected a rate limiter built from the --rate-limit-* flags")
+	}
+	if got := rl.When(reconcile.Request{}); got != time.Second {
+		t.Errorf("first retry delay = %s, want 1s", got)
+	}
+	if c.errorBackoff.BaseDelay != 2*time.Second {
+		t.Errorf("error backoff base delay = %s, want 2s", c.errorBackoff.BaseDelay)
+	}
+}
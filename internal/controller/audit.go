@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// Audit log operations.
+const (
+	auditOperationCreate = "create"
+	auditOperationUpdate = "update"
+	auditOperationDelete = "delete"
+)
+
+// AuditStdout is the --audit-log-path value that writes the audit log to stdout.
+const AuditStdout = "-"
+
+// auditEntry is one line of the audit log: a change to a single DNS record.
+type auditEntry struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Operation    string    `json:"operation"`
+	VMINamespace string    `json:"vmiNamespace"`
+	VMIName      string    `json:"vmiName"`
+	Hostname     string    `json:"hostname"`
+	RecordType   string    `json:"recordType"`
+	OldTargets   []string  `json:"oldTargets"`
+	NewTargets   []string  `json:"newTargets"`
+}
+
+// AuditLogger writes one JSON line per DNS record created, updated or deleted
+// on behalf of a VMI. A nil *AuditLogger is valid and discards everything.
+// It is safe for concurrent use.
+type AuditLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+	// file is the log file opened by OpenAuditLog, closed by Close.
+	file *os.File
+}
+
+// NewAuditLogger returns an AuditLogger writing to w.
+func NewAuditLogger(w io.Writer) *AuditLogger {
+	return &AuditLogger{w: w}
+}
+
+// OpenAuditLog opens the audit log at path: AuditStdout writes to stdout, any
+// other value appends to that file. An empty path disables auditing and
+// returns a nil logger.
+func OpenAuditLog(path string) (*AuditLogger, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case AuditStdout:
+		return NewAuditLogger(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLogger{w: f, file: f}, nil
+}
+
+// Close closes the log file opened by OpenAuditLog, if any.
+func (a *AuditLogger) Close() error {
+	if a == nil || a.file == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
+
+// recordChanges logs the records that differ between the endpoints of the
+// VMI's DNSEndpoint before and after a write. Records present only before are
+// logged as deletes, only after as creates, and with changed targets as
+// updates. Unchanged records are not logged.
+func (a *AuditLogger) recordChanges(now time.Time, vmi *kubevirtv1.VirtualMachineInstance, before, after []*dnsendpointv1alpha1.Endpoint) error {
+	if a == nil {
+		return nil
+	}
+	type recordKey struct{ hostname, recordType string }
+	oldTargets := map[recordKey][]string{}
+	for _, ep := range before {
+		oldTargets[recordKey{ep.DNSName, ep.RecordType}] = ep.Targets
+	}
+	newTargets := map[recordKey][]string{}
+	for _, ep := range after {
+		newTargets[recordKey{ep.DNSName, ep.RecordType}] = ep.Targets
+	}
+
+	var entries []auditEntry
+	add := func(op string, k recordKey, oldT, newT []string) {
+		entries = append(entries, auditEntry{
+			Timestamp:    now.UTC(),
+			Operation:    op,
+			VMINamespace: vmi.Namespace,
+			VMIName:      vmi.Name,
+			Hostname:     k.hostname,
+			RecordType:   k.recordType,
+			OldTargets:   nonNil(oldT),
+			NewTargets:   nonNil(newT),
+		})
+	}
+	for k, n := range newTargets {
+		o, existed := oldTargets[k]
+		switch {
+		case !existed:
+			add(auditOperationCreate, k, nil, n)
+		case !slices.Equal(o, n):
+			add(auditOperationUpdate, k, o, n)
+		}
+	}
+	for k, o := range oldTargets {
+		if _, kept := newTargets[k]; !kept {
+			add(auditOperationDelete, k, o, nil)
+		}
+	}
+	slices.SortFunc(entries, func(x, y auditEntry) int {
+		if c := cmp.Compare(x.Hostname, y.Hostname); c != 0 {
+			return c
+		}
+		return cmp.Compare(x.RecordType, y.RecordType)
+	})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	enc := json.NewEncoder(a.w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nonNil returns targets, or an empty slice when it is nil, so the audit log
+// shows [] rather than null.
+func nonNil(targets []string) []string {
+	if targets == nil {
+		return []string{}
+	}
+	return targets
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestAuditLogger_RecordChanges(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAuditLogger(&buf)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	before := []*dnsendpointv1alpha1.Endpoint{
+		{DNSName: "a.example.com", RecordType: "A", Targets: []string{"10.0.0.1"}},
+		{DNSName: "b.example.com", RecordType: "A", Targets: []string{"10.0.0.1"}},
+		{DNSName: "c.example.com", RecordType: "A", Targets: []string{"10.0.0.1"}},
+	}
+	after := []*dnsendpointv1alpha1.Endpoint{
+		{DNSName: "a.example.com", RecordType: "A", Targets: []string{"10.0.0.2"}},
+		{DNSName: "b.example.com", RecordType: "A", Targets: []string{"10.0.0.1"}},
+		{DNSName: "d.example.com", RecordType: "AAAA", Targets: []string{"2001:db8::1"}},
+	}
+
+	if err := a.recordChanges(now, newTestVMI("vm", nil), before, after); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`{"timestamp":"2026-10-16T12:00:00Z","operation":"update","vmiNamespace":"default","vmiName":"vm","hostname":"a.example.com","recordType":"A","oldTargets":["10.0.0.1"],"newTargets":["10.0.0.2"]}`,
+		`{"timestamp":"2026-10-16T12:00:00Z","operation":"delete","vmiNamespace":"default","vmiName":"vm","hostname":"c.example.com","recordType":"A","oldTargets":["10.0.0.1"],"newTargets":[]}`,
+		`{"timestamp":"2026-10-16T12:00:00Z","operation":"create","vmiNamespace":"default","vmiName":"vm","hostname":"d.example.com","recordType":"AAAA","oldTargets":[],"newTargets":["2001:db8::1"]}`,
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("audit log =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAuditLogger_Disabled(t *testing.T) {
+	a, err := OpenAuditLog("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != nil {
+		t.Fatalf("expected an empty path to disable the audit log, got %+v", a)
+	}
+	after := []*dnsendpointv1alpha1.Endpoint{{DNSName: "vm.example.com", RecordType: "A", Targets: []string{"10.0.0.1"}}}
+	if err := a.recordChanges(time.Now(), newTestVMI("vm", nil), nil, after); err != nil {
+		t.Errorf("disabled audit log returned %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Errorf("Close on a disabled audit log returned %v", err)
+	}
+}
+
+func TestOpenAuditLog_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	a, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := []*dnsendpointv1alpha1.Endpoint{{DNSName: "vm.example.com", RecordType: "A", Targets: []string{"10.0.0.1"}}}
+	if err := a.recordChanges(time.Now(), newTestVMI("vm", nil), nil, after); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "\n"); got != 1 {
+		t.Errorf("expected 1 audit line in the file, got %d", got)
+	}
+}
+
+func TestReconcile_AuditLog(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	var buf bytes.Buffer
+	r.Audit = NewAuditLogger(&buf)
+
+	reconcileVMI(t, r, "vm")
+	// Nothing changed, so nothing is logged.
+	r.now = func() time.Time { return time.Now().Add(time.Hour) }
+	reconcileVMI(t, r, "vm")
+	if err := r.deleteEndpointIfExists(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+
+	var ops []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e auditEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		if e.VMIName != "vm" || e.Hostname != "vm.example.com" || e.RecordType != "A" {
+			t.Errorf("unexpected audit entry %+v", e)
+		}
+		ops = append(ops, e.Operation)
+	}
+	if strings.Join(ops, ",") != "create,delete" {
+		t.Errorf("audit operations = %v, want [create delete]", ops)
+	}
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// backoffJitter is the largest random fraction added on top of each backoff delay,
+	// so VMIs failing together do not retry in lockstep.
+	backoffJitter = 0.1
+	// conflictJitter is the largest random fraction added to or taken from the
+	// conflict retry delay.
+	conflictJitter = 0.2
+	// DefaultConflictRetryBase is the requeue delay after a DNSEndpoint write
+	// hits a resource-version conflict, before jitter.
+	DefaultConflictRetryBase = 500 * time.Millisecond
+)
+
+// BackoffConfig configures the per-VMI requeue delay after a failed reconcile.
+// The zero value disables it, leaving retries to the controller's RateLimiter.
+type BackoffConfig struct {
+	// BaseDelay is the delay after the first failure; it doubles on every further one.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay, jitter included. Zero leaves it uncapped.
+	MaxDelay time.Duration
+	// MaxAttempts is the number of consecutive failures retried with this
+	// backoff. Later failures are returned to controller-runtime, whose rate
+	// limiter takes over. Zero means no limit.
+	MaxAttempts int
+}
+
+// delay returns BaseDelay * 2^attempts plus up to backoffJitter of random
+// jitter, capped at MaxDelay. jitter must return a value in [0, 1).
+func (c BackoffConfig) delay(attempts int, jitter func() float64) time.Duration {
+	d := c.BaseDelay
+	for i := 0; i < attempts; i++ {
+		if c.MaxDelay > 0 && d >= c.MaxDelay {
+			break
+		}
+		d *= 2
+	}
+	d += time.Duration(float64(d) * backoffJitter * jitter())
+	if c.MaxDelay > 0 && d > c.MaxDelay {
+		d = c.MaxDelay
+	}
+	return d
+}
+
+// backoffOnError turns a failed reconcile of key into a requeue after the
+// configured backoff delay, and resets the failure count on success. The error
+// is logged here, since controller-runtime ignores RequeueAfter when an error
+// is returned.
+func (r *VirtualMachineInstanceReconciler) backoffOnError(ctx context.Context, key types.NamespacedName, result ctrl.Result, err error) (ctrl.Result, error) {
+	if err == nil {
+		r.errorAttempts.Delete(key)
+		return result, nil
+	}
+	if r.Backoff.BaseDelay <= 0 {
+		return result, err
+	}
+	attempts := 0
+	if v, ok := r.errorAttempts.Load(key); ok {
+		attempts = v.(int)
+	}
+	r.errorAttempts.Store(key, attempts+1)
+	if r.Backoff.MaxAttempts > 0 && attempts >= r.Backoff.MaxAttempts {
+		return result, err
+	}
+	delay := r.Backoff.delay(attempts, rand.Float64)
+	log.FromContext(ctx).Error(err, "reconcile failed, retrying with backoff", "vmi", key, "attempt", attempts+1, "requeueAfter", delay)
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// jitter returns d shifted by up to ±conflictJitter of itself. random must
+// return a value in [0, 1).
+func jitter(d time.Duration, random func() float64) time.Duration {
+	return d + time.Duration(float64(d)*conflictJitter*(2*random()-1))
+}
+
+// conflictRetryAfter returns the jittered requeue delay after a DNSEndpoint
+// write lost a resource-version race.
+func (r *VirtualMachineInstanceReconciler) conflictRetryAfter() time.Duration {
+	base := r.ConflictRetryBase
+	if base <= 0 {
+		base = DefaultConflictRetryBase
+	}
+	return jitter(base, rand.Float64)
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestBackoffConfig_Delay(t *testing.T) {
+	c := BackoffConfig{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	noJitter := func() float64 { return 0 }
+	fullJitter := func() float64 { return 0.99 }
+
+	tests := []struct {
+		attempts int
+		jitter   func() float64
+		want     time.Duration
+	}{
+		{0, noJitter, time.Second},
+		{1, noJitter, 2 * time.Second},
+		{3, noJitter, 8 * time.Second},
+		{4, noJitter, 10 * time.Second},
+		{40, noJitter, 10 * time.Second},
+		{0, fullJitter, time.Second + 99*time.Millisecond},
+		{3, fullJitter, 8*time.Second + 792*time.Millisecond},
+		{4, fullJitter, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := c.delay(tt.attempts, tt.jitter); got != tt.want {
+			t.Errorf("delay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestReconcile_BackoffOnErrorAndReset(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	vmi.Finalizers = []string{DefaultFinalizerName}
+	failing := true
+	s := newTestScheme(t)
+	c := withHostnameIndex(fake.NewClientBuilder().WithScheme(s)).WithObjects(vmi).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if failing {
+				return errors.New("boom")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	r := &VirtualMachineInstanceReconciler{
+		Client:   c,
+		Scheme:   s,
+		Recorder: record.NewFakeRecorder(100),
+		Backoff:  BackoffConfig{BaseDelay: time.Second, MaxDelay: time.Minute, MaxAttempts: 3},
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "vm"}
+	req := ctrl.Request{NamespacedName: key}
+
+	for attempt, base := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		res, err := r.Reconcile(context.Background(), req)
+		if err != nil {
+			t.Fatalf("attempt %d: expected the error to be turned into a requeue, got %v", attempt+1, err)
+		}
+		if res.RequeueAfter < base || res.RequeueAfter > base+base/10 {
+			t.Errorf("attempt %d: RequeueAfter = %s, want %s plus up to 10%% jitter", attempt+1, res.RequeueAfter, base)
+		}
+	}
+
+	// Past MaxAttempts the error is handed back to controller-runtime.
+	if _, err := r.Reconcile(context.Background(), req); err == nil {
+		t.Error("expected the error to be returned after MaxAttempts")
+	}
+
+	failing = false
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.errorAttempts.Load(key); ok {
+		t.Error("expected the failure count to be reset after a successful reconcile")
+	}
+}
+
+func TestReconcile_NoBackoffByDefault(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	s := newTestScheme(t)
+	c := withHostnameIndex(fake.NewClientBuilder().WithScheme(s)).WithObjects(vmi).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			return errors.New("boom")
+		},
+	}).Build()
+	r := &VirtualMachineInstanceReconciler{Client: c, Scheme: s, Recorder: record.NewFakeRecorder(100)}
+
+	res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "vm"}})
+	if err == nil || res.RequeueAfter != 0 {
+		t.Errorf("expected the error to be left to the rate limiter, got result %+v and error %v", res, err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for _, tt := range []struct {
+		random float64
+		want   time.Duration
+	}{
+		{0, 400 * time.Millisecond},
+		{0.5, 500 * time.Millisecond},
+		{0.75, 550 * time.Millisecond},
+	} {
+		if got := jitter(500*time.Millisecond, func() float64 { return tt.random }); got != tt.want {
+			t.Errorf("jitter(500ms) with random %v = %s, want %s", tt.random, got, tt.want)
+		}
+	}
+}
+
+func TestReconcile_RequeuesOnEndpointConflict(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	vmi.Finalizers = []string{DefaultFinalizerName}
+	conflicting := false
+	s := newTestScheme(t)
+	c := withHostnameIndex(fake.NewClientBuilder().WithScheme(s)).WithObjects(vmi).WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if _, ok := obj.(*dnsendpointv1alpha1.DNSEndpoint); ok && conflicting {
+				return apierrors.NewConflict(schema.GroupResource{Group: "externaldns.k8s.io", Resource: "dnsendpoints"}, obj.GetName(), errors.New("object was modified"))
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+	r := &VirtualMachineInstanceReconciler{
+		Client:   c,
+		Scheme:   s,
+		Recorder: record.NewFakeRecorder(100),
+		Backoff:  BackoffConfig{BaseDelay: time.Minute},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "vm"}}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Get(context.Background(), req.NamespacedName, vmi); err != nil {
+		t.Fatal(err)
+	}
+	vmi.Status.Interfaces[0].IP = "10.0.0.2"
+	if err := c.Update(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+
+	conflicting = true
+	for _, tt := range []struct {
+		base     time.Duration
+		min, max time.Duration
+	}{
+		{0, 400 * time.Millisecond, 600 * time.Millisecond},
+		{2 * time.Second, 1600 * time.Millisecond, 2400 * time.Millisecond},
+	} {
+		r.ConflictRetryBase = tt.base
+		res, err := r.Reconcile(context.Background(), req)
+		if err != nil {
+			t.Fatalf("ConflictRetryBase %s: expected the conflict to be turned into a requeue, got %v", tt.base, err)
+		}
+		if res.RequeueAfter < tt.min || res.RequeueAfter > tt.max {
+			t.Errorf("ConflictRetryBase %s: RequeueAfter = %s, want between %s and %s", tt.base, res.RequeueAfter, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// DefaultConfigMapName is the ConfigMap watched for runtime settings when --config-map is not set.
+	DefaultConfigMapName = "external-dns-kubevirt-config"
+
+	// ConfigMap data keys.
+	configKeyDefaultTTL       = "defaultTTL"
+	configKeyAnnotationPrefix = "annotationPrefix"
+	configKeyIPFamily         = "ipFamily"
+
+	// Accepted values for ControllerSettings.IPFamily.
+	ipFamilyDual = "dual"
+	ipFamilyIPv4 = "ipv4"
+	ipFamilyIPv6 = "ipv6"
+)
+
+// ControllerSettings is a snapshot of the settings that can be changed at runtime.
+type ControllerSettings struct {
+	// DefaultTTL is used when a VMI has no valid TTL annotation.
+	DefaultTTL dnsendpointv1alpha1.TTL
+	// AnnotationPrefix replaces "external-dns.alpha.kubernetes.io/" in every annotation key the controller reads.
+	AnnotationPrefix string
+	// IPFamily limits published records to "ipv4", "ipv6" or "dual" (both).
+	IPFamily string
+}
+
+// DefaultSettings returns the settings used when no ConfigMap overrides them.
+func DefaultSettings() ControllerSettings {
+	return ControllerSettings{
+		DefaultTTL:       defaultTTL,
+		AnnotationPrefix: defaultAnnotationPrefix,
+		IPFamily:         ipFamilyDual,
+	}
+}
+
+// annotationKey maps one of the annotation constants onto the configured prefix.
+func (s ControllerSettings) annotationKey(key string) string {
+	if s.AnnotationPrefix == "" || s.AnnotationPrefix == defaultAnnotationPrefix {
+		return key
+	}
+	return s.AnnotationPrefix + strings.TrimPrefix(key, defaultAnnotationPrefix)
+}
+
+// filterIPFamily drops the addresses excluded by the configured IP family.
+func (s ControllerSettings) filterIPFamily(ipv4, ipv6 []string) ([]string, []string) {
+	switch s.IPFamily {
+	case ipFamilyIPv4:
+		return ipv4, nil
+	case ipFamilyIPv6:
+		return nil, ipv6
+	default:
+		return ipv4, ipv6
+	}
+}
+
+// ControllerConfig holds the current ControllerSettings and is safe for concurrent use.
+type ControllerConfig struct {
+	mu       sync.RWMutex
+	settings ControllerSettings
+}
+
+// NewControllerConfig returns a ControllerConfig initialised with the given settings.
+func NewControllerConfig(settings ControllerSettings) *ControllerConfig {
+	return &ControllerConfig{settings: settings}
+}
+
+// Get returns a copy of the current settings.
+func (c *ControllerConfig) Get() ControllerSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.settings
+}
+
+// Set replaces the current settings.
+func (c *ControllerConfig) Set(settings ControllerSettings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settings = settings
+}
+
+// ConfigMapReconciler keeps a ControllerConfig in sync with a single ConfigMap.
+type ConfigMapReconciler struct {
+	client.Client
+	// Name and Namespace identify the watched ConfigMap.
+	Name      string
+	Namespace string
+	// Defaults are applied for any key missing from the ConfigMap, or when it does not exist.
+	Defaults ControllerSettings
+	// Config receives the merged settings.
+	Config *ControllerConfig
+}
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// Reconcile reloads the settings from the ConfigMap.
+func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.Name, Namespace: r.Namespace}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("config ConfigMap not found, using defaults", "configmap", req.NamespacedName)
+			r.Config.Set(r.Defaults)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	settings := parseSettings(cm.Data, r.Defaults, func(key, value string) {
+		logger.Info("ignoring invalid config value", "configmap", req.NamespacedName, "key", key, "value", value)
+	})
+	r.Config.Set(settings)
+	logger.Info("loaded controller config", "configmap", req.NamespacedName, "defaultTTL", settings.DefaultTTL,
+		"annotationPrefix", settings.AnnotationPrefix, "ipFamily", settings.IPFamily)
+	return ctrl.Result{}, nil
+}
+
+// parseSettings merges ConfigMap data over defaults. invalid is called for every
+// value that cannot be parsed; that key keeps its default.
+func parseSettings(data map[string]string, defaults ControllerSettings, invalid func(key, value string)) ControllerSettings {
+	settings := defaults
+
+	if raw, ok := data[configKeyDefaultTTL]; ok {
+		v, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil || v <= 0 {
+			invalid(configKeyDefaultTTL, raw)
+		} else {
+			settings.DefaultTTL = dnsendpointv1alpha1.TTL(v)
+		}
+	}
+
+	if raw, ok := data[configKeyAnnotationPrefix]; ok {
+		prefix := strings.TrimSpace(raw)
+		if prefix == "" {
+			invalid(configKeyAnnotationPrefix, raw)
+		} else {
+			if !strings.HasSuffix(prefix, "/") {
+				prefix += "/"
+			}
+			settings.AnnotationPrefix = prefix
+		}
+	}
+
+	if raw, ok := data[configKeyIPFamily]; ok {
+		switch family := strings.ToLower(strings.TrimSpace(raw)); family {
+		case ipFamilyDual, ipFamilyIPv4, ipFamilyIPv6:
+			settings.IPFamily = family
+		default:
+			invalid(configKeyIPFamily, raw)
+		}
+	}
+
+	return settings
+}
+
+// SetupWithManager registers the ConfigMap controller with the manager.
+func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isConfigMap := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetName() == r.Name && obj.GetNamespace() == r.Namespace
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("configmap").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(isConfigMap)).
+		Complete(r)
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestParseSettings(t *testing.T) {
+	var invalid []string
+	got := parseSettings(map[string]string{
+		configKeyDefaultTTL:       "60",
+		configKeyAnnotationPrefix: "dns.example.com",
+		configKeyIPFamily:         "IPv4",
+	}, DefaultSettings(), func(key, _ string) { invalid = append(invalid, key) })
+
+	if got.DefaultTTL != 60 || got.AnnotationPrefix != "dns.example.com/" || got.IPFamily != ipFamilyIPv4 {
+		t.Errorf("unexpected settings: %+v", got)
+	}
+	if len(invalid) != 0 {
+		t.Errorf("expected no invalid keys, got %v", invalid)
+	}
+}
+
+func TestParseSettings_InvalidValuesKeepDefaults(t *testing.T) {
+	var invalid []string
+	got := parseSettings(map[string]string{
+		configKeyDefaultTTL: "-5",
+		configKeyIPFamily:   "ipx",
+	}, DefaultSettings(), func(key, _ string) { invalid = append(invalid, key) })
+
+	if got != DefaultSettings() {
+		t.Errorf("expected defaults, got %+v", got)
+	}
+	if len(invalid) != 2 {
+		t.Errorf("expected 2 invalid keys, got %v", invalid)
+	}
+}
+
+func TestConfigMapReconciler_PropagatesToVMIReconciler(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultConfigMapName, Namespace: "external-dns-kubevirt"},
+		Data: map[string]string{
+			configKeyDefaultTTL: "42",
+			configKeyIPFamily:   ipFamilyIPv6,
+		},
+	}
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{
+			IPs:        []string{"10.0.0.1", "2001:db8::1"},
+			InfoSource: guestAgentInfoSource,
+		})
+	r := newTestReconciler(t, cm, vmi)
+	config := NewControllerConfig(DefaultSettings())
+	r.Config = config
+
+	cmr := &ConfigMapReconciler{
+		Client:    r.Client,
+		Name:      DefaultConfigMapName,
+		Namespace: "external-dns-kubevirt",
+		Defaults:  DefaultSettings(),
+		Config:    config,
+	}
+	key := types.NamespacedName{Name: DefaultConfigMapName, Namespace: "external-dns-kubevirt"}
+	if _, err := cmr.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	reconcileVMI(t, r, "vm")
+	ep := getEndpoint(t, r, "vm")
+	if len(ep.Spec.Endpoints) != 1 || ep.Spec.Endpoints[0].RecordType != "AAAA" {
+		t.Fatalf("expected a single AAAA endpoint with ipFamily=ipv6, got %v", ep.Spec.Endpoints)
+	}
+	if ep.Spec.Endpoints[0].RecordTTL != 42 {
+		t.Errorf("expected TTL from ConfigMap (42), got %d", ep.Spec.Endpoints[0].RecordTTL)
+	}
+
+	// Deleting the ConfigMap falls back to the defaults.
+	if err := r.Delete(context.Background(), cm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmr.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Get(); got != DefaultSettings() {
+		t.Errorf("expected defaults after ConfigMap deletion, got %+v", got)
+	}
+}
+
+func TestReconcile_CustomAnnotationPrefix(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{"dns.example.com/hostname": "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	settings := DefaultSettings()
+	settings.AnnotationPrefix = "dns.example.com/"
+	r.Config = NewControllerConfig(settings)
+
+	reconcileVMI(t, r, "vm")
+	if ep := getEndpoint(t, r, "vm"); ep.Spec.Endpoints[0].DNSName != "vm.example.com" {
+		t.Errorf("unexpected endpoints: %v", ep.Spec.Endpoints)
+	}
+}
+
+func TestControllerConfig_ConcurrentAccess(t *testing.T) {
+	config := NewControllerConfig(DefaultSettings())
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(2)
+		go func(ttl dnsendpointv1alpha1.TTL) {
+			defer wg.Done()
+			s := DefaultSettings()
+			s.DefaultTTL = ttl
+			s.IPFamily = ipFamilyIPv4
+			config.Set(s)
+		}(dnsendpointv1alpha1.TTL(i))
+		go func() {
+			defer wg.Done()
+			got := config.Get()
+			// Every write changes DefaultTTL and IPFamily together, so a reader must
+			// never see one without the other.
+			if (got.DefaultTTL == defaultTTL) != (got.IPFamily == ipFamilyDual) {
+				t.Errorf("observed torn settings: %+v", got)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// eventReasonHostnameConflict is emitted on both VMIs when they claim the same hostname.
+	eventReasonHostnameConflict = "HostnameConflict"
+	// reasonHostnameConflict is the Ready condition reason while a conflict blocks publishing.
+	reasonHostnameConflict = "HostnameConflict"
+	// vmiHostnameIndex is the cache field index of the normalized hostnames a
+	// VMI's hostname annotation requests.
+	vmiHostnameIndex = "metadata.annotations.hostname"
+)
+
+// errHostnameConflict marks reconciles skipped because another VMI already
+// publishes one of the requested hostnames.
+var errHostnameConflict = errors.New("hostname claimed by another VirtualMachineInstance")
+
+// hostnameConflict describes a hostname already published by another VMI's DNSEndpoint.
+type hostnameConflict struct {
+	Hostname string
+	Endpoint types.NamespacedName
+	Owner    types.NamespacedName
+}
+
+// indexVMIHostnames is the vmiHostnameIndex indexer: it returns the
+// normalized hostnames the VMI's hostname annotation resolves to, with
+// templates rendered, affixes applied and short names expanded as in
+// Reconcile. Invalid entries are left out.
+func (r *VirtualMachineInstanceReconciler) indexVMIHostnames(obj client.Object) []string {
+	vmi, ok := obj.(*kubevirtv1.VirtualMachineInstance)
+	if !ok {
+		return nil
+	}
+	settings := r.cachedSettingsFor(vmi.Namespace)
+	hostnames, _ := renderHostnames(parseHostnames(vmi.Annotations[settings.annotationKey(annotationHostname)]), vmi, r.hostnameTemplates())
+	hostnames, _ = applyHostnameAffixes(hostnames,
+		strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationHostnamePrefix)]),
+		strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationHostnameSuffix)]))
+	if r.ExpandShortHostnames {
+		hostnames, _ = expandShortHostnames(hostnames, vmi.Namespace, r.clusterDomain())
+	}
+	keys := make([]string, 0, len(hostnames))
+	for _, h := range hostnames {
+		keys = append(keys, normalizeDNSName(h))
+	}
+	return keys
+}
+
+// findVMIHostnameConflict looks up, through vmiHostnameIndex, another VMI in
+// the same namespace whose annotations request any of hostnames and that has
+// the better claim to it. Hostnames the VMI already publishes in a
+// DNSEndpoint in namespace stay with it; otherwise the earlier created Running
+// VMI wins, so only the newcomer is blocked. VMIs that are not Running or are
+// being deleted hold no claim. As in findHostnameConflict, VMIs with a
+// different set identifier do not conflict. It returns nil when there is no
+// conflict.
+func (r *VirtualMachineInstanceReconciler) findVMIHostnameConflict(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, namespace string, hostnames []string, setIdentifier string) (*hostnameConflict, error) {
+	published, err := r.publishedHostnames(ctx, vmi, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hostnames {
+		if published[normalizeDNSName(h)] {
+			continue
+		}
+		list := &kubevirtv1.VirtualMachineInstanceList{}
+		if err := r.List(ctx, list, client.InNamespace(vmi.Namespace), client.MatchingFields{vmiHostnameIndex: normalizeDNSName(h)}); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			other := &list.Items[i]
+			if other.UID == vmi.UID || other.DeletionTimestamp != nil || other.Status.Phase != kubevirtv1.Running || !createdBefore(other, vmi) {
+				continue
+			}
+			settings := r.cachedSettingsFor(other.Namespace)
+			otherSetIdentifier, _ := parseSetIdentifier(other.Annotations, settings)
+			otherWeight, _ := parseWeight(other.Annotations[settings.annotationKey(annotationWeight)])
+			otherSetIdentifier = setIdentifierFor(other, otherSetIdentifier, otherWeight)
+			if setIdentifier != "" && otherSetIdentifier != "" && setIdentifier != otherSetIdentifier {
+				continue
+			}
+			return &hostnameConflict{Hostname: h, Owner: client.ObjectKeyFromObject(other)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// publishedHostnames returns the normalized hostnames of the DNSEndpoints in
+// namespace owned by vmi.
+func (r *VirtualMachineInstanceReconciler) publishedHostnames(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, namespace string) (map[string]bool, error) {
+	list := &dnsendpointv1alpha1.DNSEndpointList{}
+	if err := r.endpoints().List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	published := map[string]bool{}
+	for i := range list.Items {
+		if _, uid, ok := endpointOwner(&list.Items[i]); !ok || uid != vmi.UID {
+			continue
+		}
+		for _, e := range list.Items[i].Spec.Endpoints {
+			published[normalizeDNSName(e.DNSName)] = true
+		}
+	}
+	return published, nil
+}
+
+// createdBefore reports whether a was created before b. VMIs created in the
+// same second are ordered by name, so exactly one of them comes first.
+func createdBefore(a, b *kubevirtv1.VirtualMachineInstance) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// reportVMIHostnameConflict emits a Warning event on both VMIs of a conflict
+// found by findVMIHostnameConflict. It leaves any DNSEndpoint untouched.
+func (r *VirtualMachineInstanceReconciler) reportVMIHostnameConflict(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, conflict *hostnameConflict) {
+	logger := log.FromContext(ctx)
+	r.Recorder.Eventf(vmi, corev1.EventTypeWarning, eventReasonHostnameConflict,
+		"hostname %s is also requested by VirtualMachineInstance %s", conflict.Hostname, conflict.Owner.Name)
+
+	other := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(ctx, conflict.Owner, other); err != nil {
+		logger.Info("could not fetch conflicting VirtualMachineInstance", "vmi", conflict.Owner.String(), "error", err.Error())
+		return
+	}
+	r.Recorder.Eventf(other, corev1.EventTypeWarning, eventReasonHostnameConflict,
+		"hostname %s is also requested by VirtualMachineInstance %s", conflict.Hostname, vmi.Name)
+}
+
+// findHostnameConflict lists the DNSEndpoints in namespace, where the VMI's
+// own DNSEndpoint lives, and returns the first one owned by a different VMI
+// that publishes any of hostnames. Records with different set identifiers are
+// separate record sets under a routing policy and do not conflict, so when
+// setIdentifier is non-empty only records without one or with the same one
+// are considered. It returns nil when there is no conflict.
+func (r *VirtualMachineInstanceReconciler) findHostnameConflict(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, namespace string, hostnames []string, setIdentifier string) (*hostnameConflict, error) {
+	if len(hostnames) == 0 {
+		return nil, nil
+	}
+	wanted := make(map[string]bool, len(hostnames))
+	for _, h := range hostnames {
+		wanted[normalizeDNSName(h)] = true
+	}
+
+	list := &dnsendpointv1alpha1.DNSEndpointList{}
+	if err := r.endpoints().List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		ep := &list.Items[i]
+		owner, uid, ok := endpointOwner(ep)
+		if !ok || uid == vmi.UID {
+			continue
+		}
+		for _, e := range ep.Spec.Endpoints {
+			if setIdentifier != "" && e.SetIdentifier != "" && e.SetIdentifier != setIdentifier {
+				continue
+			}
+			if wanted[normalizeDNSName(e.DNSName)] {
+				return &hostnameConflict{Hostname: e.DNSName, Endpoint: client.ObjectKeyFromObject(ep), Owner: owner}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// reportHostnameConflict emits a Warning event on the VMI and on the VMI that
+// owns the conflicting DNSEndpoint, and marks the VMI's own DNSEndpoint (if
+// any) at key as not Ready.
+func (r *VirtualMachineInstanceReconciler) reportHostnameConflict(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, key client.ObjectKey, conflict *hostnameConflict) error {
+	logger := log.FromContext(ctx)
+	msg := fmt.Sprintf("hostname %s is already published by DNSEndpoint %s owned by VirtualMachineInstance %s", conflict.Hostname, conflict.Endpoint.Name, conflict.Owner.Name)
+	r.Recorder.Event(vmi, corev1.EventTypeWarning, eventReasonHostnameConflict, msg)
+
+	other := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(ctx, conflict.Owner, other); err != nil {
+		logger.Info("could not fetch conflicting VirtualMachineInstance", "vmi", conflict.Owner.String(), "error", err.Error())
+	} else {
+		r.Recorder.Eventf(other, corev1.EventTypeWarning, eventReasonHostnameConflict,
+			"hostname %s is also requested by VirtualMachineInstance %s", conflict.Hostname, vmi.Name)
+	}
+
+	cond := newCondition(vmi, conditionReady, metav1.ConditionFalse, reasonHostnameConflict, msg)
+	return r.patchEndpointConditions(ctx, key, cond)
+}
+
+// normalizeDNSName lowercases name and strips a trailing dot so hostnames can
+// be compared regardless of how they were written.
+func normalizeDNSName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
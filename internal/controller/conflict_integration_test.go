@@ -0,0 +1,116 @@
+//go:build integration
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestManager_HostnameIndex(t *testing.T) {
+	cfg := startTestEnv(t, &envtest.Environment{CRDs: []*apiextensionsv1.CustomResourceDefinition{vmiCRD(), dnsEndpointCRD()}})
+
+	scheme := newTestScheme(t)
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&VirtualMachineInstanceReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("test"),
+	}).SetupWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = mgr.Start(ctx) }()
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ns := range []string{"team-a", "team-b"} {
+		if err := c.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newVMI := func(ns, name, hostname string) *kubevirtv1.VirtualMachineInstance {
+		return &kubevirtv1.VirtualMachineInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   ns,
+				Annotations: map[string]string{annotationHostname: hostname},
+			},
+			Status: kubevirtv1.VirtualMachineInstanceStatus{
+				Phase:      kubevirtv1.Running,
+				Interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{{IP: "10.0.0.5", InfoSource: multusInfoSource}},
+				Conditions: []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+	for _, vmi := range []*kubevirtv1.VirtualMachineInstance{
+		newVMI("team-a", "vm1", "shared.example.com,vm1.example.com"),
+		newVMI("team-a", "vm2", "Shared.example.com."),
+		newVMI("team-a", "vm3", "vm3.example.com"),
+		newVMI("team-b", "vm1", "shared.example.com"),
+	} {
+		if err := c.Create(ctx, vmi); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Listing by the field only works through the index registered by SetupWithManager.
+	indexed := func(ns, hostname string) []string {
+		list := &kubevirtv1.VirtualMachineInstanceList{}
+		if err := mgr.GetClient().List(ctx, list, client.InNamespace(ns), client.MatchingFields{vmiHostnameIndex: hostname}); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, vmi := range list.Items {
+			names = append(names, vmi.Name)
+		}
+		return names
+	}
+	waitFor(t, 30*time.Second, "the hostname index did not list both VMIs claiming shared.example.com", func() bool {
+		return len(indexed("team-a", "shared.example.com")) == 2
+	})
+	if got := indexed("team-a", "vm3.example.com"); len(got) != 1 || got[0] != "vm3" {
+		t.Errorf("expected only vm3 under vm3.example.com, got %v", got)
+	}
+	if got := indexed("team-b", "shared.example.com"); len(got) != 1 || got[0] != "vm1" {
+		t.Errorf("expected only team-b/vm1 under shared.example.com in team-b, got %v", got)
+	}
+
+	endpointExists := func(ns, name string) bool {
+		err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, &dnsendpointv1alpha1.DNSEndpoint{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+	waitFor(t, 30*time.Second, "DNSEndpoints were not created for the VMIs without a conflict", func() bool {
+		return endpointExists("team-a", "vm3") && endpointExists("team-b", "vm1")
+	})
+	if endpointExists("team-a", "vm1") || endpointExists("team-a", "vm2") {
+		t.Error("expected no DNSEndpoint for the VMIs claiming the same hostname")
+	}
+}
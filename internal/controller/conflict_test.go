@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestReconcile_HostnameConflict(t *testing.T) {
+	iface := kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.5"}, InfoSource: guestAgentInfoSource}
+	first := newTestVMI("first", map[string]string{annotationHostname: "vm.example.com"}, iface)
+	second := newTestVMI("second", map[string]string{annotationHostname: "other.example.com,VM.example.com."}, iface)
+	r := newTestReconciler(t, first)
+
+	reconcileVMI(t, r, "first")
+	recordedEvents(r)
+	if err := r.Create(context.Background(), second); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "second")
+
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "second"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected no DNSEndpoint for the conflicting VMI, got %v", err)
+	}
+	if ep := getEndpoint(t, r, "first"); ep.Spec.Endpoints[0].DNSName != "vm.example.com" {
+		t.Errorf("expected the first VMI's endpoint to be untouched, got %v", ep.Spec.Endpoints)
+	}
+
+	events := recordedEvents(r)
+	var conflicts int
+	for _, e := range events {
+		if strings.HasPrefix(e, "Warning "+eventReasonHostnameConflict) {
+			conflicts++
+		}
+	}
+	if conflicts != 2 {
+		t.Errorf("expected a HostnameConflict warning on both VMIs, got %v", events)
+	}
+}
+
+func TestReconcile_HostnameConflictIncumbentKeepsRecord(t *testing.T) {
+	iface := kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.5"}, InfoSource: guestAgentInfoSource}
+	// The newcomer sorts first by name, so only the published record makes
+	// the incumbent win.
+	incumbent := newTestVMI("incumbent", map[string]string{annotationHostname: "vm.example.com"}, iface)
+	r := newTestReconciler(t, incumbent)
+	reconcileVMI(t, r, "incumbent")
+
+	newcomer := newTestVMI("a-newcomer", map[string]string{annotationHostname: "vm.example.com"}, iface)
+	if err := r.Create(context.Background(), newcomer); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "a-newcomer")
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "a-newcomer"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected no DNSEndpoint for the newcomer, got %v", err)
+	}
+
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(incumbent), incumbent); err != nil {
+		t.Fatal(err)
+	}
+	incumbent.Status.Interfaces[0].IPs = []string{"10.0.0.6"}
+	if err := r.Update(context.Background(), incumbent); err != nil {
+		t.Fatal(err)
+	}
+	recordedEvents(r)
+
+	reconcileVMI(t, r, "incumbent")
+	if got := getEndpoint(t, r, "incumbent").Spec.Endpoints[0].Targets; len(got) != 1 || got[0] != "10.0.0.6" {
+		t.Errorf("expected the incumbent to keep updating its record, got %v", got)
+	}
+	for _, e := range recordedEvents(r) {
+		if strings.Contains(e, eventReasonHostnameConflict) {
+			t.Errorf("unexpected conflict event for the incumbent: %s", e)
+		}
+	}
+}
+
+func TestFindVMIHostnameConflict(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		namespace   string
+		want        bool
+	}{
+		{"same hostname", map[string]string{annotationHostname: "VM.example.com."}, "default", true},
+		{"rendered template", map[string]string{annotationHostname: "{{ .Name }}.example.com"}, "default", false},
+		{"other namespace", map[string]string{annotationHostname: "vm.example.com"}, "other", false},
+		{"different set identifier", map[string]string{annotationHostname: "vm.example.com", annotationSetIdentifier: "b"}, "default", false},
+		{"weighted with a custom prefix", map[string]string{"dns.example.org/hostname": "vm.example.com", "dns.example.org/weight": "10"}, "default", false},
+		{"not running", map[string]string{annotationHostname: "vm.example.com"}, "default", false},
+		{"created later", map[string]string{annotationHostname: "vm.example.com"}, "default", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := newTestVMI("other", tt.annotations)
+			other.Namespace = tt.namespace
+			switch tt.name {
+			case "not running":
+				other.Status.Phase = kubevirtv1.Failed
+			case "created later":
+				other.CreationTimestamp = metav1.NewTime(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+			}
+			r := newTestReconciler(t, vmi, other)
+			if strings.HasPrefix(tt.name, "weighted") {
+				r.Config = NewControllerConfig(ControllerSettings{AnnotationPrefix: "dns.example.org/"})
+			}
+
+			conflict, err := r.findVMIHostnameConflict(context.Background(), vmi, "default", []string{"vm.example.com"}, "a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := conflict != nil; got != tt.want {
+				t.Errorf("conflict = %+v, want conflict %v", conflict, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcile_NoConflictWithOwnEndpoint(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.5"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+	reconcileVMI(t, r, "vm")
+
+	for _, e := range recordedEvents(r) {
+		if strings.Contains(e, eventReasonHostnameConflict) {
+			t.Errorf("unexpected conflict event: %s", e)
+		}
+	}
+}
+
+func TestFindHostnameConflict_IgnoresUnmanagedEndpoints(t *testing.T) {
+	unmanaged := &dnsendpointv1alpha1.DNSEndpoint{}
+	unmanaged.Name = "manual"
+	unmanaged.Namespace = "default"
+	unmanaged.Spec.Endpoints = []*dnsendpointv1alpha1.Endpoint{{DNSName: "vm.example.com", RecordType: "A", Targets: []string{"10.0.0.9"}}}
+	vmi := newTestVMI("vm", nil)
+	r := newTestReconciler(t, unmanaged, vmi)
+
+	conflict, err := r.findHostnameConflict(context.Background(), vmi, "default", []string{"vm.example.com"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflict != nil {
+		t.Errorf("expected unmanaged DNSEndpoints to be ignored, got %+v", conflict)
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// Dry-run log messages, one per kind of write that was skipped.
+const (
+	dryRunWouldCreate = "DryRun/WouldCreate"
+	dryRunWouldUpdate = "DryRun/WouldUpdate"
+	dryRunWouldDelete = "DryRun/WouldDelete"
+)
+
+// dryRunClient reads through the wrapped client and logs writes instead of
+// sending them.
+type dryRunClient struct {
+	client.Client
+}
+
+// NewDryRunClient returns a client that reads through c but never writes:
+// creates, updates, patches and deletes, of objects and their subresources,
+// are logged as DryRun/WouldCreate, DryRun/WouldUpdate or DryRun/WouldDelete
+// and reported as successful. A DNSEndpoint's log line carries its spec as
+// JSON, so the records the controller would publish can be reviewed.
+func NewDryRunClient(c client.Client) client.Client {
+	return dryRunClient{Client: c}
+}
+
+func (c dryRunClient) Create(ctx context.Context, obj client.Object, _ ...client.CreateOption) error {
+	c.log(ctx, dryRunWouldCreate, obj)
+	return nil
+}
+
+func (c dryRunClient) Update(ctx context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	c.log(ctx, dryRunWouldUpdate, obj)
+	return nil
+}
+
+func (c dryRunClient) Patch(ctx context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	c.log(ctx, dryRunWouldUpdate, obj)
+	return nil
+}
+
+func (c dryRunClient) Delete(ctx context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	c.log(ctx, dryRunWouldDelete, obj)
+	return nil
+}
+
+func (c dryRunClient) DeleteAllOf(ctx context.Context, obj client.Object, _ ...client.DeleteAllOfOption) error {
+	c.log(ctx, dryRunWouldDelete, obj)
+	return nil
+}
+
+func (c dryRunClient) Status() client.SubResourceWriter {
+	return c.SubResource("status")
+}
+
+func (c dryRunClient) SubResource(subResource string) client.SubResourceClient {
+	return dryRunSubResourceClient{SubResourceClient: c.Client.SubResource(subResource), parent: c, subResource: subResource}
+}
+
+// log writes one dry-run line for obj.
+func (c dryRunClient) log(ctx context.Context, msg string, obj client.Object, keysAndValues ...any) {
+	kind := fmt.Sprintf("%T", obj)
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = gvk.Kind
+	}
+	keysAndValues = append(keysAndValues, "kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+	if endpoint, ok := obj.(*dnsendpointv1alpha1.DNSEndpoint); ok {
+		spec, err := json.Marshal(endpoint.Spec)
+		if err != nil {
+			spec = []byte(err.Error())
+		}
+		keysAndValues = append(keysAndValues, "spec", string(spec))
+	}
+	log.FromContext(ctx).Info(msg, keysAndValues...)
+}
+
+// dryRunSubResourceClient reads subresources through the wrapped client and
+// logs writes to them instead of sending them.
+type dryRunSubResourceClient struct {
+	client.SubResourceClient
+	parent      dryRunClient
+	subResource string
+}
+
+func (c dryRunSubResourceClient) Create(ctx context.Context, obj client.Object, _ client.Object, _ ...client.SubResourceCreateOption) error {
+	c.parent.log(ctx, dryRunWouldCreate, obj, "subresource", c.subResource)
+	return nil
+}
+
+func (c dryRunSubResourceClient) Update(ctx context.Context, obj client.Object, _ ...client.SubResourceUpdateOption) error {
+	c.parent.log(ctx, dryRunWouldUpdate, obj, "subresource", c.subResource)
+	return nil
+}
+
+func (c dryRunSubResourceClient) Patch(ctx context.Context, obj client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
+	c.parent.log(ctx, dryRunWouldUpdate, obj, "subresource", c.subResource)
+	return nil
+}
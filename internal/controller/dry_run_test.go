@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestReconcile_DryRunWritesNothing(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	s := newTestScheme(t)
+	var writes []string
+	count := func(op string, obj client.Object) {
+		writes = append(writes, op+" "+obj.GetName())
+	}
+	base := withHostnameIndex(fake.NewClientBuilder().WithScheme(s)).WithObjects(vmi).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			count("create", obj)
+			return c.Create(ctx, obj, opts...)
+		},
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			count("update", obj)
+			return c.Update(ctx, obj, opts...)
+		},
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			count("patch", obj)
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+		Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			count("delete", obj)
+			return c.Delete(ctx, obj, opts...)
+		},
+		SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			count(subResource+" update", obj)
+			return c.SubResource(subResource).Update(ctx, obj, opts...)
+		},
+		SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			count(subResource+" patch", obj)
+			return c.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+	var audit bytes.Buffer
+	r := &VirtualMachineInstanceReconciler{Client: NewDryRunClient(base), Scheme: s, Recorder: record.NewFakeRecorder(100), Audit: NewAuditLogger(&audit), DryRun: true}
+	key := client.ObjectKeyFromObject(vmi)
+	managed := managedEndpoints.WithLabelValues("default")
+
+	var logs []string
+	ctx := ctrl.LoggerInto(context.Background(), funcr.New(func(_, args string) { logs = append(logs, args) }, funcr.Options{}))
+	// dryRun reconciles the VMI through the dry-run client and returns the
+	// dry-run log lines.
+	dryRun := func() []string {
+		t.Helper()
+		writes, logs = nil, nil
+		audit.Reset()
+		gauge := testutil.ToFloat64(managed)
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		if len(writes) > 0 {
+			t.Errorf("expected no API writes in dry-run mode, got %v", writes)
+		}
+		if events := recordedEvents(r); len(events) > 0 {
+			t.Errorf("expected no events for writes that did not happen, got %v", events)
+		}
+		if audit.Len() > 0 {
+			t.Errorf("expected no audit lines for writes that did not happen, got %s", audit.String())
+		}
+		if got := testutil.ToFloat64(managed); got != gauge {
+			t.Errorf("managed endpoints gauge moved from %v to %v", gauge, got)
+		}
+		var dryRunLogs []string
+		for _, line := range logs {
+			if strings.Contains(line, `"msg"="DryRun/`) {
+				dryRunLogs = append(dryRunLogs, line)
+			}
+		}
+		return dryRunLogs
+	}
+	// hasLine reports whether one of lines contains every one of wants.
+	hasLine := func(lines []string, wants ...string) bool {
+		for _, line := range lines {
+			matched := true
+			for _, want := range wants {
+				matched = matched && strings.Contains(line, want)
+			}
+			if matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Create: nothing exists yet.
+	lines := dryRun()
+	if !hasLine(lines, dryRunWouldCreate, `"kind"="DNSEndpoint"`, "vm.example.com", "10.0.0.1") {
+		t.Errorf("expected a %s line with the DNSEndpoint spec, got %v", dryRunWouldCreate, lines)
+	}
+	if err := base.Get(ctx, key, new(dnsendpointv1alpha1.DNSEndpoint)); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected no DNSEndpoint to be created, got %v", err)
+	}
+
+	// Update: publish for real, then change the VMI's address.
+	r.Client, r.DryRun = base, false
+	reconcileVMI(t, r, "vm")
+	recordedEvents(r)
+	r.Client, r.DryRun = NewDryRunClient(base), true
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := base.Get(ctx, key, got); err != nil {
+		t.Fatal(err)
+	}
+	got.Status.Interfaces[0].IP = "10.0.0.2"
+	if err := base.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	lines = dryRun()
+	if !hasLine(lines, dryRunWouldUpdate, `"kind"="DNSEndpoint"`, "10.0.0.2") {
+		t.Errorf("expected a %s line with the new address, got %v", dryRunWouldUpdate, lines)
+	}
+	if target := getEndpoint(t, r, "vm").Spec.Endpoints[0].Targets[0]; target != "10.0.0.1" {
+		t.Errorf("expected the DNSEndpoint to keep 10.0.0.1, got %s", target)
+	}
+
+	// Delete: the VMI is deleted and waits on the cleanup finalizer.
+	if err := base.Delete(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	lines = dryRun()
+	if !hasLine(lines, dryRunWouldDelete, `"kind"="DNSEndpoint"`, `"name"="vm"`) {
+		t.Errorf("expected a %s line for the DNSEndpoint, got %v", dryRunWouldDelete, lines)
+	}
+	getEndpoint(t, r, "vm")
+	if err := base.Get(ctx, key, got); err != nil || !controllerutil.ContainsFinalizer(got, r.finalizerName()) {
+		t.Errorf("expected the VMI to keep its finalizer, got err=%v finalizers=%v", err, got.Finalizers)
+	}
+}
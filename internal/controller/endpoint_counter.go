@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// ManagedEndpointCounter periodically recounts the DNSEndpoints owned by VMIs
+// and publishes the result on the managed endpoints gauge. The first count runs
+// as soon as the manager starts, which seeds the gauge after a restart; later
+// counts correct any drift from the increments done during reconciles.
+type ManagedEndpointCounter struct {
+	client.Reader
+	// Interval between recounts.
+	Interval time.Duration
+}
+
+// Start implements manager.Runnable.
+func (c *ManagedEndpointCounter) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("endpoint-counter")
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.count(ctx); err != nil {
+			logger.Error(err, "failed to count managed DNSEndpoints")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// count lists all DNSEndpoints and sets the gauge to the number owned by a VMI in each namespace.
+func (c *ManagedEndpointCounter) count(ctx context.Context) error {
+	list := &dnsendpointv1alpha1.DNSEndpointList{}
+	if err := c.List(ctx, list); err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for i := range list.Items {
+		if isManagedEndpoint(&list.Items[i]) {
+			counts[list.Items[i].Namespace]++
+		}
+	}
+
+	managedEndpoints.Reset()
+	for ns, n := range counts {
+		managedEndpoints.WithLabelValues(ns).Set(float64(n))
+	}
+	return nil
+}
+
+// isManagedEndpoint reports whether the DNSEndpoint is owned by a VirtualMachineInstance.
+func isManagedEndpoint(ep *dnsendpointv1alpha1.DNSEndpoint) bool {
+	_, _, ok := endpointOwner(ep)
+	return ok
+}
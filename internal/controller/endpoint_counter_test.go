@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestManagedEndpointCounter_CountsOwnedEndpoints(t *testing.T) {
+	iface := kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource}
+	vm1 := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"}, iface)
+	vm2 := newTestVMI("vm2", map[string]string{annotationHostname: "vm2.example.com"}, iface)
+	unmanaged := &dnsendpointv1alpha1.DNSEndpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: "default"},
+	}
+	r := newTestReconciler(t, vm1, vm2, unmanaged)
+
+	reconcileVMI(t, r, "vm1")
+	reconcileVMI(t, r, "vm2")
+
+	counter := &ManagedEndpointCounter{Reader: r.Client}
+	if err := counter.count(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(managedEndpoints.WithLabelValues("default")); got != 2 {
+		t.Errorf("expected 2 managed endpoints, got %v", got)
+	}
+}
+
+func TestManagedEndpoints_DecrementedOnDelete(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	managedEndpoints.Reset()
+
+	reconcileVMI(t, r, "vm")
+	if got := testutil.ToFloat64(managedEndpoints.WithLabelValues("default")); got != 1 {
+		t.Fatalf("expected gauge 1 after create, got %v", got)
+	}
+
+	if err := r.deleteEndpointIfExists(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(managedEndpoints.WithLabelValues("default")); got != 0 {
+		t.Errorf("expected gauge 0 after delete, got %v", got)
+	}
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// DefaultEndpointNameFormat names each DNSEndpoint after its VMI.
+const DefaultEndpointNameFormat = "{{ .Name }}"
+
+// endpointNameData is the data available to endpoint name templates.
+type endpointNameData struct {
+	Name      string
+	Namespace string
+}
+
+// ParseEndpointNameFormat parses a DNSEndpoint name template over the VMI's
+// .Name and .Namespace. The template is test-rendered, so references to
+// anything else, or a format that cannot yield a valid object name, fail here
+// rather than on every reconcile.
+func ParseEndpointNameFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("endpoint-name").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+	sample := &kubevirtv1.VirtualMachineInstance{}
+	sample.Name, sample.Namespace = "vm", "default"
+	if _, err := renderEndpointName(tmpl, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// renderEndpointName renders the name of the VMI's DNSEndpoint. A nil
+// template yields the VMI's name. The result must be a valid object name.
+func renderEndpointName(tmpl *template.Template, vmi *kubevirtv1.VirtualMachineInstance) (string, error) {
+	if tmpl == nil {
+		return vmi.Name, nil
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, endpointNameData{Name: vmi.Name, Namespace: vmi.Namespace}); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(sb.String())
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", fmt.Errorf("DNSEndpoint name %q: %s", name, strings.Join(errs, "; "))
+	}
+	return name, nil
+}
+
+// endpointName returns the name of the VMI's DNSEndpoint in its own namespace,
+// falling back to the VMI's name when EndpointNameFormat fails to render.
+func (r *VirtualMachineInstanceReconciler) endpointName(vmi *kubevirtv1.VirtualMachineInstance) (string, error) {
+	name, err := renderEndpointName(r.EndpointNameFormat, vmi)
+	if err != nil {
+		return vmi.Name, err
+	}
+	return name, nil
+}
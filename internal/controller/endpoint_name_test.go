@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"testing"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+func TestParseEndpointNameFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{format: DefaultEndpointNameFormat},
+		{format: "{{ .Namespace }}-{{ .Name }}"},
+		{format: "dns-{{ .Name }}"},
+		{format: "{{ .Name", wantErr: true},
+		{format: "{{ .Labels.team }}-{{ .Name }}", wantErr: true},
+		{format: "{{ .Name }}_DNS", wantErr: true},
+		{format: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := ParseEndpointNameFormat(tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEndpointNameFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRenderEndpointName(t *testing.T) {
+	vmi := newTestVMI("vm", nil)
+	vmi.Namespace = "team-a"
+
+	if got, err := renderEndpointName(nil, vmi); err != nil || got != "vm" {
+		t.Errorf("default format: got %q, %v; want vm", got, err)
+	}
+	tmpl, err := ParseEndpointNameFormat("{{ .Namespace }}-{{ .Name }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := renderEndpointName(tmpl, vmi); err != nil || got != "team-a-vm" {
+		t.Errorf("custom format: got %q, %v; want team-a-vm", got, err)
+	}
+}
+
+func TestReconcile_EndpointNameFormat(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	tmpl, err := ParseEndpointNameFormat("{{ .Namespace }}-{{ .Name }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.EndpointNameFormat = tmpl
+
+	reconcileVMI(t, r, "vm")
+
+	ep := getEndpoint(t, r, "default-vm")
+	if len(ep.OwnerReferences) != 1 || ep.OwnerReferences[0].UID != vmi.UID || ep.OwnerReferences[0].Name != "vm" {
+		t.Errorf("expected the VMI to own the DNSEndpoint, got %v", ep.OwnerReferences)
+	}
+	if owner, _, ok := endpointOwner(ep); !ok || owner != (types.NamespacedName{Namespace: "default", Name: "vm"}) {
+		t.Errorf("endpointOwner = %v, %v; want default/vm", owner, ok)
+	}
+	if lookupEndpoint(t, r, "vm") != nil {
+		t.Error("expected no DNSEndpoint named after the VMI")
+	}
+}
+
+func TestReconcile_EndpointNameFormatReplacesOldEndpoint(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	reconcileVMI(t, r, "vm")
+	getEndpoint(t, r, "vm")
+
+	tmpl, err := ParseEndpointNameFormat("{{ .Name }}-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.EndpointNameFormat = tmpl
+	reconcileVMI(t, r, "vm")
+
+	getEndpoint(t, r, "vm-dns")
+	if lookupEndpoint(t, r, "vm") != nil {
+		t.Error("expected the DNSEndpoint named after the VMI to be replaced")
+	}
+}
+
+func TestReconcile_EndpointNameFormatRenderErrorFallsBack(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	// Bypasses ParseEndpointNameFormat, which would reject the invalid name.
+	r.EndpointNameFormat = template.Must(template.New("endpoint-name").Parse("{{ .Name }}."))
+
+	reconcileVMI(t, r, "vm")
+
+	getEndpoint(t, r, "vm")
+}
@@ -0,0 +1,80 @@
+//go:build integration
+
+package controller
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// startTestEnv starts env and stops it when the test ends. The test is skipped
+// when the envtest binaries are not available.
+func startTestEnv(t *testing.T, env *envtest.Environment) *rest.Config {
+	t.Helper()
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set; run `setup-envtest use` first")
+	}
+	cfg, err := env.Start()
+	if err != nil {
+		t.Fatalf("failed to start envtest: %v", err)
+	}
+	t.Cleanup(func() { _ = env.Stop() })
+	return cfg
+}
+
+// waitFor polls cond until it returns true, failing the test with msg after timeout.
+func waitFor(t *testing.T, timeout time.Duration, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// schemalessCRD returns a namespaced CRD for group/kind that accepts any content.
+// It stands in for the KubeVirt and External-DNS CRDs, which are not vendored.
+func schemalessCRD(group, version, kind, plural string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plural + "." + group},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:     kind,
+				ListKind: kind + "List",
+				Plural:   plural,
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:    version,
+				Served:  true,
+				Storage: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type:                   "object",
+						XPreserveUnknownFields: ptr.To(true),
+					},
+				},
+			}},
+		},
+	}
+}
+
+// vmiCRD stands in for the KubeVirt VirtualMachineInstance CRD.
+func vmiCRD() *apiextensionsv1.CustomResourceDefinition {
+	return schemalessCRD("kubevirt.io", "v1", "VirtualMachineInstance", "virtualmachineinstances")
+}
+
+// dnsEndpointCRD stands in for the External-DNS DNSEndpoint CRD.
+func dnsEndpointCRD() *apiextensionsv1.CustomResourceDefinition {
+	return schemalessCRD("externaldns.k8s.io", "v1alpha1", "DNSEndpoint", "dnsendpoints")
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// ValidateFinalizerName checks that name is a valid custom finalizer: a
+// qualified name with a DNS subdomain prefix, such as
+// example.com/dns-cleanup. Unprefixed names are reserved for Kubernetes.
+func ValidateFinalizerName(name string) error {
+	if errs := validation.IsQualifiedName(name); len(errs) > 0 {
+		return fmt.Errorf("invalid finalizer name %q: %s", name, strings.Join(errs, "; "))
+	}
+	if !strings.Contains(name, "/") {
+		return fmt.Errorf("invalid finalizer name %q: must be domain-qualified, e.g. example.com/%s", name, name)
+	}
+	if errs := validation.IsQualifiedName(propagationFinalizerFor(name)); len(errs) > 0 {
+		return fmt.Errorf("invalid finalizer name %q: its DNS propagation finalizer %q is invalid: %s",
+			name, propagationFinalizerFor(name), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// propagationFinalizerFor returns the DNS propagation finalizer paired with the
+// cleanup finalizer name, so controllers with distinct finalizer names never
+// release each other's VMIs. DefaultFinalizerName keeps the historical
+// defaultPropagationFinalizer.
+func propagationFinalizerFor(name string) string {
+	if name == DefaultFinalizerName {
+		return defaultPropagationFinalizer
+	}
+	return name + "-dns-propagation"
+}
+
+// finalizerName returns the cleanup finalizer added to annotated VMIs.
+func (r *VirtualMachineInstanceReconciler) finalizerName() string {
+	if r.FinalizerName == "" {
+		return DefaultFinalizerName
+	}
+	return r.FinalizerName
+}
+
+// propagationFinalizer returns the DNS propagation finalizer added to
+// annotated VMIs when DNSPropagationDelay is set.
+func (r *VirtualMachineInstanceReconciler) propagationFinalizer() string {
+	return propagationFinalizerFor(r.finalizerName())
+}
+
+// previousFinalizers returns the cleanup and DNS propagation finalizers of
+// PreviousFinalizerNames, other than the current ones.
+func (r *VirtualMachineInstanceReconciler) previousFinalizers() []string {
+	var previous []string
+	for _, name := range r.PreviousFinalizerNames {
+		if name == r.finalizerName() {
+			continue
+		}
+		previous = append(previous, name, propagationFinalizerFor(name))
+	}
+	return previous
+}
+
+// hasOwnFinalizer reports whether vmi carries any finalizer of this
+// controller, current or previous.
+func (r *VirtualMachineInstanceReconciler) hasOwnFinalizer(vmi *kubevirtv1.VirtualMachineInstance) bool {
+	for _, f := range append([]string{r.finalizerName(), r.propagationFinalizer()}, r.previousFinalizers()...) {
+		if controllerutil.ContainsFinalizer(vmi, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+const testFinalizerName = "kubevirt.example.com/dns-cleanup"
+
+func TestValidateFinalizerName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{DefaultFinalizerName, false},
+		{testFinalizerName, false},
+		{"cleanup", true},
+		{"", true},
+		{"Example.com/cleanup", true},
+		{"example.com/", true},
+		{"example.com/clean up", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateFinalizerName(tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateFinalizerName(%q) = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// getVMIFinalizers returns the finalizers of the named VMI in the default namespace.
+func getVMIFinalizers(t *testing.T, r *VirtualMachineInstanceReconciler, name string) []string {
+	t.Helper()
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, vmi); err != nil {
+		t.Fatal(err)
+	}
+	return vmi.Finalizers
+}
+
+func TestReconcile_CustomFinalizerAddedAndRemoved(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.FinalizerName = testFinalizerName
+
+	reconcileVMI(t, r, "vm")
+	if got := getVMIFinalizers(t, r, "vm"); !slices.Equal(got, []string{testFinalizerName}) {
+		t.Errorf("expected only finalizer %q, got %v", testFinalizerName, got)
+	}
+
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(vmi), vmi); err != nil {
+		t.Fatal(err)
+	}
+	delete(vmi.Annotations, annotationHostname)
+	if err := r.Update(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+	if got := getVMIFinalizers(t, r, "vm"); len(got) != 0 {
+		t.Errorf("expected the finalizer to be removed with the annotation, got %v", got)
+	}
+}
+
+func TestReconcile_CustomFinalizerLeavesOtherControllersAlone(t *testing.T) {
+	// A legacy controller still manages the default finalizer name.
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	vmi.Finalizers = []string{DefaultFinalizerName}
+	r := newTestReconciler(t, vmi)
+	r.FinalizerName = testFinalizerName
+
+	reconcileVMI(t, r, "vm")
+	if got := getVMIFinalizers(t, r, "vm"); !slices.Equal(got, []string{DefaultFinalizerName, testFinalizerName}) {
+		t.Errorf("expected both finalizers, got %v", got)
+	}
+
+	if err := r.Delete(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the DNSEndpoint to be deleted, got err=%v", err)
+	}
+	if got := getVMIFinalizers(t, r, "vm"); !slices.Equal(got, []string{DefaultFinalizerName}) {
+		t.Errorf("expected only the other controller's finalizer to remain, got %v", got)
+	}
+}
+
+func TestPropagationFinalizerFor(t *testing.T) {
+	if got := propagationFinalizerFor(DefaultFinalizerName); got != defaultPropagationFinalizer {
+		t.Errorf("propagationFinalizerFor(default) = %q, want %q", got, defaultPropagationFinalizer)
+	}
+	if got, want := propagationFinalizerFor(testFinalizerName), testFinalizerName+"-dns-propagation"; got != want {
+		t.Errorf("propagationFinalizerFor(%q) = %q, want %q", testFinalizerName, got, want)
+	}
+	// The name part is valid alone, but too long once the suffix is added.
+	long := "example.com/" + strings.Repeat("a", 60)
+	if err := ValidateFinalizerName(long); err == nil {
+		t.Errorf("expected %q to be rejected for its propagation finalizer", long)
+	}
+}
+
+func TestReconcile_CustomFinalizerDerivesPropagationFinalizer(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.FinalizerName = testFinalizerName
+	r.DNSPropagationDelay = time.Minute
+
+	reconcileVMI(t, r, "vm")
+	want := []string{testFinalizerName, testFinalizerName + "-dns-propagation"}
+	if got := getVMIFinalizers(t, r, "vm"); !slices.Equal(got, want) {
+		t.Errorf("expected finalizers %v, got %v", want, got)
+	}
+}
+
+func TestReconcile_PreviousFinalizerMigrated(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	vmi.Finalizers = []string{DefaultFinalizerName, defaultPropagationFinalizer}
+	r := newTestReconciler(t, vmi)
+	r.FinalizerName = testFinalizerName
+	r.PreviousFinalizerNames = []string{DefaultFinalizerName}
+
+	reconcileVMI(t, r, "vm")
+	if got := getVMIFinalizers(t, r, "vm"); !slices.Equal(got, []string{testFinalizerName}) {
+		t.Errorf("expected the previous finalizers to be replaced by %q, got %v", testFinalizerName, got)
+	}
+}
+
+func TestReconcile_PreviousFinalizerReleasedOnDelete(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	reconcileVMI(t, r, "vm")
+
+	// The controller restarts with a new finalizer name before it ever
+	// reconciled the VMI again, which is then deleted.
+	r.FinalizerName = testFinalizerName
+	r.PreviousFinalizerNames = []string{DefaultFinalizerName}
+	if err := r.Delete(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the DNSEndpoint to be deleted, got err=%v", err)
+	}
+	err = r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, &kubevirtv1.VirtualMachineInstance{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the VMI to be released, got err=%v", err)
+	}
+}
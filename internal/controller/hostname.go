@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// annotationHostnamePrefix is prepended to every hostname on the VMI.
+	annotationHostnamePrefix = defaultAnnotationPrefix + "hostname-prefix"
+	// annotationHostnameSuffix is appended to every hostname on the VMI.
+	annotationHostnameSuffix = defaultAnnotationPrefix + "hostname-suffix"
+
+	// DefaultClusterDomain is the cluster domain short hostnames are expanded
+	// under when none is configured.
+	DefaultClusterDomain = "cluster.local"
+
+	// maxFQDNLength is the longest name allowed by RFC 1035, without the trailing dot.
+	maxFQDNLength = 253
+	// maxLabelLength is the longest single label allowed by RFC 1035.
+	maxLabelLength = 63
+
+	// eventReasonInvalidHostname is emitted when hostnames are skipped for failing validateFQDN.
+	eventReasonInvalidHostname = "InvalidHostname"
+	// eventReasonWildcardRejected is emitted when wildcard hostnames are skipped
+	// because of --reject-wildcards.
+	eventReasonWildcardRejected = "WildcardHostnameRejected"
+)
+
+// applyHostnameAffixes returns prefix + hostname + suffix, lowercased, for
+// each hostname. Names that are not valid FQDNs afterwards are dropped and
+// reported through the returned error, which wraps errInvalidAnnotation.
+func applyHostnameAffixes(hostnames []string, prefix, suffix string) ([]string, error) {
+	var result []string
+	var errs []error
+	for _, h := range hostnames {
+		name := strings.ToLower(prefix + h + suffix)
+		if err := validateFQDN(name); err != nil {
+			errs = append(errs, fmt.Errorf("%w: hostname %q: %v", errInvalidAnnotation, name, err))
+			continue
+		}
+		result = append(result, name)
+	}
+	return result, errors.Join(errs...)
+}
+
+// expandShortHostnames expands every single-label hostname to
+// <name>.<namespace>.svc.<clusterDomain>, the form Kubernetes DNS uses for
+// services. Hostnames that already contain a dot are returned unchanged.
+// Expanded names that are not valid FQDNs, e.g. because they grow past the
+// length limit, are dropped and reported through the returned error, which
+// wraps errInvalidAnnotation.
+func expandShortHostnames(hostnames []string, namespace, clusterDomain string) ([]string, error) {
+	result := make([]string, 0, len(hostnames))
+	var errs []error
+	for _, h := range hostnames {
+		if !strings.Contains(h, ".") {
+			h = h + "." + namespace + ".svc." + clusterDomain
+			if err := validateFQDN(h); err != nil {
+				errs = append(errs, fmt.Errorf("%w: hostname %q: %v", errInvalidAnnotation, h, err))
+				continue
+			}
+		}
+		result = append(result, h)
+	}
+	return result, errors.Join(errs...)
+}
+
+// validateFQDN checks the RFC 1035 limits: name is at most 253 characters and
+// is made of non-empty labels of at most 63 lowercase letters, digits and
+// hyphens that do not start or end with a hyphen. A single trailing dot and a leading "*" wildcard label
+// are accepted.
+func validateFQDN(name string) error {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return errors.New("empty name")
+	}
+	if len(name) > maxFQDNLength {
+		return fmt.Errorf("longer than %d characters", maxFQDNLength)
+	}
+	for i, label := range strings.Split(name, ".") {
+		if i == 0 && label == "*" {
+			continue
+		}
+		if err := validateLabel(label); err != nil {
+			return fmt.Errorf("label %q: %w", label, err)
+		}
+	}
+	return nil
+}
+
+// validateLabel checks a single DNS label.
+func validateLabel(label string) error {
+	if label == "" {
+		return errors.New("empty label")
+	}
+	if len(label) > maxLabelLength {
+		return fmt.Errorf("longer than %d characters", maxLabelLength)
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return errors.New("starts or ends with a hyphen")
+	}
+	for _, c := range label {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return fmt.Errorf("invalid character %q", c)
+		}
+	}
+	return nil
+}
+
+// isWildcardHostname reports whether name is a wildcard record such as *.example.com.
+func isWildcardHostname(name string) bool {
+	return strings.HasPrefix(name, "*.")
+}
+
+// filterWildcardHostnames splits hostnames into non-wildcard and wildcard names.
+func filterWildcardHostnames(hostnames []string) (kept, wildcards []string) {
+	for _, h := range hostnames {
+		if isWildcardHostname(h) {
+			wildcards = append(wildcards, h)
+		} else {
+			kept = append(kept, h)
+		}
+	}
+	return kept, wildcards
+}
+
+// ParseZones parses a comma-separated list of DNS zones, dropping empty
+// entries and trailing dots and lowercasing the rest.
+func ParseZones(raw string) []string {
+	var zones []string
+	for _, part := range strings.Split(raw, ",") {
+		zone := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(part), "."))
+		if zone != "" {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+// filterHostnamesByZone splits hostnames into those inside an allowed zone and
+// outside every denied zone, and the rest. An empty allowlist allows every zone.
+func filterHostnamesByZone(hostnames, allow, deny []string) (kept, rejected []string) {
+	for _, h := range hostnames {
+		if (len(allow) == 0 || inAnyZone(h, allow)) && !inAnyZone(h, deny) {
+			kept = append(kept, h)
+		} else {
+			rejected = append(rejected, h)
+		}
+	}
+	return kept, rejected
+}
+
+// inAnyZone reports whether hostname equals or is a subdomain of one of zones.
+func inAnyZone(hostname string, zones []string) bool {
+	name := strings.ToLower(strings.TrimSuffix(hostname, "."))
+	for _, zone := range zones {
+		if name == zone || strings.HasSuffix(name, "."+zone) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// hostnamePolicy holds the hostname rules every reconciler applies to rendered
+// hostnames before publishing them, so a replica set cannot publish a name
+// that a VMI would be refused.
+type hostnamePolicy struct {
+	// expandShort expands single-label hostnames under clusterDomain.
+	expandShort   bool
+	clusterDomain string
+	// zoneAllowlist and zoneDenylist are the --zone-allowlist and
+	// --zone-denylist zones.
+	zoneAllowlist []string
+	zoneDenylist  []string
+	// rejectWildcards drops wildcard hostnames.
+	rejectWildcards bool
+	// recorder receives the Warning events about skipped hostnames.
+	recorder record.EventRecorder
+}
+
+// filter applies prefix and suffix to hostnames, expands short names under
+// obj's namespace, and drops names outside the allowed zones and, when
+// rejectWildcards is set, wildcard names. Every skipped name is logged with
+// logKey set to obj's key; invalid and wildcard names are also counted and
+// reported as Warning events on obj.
+func (p hostnamePolicy) filter(ctx context.Context, obj client.Object, logKey string, hostnames []string, prefix, suffix string) []string {
+	logger := log.FromContext(ctx)
+	key := client.ObjectKeyFromObject(obj)
+	hostnames, err := applyHostnameAffixes(hostnames, prefix, suffix)
+	if err != nil {
+		logger.Info("skipping invalid hostnames", logKey, key, "error", err.Error())
+		countReconcileError(err)
+		p.recorder.Eventf(obj, corev1.EventTypeWarning, eventReasonInvalidHostname, "Skipping invalid hostnames: %v", err)
+	}
+	if p.expandShort {
+		hostnames, err = expandShortHostnames(hostnames, obj.GetNamespace(), p.clusterDomain)
+		if err != nil {
+			logger.Info("skipping invalid expanded hostnames", logKey, key, "error", err.Error())
+			countReconcileError(err)
+			p.recorder.Eventf(obj, corev1.EventTypeWarning, eventReasonInvalidHostname, "Skipping invalid hostnames: %v", err)
+		}
+	}
+	hostnames, rejected := filterHostnamesByZone(hostnames, p.zoneAllowlist, p.zoneDenylist)
+	for _, h := range rejected {
+		logger.Info("skipping hostname outside the allowed zones", logKey, key, "hostname", h)
+	}
+	if !p.rejectWildcards {
+		return hostnames
+	}
+	kept, wildcards := filterWildcardHostnames(hostnames)
+	for _, h := range wildcards {
+		logger.Info("skipping wildcard hostname because --reject-wildcards is set", logKey, key, "hostname", h)
+	}
+	if len(wildcards) > 0 {
+		p.recorder.Eventf(obj, corev1.EventTypeWarning, eventReasonWildcardRejected,
+			"Skipping wildcard hostnames %v: wildcard records are disabled", wildcards)
+	}
+	return kept
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// hostnameTemplateData is the data available to hostname templates.
+type hostnameTemplateData struct {
+	Name        string
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// errHostnameTemplatesDisabled reports a template hostname entry while the
+// TEMPLATE_HOSTNAMES feature is disabled.
+var errHostnameTemplatesDisabled = errors.New("hostname templates are disabled by FEATURE_TEMPLATE_HOSTNAMES")
+
+// isHostnameTemplate reports whether a hostname entry needs rendering.
+func isHostnameTemplate(raw string) bool {
+	return strings.Contains(raw, "{{")
+}
+
+// renderHostnameTemplate renders a single hostname entry as a Go template over
+// the VMI's name, namespace, labels and annotations. Referencing a missing map
+// key is an error.
+func renderHostnameTemplate(raw string, vmi *kubevirtv1.VirtualMachineInstance) (string, error) {
+	tmpl, err := template.New("hostname").Option("missingkey=error").Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, hostnameTemplateData{
+		Name:        vmi.Name,
+		Namespace:   vmi.Namespace,
+		Labels:      vmi.Labels,
+		Annotations: vmi.Annotations,
+	}); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// renderHostnames renders every templated entry in hostnames and lowercases
+// the result. Entries that fail to render, or render to an empty string, are
+// dropped and reported through the returned error, which wraps errInvalidAnnotation.
+// When templates is false, every templated entry is dropped that way.
+func renderHostnames(hostnames []string, vmi *kubevirtv1.VirtualMachineInstance, templates bool) ([]string, error) {
+	var result []string
+	var errs []error
+	for _, h := range hostnames {
+		if !isHostnameTemplate(h) {
+			result = append(result, h)
+			continue
+		}
+		if !templates {
+			errs = append(errs, fmt.Errorf("%w: hostname template %q: %w", errInvalidAnnotation, h, errHostnameTemplatesDisabled))
+			continue
+		}
+		rendered, err := renderHostnameTemplate(h, vmi)
+		if err == nil && rendered == "" {
+			err = errors.New("rendered to an empty hostname")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: hostname template %q: %v", errInvalidAnnotation, h, err))
+			continue
+		}
+		result = append(result, strings.ToLower(rendered))
+	}
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+func TestRenderHostnameTemplate_Labels(t *testing.T) {
+	vmi := newTestVMI("web-1", nil)
+	vmi.Labels = map[string]string{"team": "payments"}
+
+	got, err := renderHostnameTemplate(`{{ .Name }}.{{ index .Labels "team" }}.{{ .Namespace }}.vms.example.com`, vmi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "web-1.payments.default.vms.example.com"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderHostnameTemplate_MissingLabel(t *testing.T) {
+	vmi := newTestVMI("web-1", nil)
+	vmi.Labels = map[string]string{"team": "payments"}
+
+	if got, err := renderHostnameTemplate(`{{ .Labels.env }}.example.com`, vmi); err == nil {
+		t.Errorf("expected an error for a missing label, got %q", got)
+	}
+}
+
+func TestRenderHostnames_MultipleEntries(t *testing.T) {
+	vmi := newTestVMI("web-1", nil)
+	vmi.Labels = map[string]string{"team": "payments"}
+
+	got, err := renderHostnames(parseHostnames(
+		"{{ .Name }}.a.example.com, static.example.com, {{ .Labels.team }}.b.example.com, {{ .Labels.env }}.c.example.com"), vmi, true)
+	if !errors.Is(err, errInvalidAnnotation) {
+		t.Errorf("expected errInvalidAnnotation for the missing label, got %v", err)
+	}
+	want := []string{"web-1.a.example.com", "static.example.com", "payments.b.example.com"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReconcile_TemplatedHostname(t *testing.T) {
+	vmi := newTestVMI("web-1", map[string]string{annotationHostname: "{{ .Name }}.{{ .Namespace }}.vms.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "web-1")
+
+	ep := getEndpoint(t, r, "web-1")
+	if len(ep.Spec.Endpoints) != 1 || ep.Spec.Endpoints[0].DNSName != "web-1.default.vms.example.com" {
+		t.Errorf("unexpected endpoints: %v", ep.Spec.Endpoints)
+	}
+}
+
+func TestRenderHostnames_TemplatesDisabled(t *testing.T) {
+	vmi := newTestVMI("web-1", nil)
+
+	got, err := renderHostnames(parseHostnames("{{ .Name }}.example.com, static.example.com"), vmi, false)
+	if !errors.Is(err, errHostnameTemplatesDisabled) {
+		t.Errorf("expected errHostnameTemplatesDisabled, got %v", err)
+	}
+	if want := []string{"static.example.com"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+func TestApplyHostnameAffixes(t *testing.T) {
+	hostnames := []string{"web", "db"}
+	tests := []struct {
+		name           string
+		prefix, suffix string
+		want           []string
+	}{
+		{"none", "", "", []string{"web", "db"}},
+		{"prefix only", "prod-", "", []string{"prod-web", "prod-db"}},
+		{"suffix only", "", ".vms.example.com", []string{"web.vms.example.com", "db.vms.example.com"}},
+		{"combined", "prod-", ".vms.example.com", []string{"prod-web.vms.example.com", "prod-db.vms.example.com"}},
+	}
+	for _, tt := range tests {
+		got, err := applyHostnameAffixes(hostnames, tt.prefix, tt.suffix)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyHostnameAffixes_FQDNTooLong(t *testing.T) {
+	// Four 63-character labels plus three dots is 255 characters.
+	label := strings.Repeat("a", 63)
+	suffix := "." + label + "." + label + "." + label
+
+	got, err := applyHostnameAffixes([]string{label, "short"}, "", suffix)
+	if !errors.Is(err, errInvalidAnnotation) {
+		t.Errorf("expected errInvalidAnnotation, got %v", err)
+	}
+	if !slices.Equal(got, []string{"short" + suffix}) {
+		t.Errorf("expected only the short hostname to remain, got %v", got)
+	}
+}
+
+func TestValidateFQDN(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"vm.example.com", true},
+		{"vm.example.com.", true},
+		{"*.vms.example.com", true},
+		{"vm-1.example.com", true},
+		{"VM-1.Example.com", false},
+		{strings.Repeat("a", 63) + ".com", true},
+		{strings.Repeat("a", 64) + ".com", false},
+		{strings.Repeat("a.", 126) + "a", true},
+		{strings.Repeat("a.", 126) + "ab", false},
+		{"", false},
+		{"vm..example.com", false},
+		{"-vm.example.com", false},
+		{"vm-.example.com", false},
+		{"vm_1.example.com", false},
+		{"vm.*.example.com", false},
+	}
+	for _, tt := range tests {
+		if err := validateFQDN(tt.name); (err == nil) != tt.valid {
+			t.Errorf("validateFQDN(%q) = %v, want valid=%v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestReconcile_HostnamePrefixAndSuffix(t *testing.T) {
+	vmi := newTestVMI("web-1", map[string]string{
+		annotationHostname:       "web-1",
+		annotationHostnamePrefix: "prod-",
+		annotationHostnameSuffix: ".vms.example.com",
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "web-1")
+
+	ep := getEndpoint(t, r, "web-1")
+	if len(ep.Spec.Endpoints) != 1 || ep.Spec.Endpoints[0].DNSName != "prod-web-1.vms.example.com" {
+		t.Errorf("unexpected endpoints: %v", ep.Spec.Endpoints)
+	}
+}
+
+func TestExpandShortHostnames(t *testing.T) {
+	tests := []struct {
+		name      string
+		hostnames []string
+		want      []string
+	}{
+		{"single label", []string{"myvm"}, []string{"myvm.team-a.svc.cluster.local"}},
+		{"already FQDN", []string{"myvm.example.com"}, []string{"myvm.example.com"}},
+		{"mixed", []string{"myvm", "myvm.example.com", "db"}, []string{"myvm.team-a.svc.cluster.local", "myvm.example.com", "db.team-a.svc.cluster.local"}},
+		{"empty", nil, []string{}},
+	}
+	for _, tt := range tests {
+		got, err := expandShortHostnames(tt.hostnames, "team-a", "cluster.local")
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExpandShortHostnames_DropsInvalidExpansions(t *testing.T) {
+	// 63 + 7 (".team-a") + 4 (".svc") + 183 exceeds the 253-character limit.
+	clusterDomain := strings.Repeat(strings.Repeat("c", 60)+".", 3) + "local"
+	got, err := expandShortHostnames([]string{strings.Repeat("a", 63), "db"}, "team-a", clusterDomain)
+	if !errors.Is(err, errInvalidAnnotation) {
+		t.Errorf("expected errInvalidAnnotation, got %v", err)
+	}
+	if want := []string{"db.team-a.svc." + clusterDomain}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReconcile_ExpandShortHostnames(t *testing.T) {
+	vmi := newTestVMI("myvm", map[string]string{annotationHostname: "myvm,myvm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.ExpandShortHostnames = true
+	r.ClusterDomain = "corp.internal"
+
+	reconcileVMI(t, r, "myvm")
+
+	var names []string
+	for _, e := range getEndpoint(t, r, "myvm").Spec.Endpoints {
+		names = append(names, e.DNSName)
+	}
+	slices.Sort(names)
+	if want := []string{"myvm.default.svc.corp.internal", "myvm.example.com"}; !slices.Equal(names, want) {
+		t.Errorf("DNS names = %v, want %v", names, want)
+	}
+}
+
+func TestReconcile_ExpandShortHostnamesSkipsInvalid(t *testing.T) {
+	long := strings.Repeat("a", 63)
+	vmi := newTestVMI("myvm", map[string]string{annotationHostname: long + ",myvm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.ExpandShortHostnames = true
+	r.ClusterDomain = strings.Repeat(strings.Repeat("c", 60)+".", 3) + "local"
+
+	reconcileVMI(t, r, "myvm")
+
+	var names []string
+	for _, e := range getEndpoint(t, r, "myvm").Spec.Endpoints {
+		names = append(names, e.DNSName)
+	}
+	if want := []string{"myvm.example.com"}; !slices.Equal(names, want) {
+		t.Errorf("DNS names = %v, want %v", names, want)
+	}
+	events := recordedEvents(r)
+	if !slices.ContainsFunc(events, func(e string) bool { return strings.Contains(e, eventReasonInvalidHostname) }) {
+		t.Errorf("expected an %s event, got %v", eventReasonInvalidHostname, events)
+	}
+}
+
+func TestFilterHostnamesByZone(t *testing.T) {
+	hostnames := []string{"a.vms.example.com", "b.example.com", "c.lab.vms.example.com.", "d.other.org"}
+	tests := []struct {
+		name        string
+		allow, deny []string
+		want        []string
+	}{
+		{"empty lists", nil, nil, hostnames},
+		{"allowlist only", []string{"vms.example.com"}, nil, []string{"a.vms.example.com", "c.lab.vms.example.com."}},
+		{"denylist only", nil, []string{"lab.vms.example.com", "other.org"}, []string{"a.vms.example.com", "b.example.com"}},
+		{"combined", []string{"example.com"}, []string{"lab.vms.example.com"}, []string{"a.vms.example.com", "b.example.com"}},
+	}
+	for _, tt := range tests {
+		kept, rejected := filterHostnamesByZone(hostnames, tt.allow, tt.deny)
+		if !slices.Equal(kept, tt.want) {
+			t.Errorf("%s: kept %v, want %v", tt.name, kept, tt.want)
+		}
+		if len(kept)+len(rejected) != len(hostnames) {
+			t.Errorf("%s: kept %v and rejected %v do not cover the input", tt.name, kept, rejected)
+		}
+	}
+}
+
+func TestInAnyZone_NoPartialLabelMatch(t *testing.T) {
+	if inAnyZone("vm.notexample.com", []string{"example.com"}) {
+		t.Error("expected notexample.com to be outside example.com")
+	}
+	if !inAnyZone("VM.Example.COM", []string{"example.com"}) {
+		t.Error("expected zone matching to be case-insensitive")
+	}
+}
+
+func TestParseZones(t *testing.T) {
+	got := ParseZones(" VMS.example.com., ,other.org")
+	if want := []string{"vms.example.com", "other.org"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReconcile_ZoneAllowlist(t *testing.T) {
+	vmi := newTestVMI("web-1", map[string]string{annotationHostname: "web-1.vms.example.com,web-1.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.ZoneAllowlist = []string{"vms.example.com"}
+
+	reconcileVMI(t, r, "web-1")
+
+	ep := getEndpoint(t, r, "web-1")
+	if len(ep.Spec.Endpoints) != 1 || ep.Spec.Endpoints[0].DNSName != "web-1.vms.example.com" {
+		t.Errorf("unexpected endpoints: %v", ep.Spec.Endpoints)
+	}
+}
+
+func TestReconcile_InvalidHostnameSkippedWithEvent(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm_1.example.com,vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	ep := getEndpoint(t, r, "vm")
+	if len(ep.Spec.Endpoints) != 1 || ep.Spec.Endpoints[0].DNSName != "vm.example.com" {
+		t.Errorf("expected only the valid hostname, got %v", ep.Spec.Endpoints)
+	}
+	assertEvents(t, recordedEvents(r), "Warning "+eventReasonInvalidHostname, "Normal "+eventReasonEndpointCreated)
+}
+
+func TestIsWildcardHostname(t *testing.T) {
+	tests := map[string]bool{
+		"*.example.com":    true,
+		"*.":               true,
+		"vm.example.com":   false,
+		"vm.*.example.com": false,
+		"*example.com":     false,
+		"":                 false,
+	}
+	for name, want := range tests {
+		if got := isWildcardHostname(name); got != want {
+			t.Errorf("isWildcardHostname(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestReconcile_Wildcards(t *testing.T) {
+	for _, reject := range []bool{false, true} {
+		vmi := newTestVMI("vm", map[string]string{annotationHostname: "*.example.com,vm.example.com"},
+			kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+		r := newTestReconciler(t, vmi)
+		r.RejectWildcards = reject
+
+		reconcileVMI(t, r, "vm")
+
+		var names []string
+		for _, e := range getEndpoint(t, r, "vm").Spec.Endpoints {
+			names = append(names, e.DNSName)
+		}
+		want := []string{"*.example.com", "vm.example.com"}
+		wantEvents := []string{"Normal " + eventReasonEndpointCreated}
+		if reject {
+			want = []string{"vm.example.com"}
+			wantEvents = append([]string{"Warning " + eventReasonWildcardRejected}, wantEvents...)
+		}
+		if !slices.Equal(names, want) {
+			t.Errorf("reject=%v: published %v, want %v", reject, names, want)
+		}
+		assertEvents(t, recordedEvents(r), wantEvents...)
+	}
+}
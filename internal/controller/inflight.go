@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// InFlightTracker counts reconciles that are currently running so shutdown can
+// wait for them to finish. The zero value is ready to use.
+type InFlightTracker struct {
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	active map[types.NamespacedName]int
+}
+
+// Begin marks a reconcile of key as started. The returned function marks it
+// as finished and must be called exactly once.
+func (t *InFlightTracker) Begin(key types.NamespacedName) func() {
+	t.wg.Add(1)
+	t.mu.Lock()
+	if t.active == nil {
+		t.active = map[types.NamespacedName]int{}
+	}
+	t.active[key]++
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		if t.active[key]--; t.active[key] == 0 {
+			delete(t.active, key)
+		}
+		t.mu.Unlock()
+		t.wg.Done()
+	}
+}
+
+// Wait blocks until every reconcile has finished or timeout elapses. It returns
+// the sorted keys of the reconciles still running, or nil if all finished.
+func (t *InFlightTracker) Wait(timeout time.Duration) []string {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	remaining := make([]string, 0, len(t.active))
+	for key := range t.active {
+		remaining = append(remaining, key.String())
+	}
+	sort.Strings(remaining)
+	return remaining
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestInFlightTracker_WaitDrains(t *testing.T) {
+	var tr InFlightTracker
+	done := tr.Begin(types.NamespacedName{Namespace: "default", Name: "vm1"})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		done()
+	}()
+
+	if remaining := tr.Wait(5 * time.Second); remaining != nil {
+		t.Errorf("expected all reconciles to finish, still running: %v", remaining)
+	}
+}
+
+func TestInFlightTracker_WaitTimesOut(t *testing.T) {
+	var tr InFlightTracker
+	tr.Begin(types.NamespacedName{Namespace: "default", Name: "vm2"})
+	tr.Begin(types.NamespacedName{Namespace: "default", Name: "vm1"})
+	tr.Begin(types.NamespacedName{Namespace: "other", Name: "vm3"})()
+
+	remaining := tr.Wait(20 * time.Millisecond)
+	if want := []string{"default/vm1", "default/vm2"}; !slices.Equal(remaining, want) {
+		t.Errorf("got %v, want %v", remaining, want)
+	}
+}
+
+func TestReconcile_TracksInFlight(t *testing.T) {
+	r := newTestReconciler(t)
+	r.InFlight = &InFlightTracker{}
+
+	reconcileVMI(t, r, "missing")
+
+	if remaining := r.InFlight.Wait(time.Second); remaining != nil {
+		t.Errorf("expected reconcile to be marked finished, still running: %v", remaining)
+	}
+}
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// annotationInterfaceDNSMap maps interface names to hostnames as a JSON
+	// object, publishing each interface's addresses in its own DNSEndpoint.
+	annotationInterfaceDNSMap = defaultAnnotationPrefix + "interface-dns-map"
+	// labelInterface marks a per-interface DNSEndpoint with its interface name.
+	labelInterface = managedAnnotationPrefix + "interface"
+)
+
+// parseInterfaceDNSMap parses the interface-dns-map annotation, a JSON object
+// of interface name (status.interfaces[].interfaceName) to comma-separated
+// hostnames, for example {"eth0":"mgmt.example.com","net1":"data.example.com"}.
+// Entries whose interface name cannot be part of a DNSEndpoint name, or whose
+// hostname is empty, are dropped and reported through the returned error,
+// which wraps errInvalidAnnotation. An empty annotation yields a nil map.
+func parseInterfaceDNSMap(raw string) (map[string]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var entries map[string]string
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("%w: interface DNS map is not a JSON object of strings: %v", errInvalidAnnotation, err)
+	}
+
+	var errs []error
+	m := make(map[string]string, len(entries))
+	for iface, hostnames := range entries {
+		iface = strings.TrimSpace(iface)
+		if msgs := validation.IsDNS1123Label(iface); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("%w: interface name %q: %s", errInvalidAnnotation, iface, strings.Join(msgs, "; ")))
+			continue
+		}
+		if strings.TrimSpace(hostnames) == "" {
+			errs = append(errs, fmt.Errorf("%w: empty hostname for interface %q", errInvalidAnnotation, iface))
+			continue
+		}
+		m[iface] = hostnames
+	}
+	return m, errors.Join(errs...)
+}
+
+// interfaceEndpointKey returns the key of the DNSEndpoint for one of the VMI's
+// interfaces: <vmi-name>-<iface> in the VMI's namespace.
+func interfaceEndpointKey(vmi *kubevirtv1.VirtualMachineInstance, iface string) client.ObjectKey {
+	return client.ObjectKey{Namespace: vmi.Namespace, Name: vmi.Name + "-" + iface}
+}
+
+// hasInterfaceDNSMap reports whether the VMI sets the interface-dns-map annotation.
+func hasInterfaceDNSMap(vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings) bool {
+	return strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationInterfaceDNSMap)]) != ""
+}
+
+// interfaceIPs returns the addresses of the VMI's interface named iface,
+// chosen from the configured sources and filtered like the VMI's other addresses.
+func (r *VirtualMachineInstanceReconciler) interfaceIPs(vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings, iface string) (ipv4, ipv6 []string) {
+	// Invalid filter annotations have already been reported by resolveIPs.
+	filter, _ := ipFilterFor(vmi.Annotations, settings)
+	filter.publicOnly = r.PublicIPsOnly
+	filter.interfaceNames = []string{iface}
+	ipv4, ipv6, _ = extractBestIPs(vmi, filter, r.IPSourcePriority)
+	ipv4, ipv6 = settings.filterIPFamily(ipv4, ipv6)
+	if r.IPv4Only {
+		ipv6 = nil
+	}
+	if r.IPv6Only {
+		ipv4 = nil
+	}
+	return ipv4, ipv6
+}
+
+// reconcileInterfaceEndpoints creates or updates one DNSEndpoint per entry of
+// the interface-dns-map annotation, holding only that interface's addresses.
+// Entries whose interface reports no addresses are left unpublished, entries
+// whose hostnames another VMI has the better claim to are reported and left as
+// they are, and the DNSEndpoints of interfaces no longer in the map are deleted.
+func (r *VirtualMachineInstanceReconciler) reconcileInterfaceEndpoints(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings, ttl dnsendpointv1alpha1.TTL) error {
+	dnsMap, err := parseInterfaceDNSMap(vmi.Annotations[settings.annotationKey(annotationInterfaceDNSMap)])
+	if err != nil {
+		log.FromContext(ctx).Info("ignoring invalid entries in interface-dns-map annotation", "vmi", client.ObjectKeyFromObject(vmi), "error", err.Error())
+		countReconcileError(err)
+	}
+
+	var errs []error
+	published := map[string]bool{}
+	ifaces := make([]string, 0, len(dnsMap))
+	for iface := range dnsMap {
+		ifaces = append(ifaces, iface)
+	}
+	slices.Sort(ifaces)
+	for _, iface := range ifaces {
+		key := interfaceEndpointKey(vmi, iface)
+		hostnames := r.secondaryHostnames(ctx, vmi, settings, key, dnsMap[iface])
+		ipv4, ipv6 := r.interfaceIPs(vmi, settings, iface)
+		if len(hostnames) == 0 || len(ipv4)+len(ipv6) == 0 {
+			continue
+		}
+		published[iface] = true
+		if conflicted, err := r.secondaryHostnameConflict(ctx, vmi, key, hostnames); conflicted || err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, r.writeSecondaryEndpoint(ctx, vmi, settings, key, map[string]string{labelInterface: iface},
+			buildEndpoints(hostnames, ipv4, ipv6, ttl, nil, nil, "")))
+	}
+	errs = append(errs, r.deleteInterfaceEndpointsExcept(ctx, vmi, published))
+	return errors.Join(errs...)
+}
+
+// deleteInterfaceEndpointsExcept deletes the VMI's per-interface DNSEndpoints
+// whose interface is not in keep.
+func (r *VirtualMachineInstanceReconciler) deleteInterfaceEndpointsExcept(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, keep map[string]bool) error {
+	list := &dnsendpointv1alpha1.DNSEndpointList{}
+	if err := r.endpoints().List(ctx, list, client.InNamespace(vmi.Namespace), client.HasLabels{labelInterface}); err != nil {
+		return err
+	}
+	var stale []*dnsendpointv1alpha1.DNSEndpoint
+	for i := range list.Items {
+		ep := &list.Items[i]
+		if owner, uid, ok := endpointOwner(ep); !ok || owner != client.ObjectKeyFromObject(vmi) || uid != vmi.UID {
+			continue
+		}
+		if !keep[ep.Labels[labelInterface]] {
+			stale = append(stale, ep)
+		}
+	}
+	return r.deleteEndpoints(ctx, vmi, stale...)
+}
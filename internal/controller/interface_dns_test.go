@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+func TestParseInterfaceDNSMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", raw: "  "},
+		{
+			name: "two interfaces",
+			raw:  `{"eth0":"mgmt.example.com","net1":"data.example.com"}`,
+			want: map[string]string{"eth0": "mgmt.example.com", "net1": "data.example.com"},
+		},
+		{
+			name: "several hostnames",
+			raw:  `{"eth0":"a.example.com, b.example.com"}`,
+			want: map[string]string{"eth0": "a.example.com, b.example.com"},
+		},
+		{name: "not JSON", raw: `eth0=mgmt.example.com`, wantErr: true},
+		{name: "non-string value", raw: `{"eth0":1}`, wantErr: true},
+		{
+			name:    "invalid interface name dropped",
+			raw:     `{"Eth_0":"bad.example.com","eth1":"ok.example.com"}`,
+			want:    map[string]string{"eth1": "ok.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "empty hostname dropped",
+			raw:     `{"eth0":" ","eth1":"ok.example.com"}`,
+			want:    map[string]string{"eth1": "ok.example.com"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInterfaceDNSMap(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errInvalidAnnotation) {
+				t.Errorf("error %v does not wrap errInvalidAnnotation", err)
+			}
+			if len(got) != 0 || len(tt.want) != 0 {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// newMultiHomedVMI returns a VMI with a management and a data interface.
+func newMultiHomedVMI(annotations map[string]string) *kubevirtv1.VirtualMachineInstance {
+	return newTestVMI("vm", annotations,
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{InterfaceName: "eth0", IPs: []string{"10.0.0.1"}, InfoSource: guestAgentInfoSource},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{InterfaceName: "net1", IPs: []string{"192.168.1.1", "fd00::1"}, InfoSource: guestAgentInfoSource},
+	)
+}
+
+func TestReconcile_InterfaceDNSMapCreatesEndpoints(t *testing.T) {
+	vmi := newMultiHomedVMI(map[string]string{
+		annotationHostname:        "vm.example.com",
+		annotationInterfaceDNSMap: `{"eth0":"mgmt.example.com","net1":"data.example.com"}`,
+	})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	if got := endpointTargets(getEndpoint(t, r, "vm"))["vm.example.com A"]; len(got) != 2 {
+		t.Errorf("main targets = %v, want the addresses of both interfaces", got)
+	}
+	mgmt := getEndpoint(t, r, "vm-eth0")
+	if got := endpointTargets(mgmt); !reflect.DeepEqual(got, map[string][]string{"mgmt.example.com A": {"10.0.0.1"}}) {
+		t.Errorf("eth0 targets = %v", got)
+	}
+	if mgmt.Labels[labelInterface] != "eth0" {
+		t.Errorf("eth0 %s label = %q", labelInterface, mgmt.Labels[labelInterface])
+	}
+	if len(mgmt.OwnerReferences) != 1 || mgmt.OwnerReferences[0].UID != vmi.UID {
+		t.Errorf("expected the VMI to own the eth0 DNSEndpoint, got %v", mgmt.OwnerReferences)
+	}
+	want := map[string][]string{"data.example.com A": {"192.168.1.1"}, "data.example.com AAAA": {"fd00::1"}}
+	if got := endpointTargets(getEndpoint(t, r, "vm-net1")); !reflect.DeepEqual(got, want) {
+		t.Errorf("net1 targets = %v, want %v", got, want)
+	}
+}
+
+func TestReconcile_InterfaceDNSMapDeletesEndpoints(t *testing.T) {
+	ctx := context.Background()
+	vmi := newMultiHomedVMI(map[string]string{
+		annotationInterfaceDNSMap: `{"eth0":"mgmt.example.com","net1":"data.example.com"}`,
+	})
+	r := newTestReconciler(t, vmi)
+	reconcileVMI(t, r, "vm")
+	getEndpoint(t, r, "vm-net1")
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	got.Annotations[annotationInterfaceDNSMap] = `{"eth0":"mgmt.example.com"}`
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+
+	if lookupEndpoint(t, r, "vm-net1") != nil {
+		t.Error("expected the net1 DNSEndpoint to be deleted once dropped from the map")
+	}
+	getEndpoint(t, r, "vm-eth0")
+
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	delete(got.Annotations, annotationInterfaceDNSMap)
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+
+	if lookupEndpoint(t, r, "vm-eth0") != nil {
+		t.Error("expected the eth0 DNSEndpoint to be deleted with the annotation")
+	}
+}
+
+func TestReconcile_InterfaceDNSMapSkipsInterfaceWithoutIPs(t *testing.T) {
+	vmi := newMultiHomedVMI(map[string]string{
+		annotationInterfaceDNSMap: `{"eth0":"mgmt.example.com","net9":"missing.example.com"}`,
+	})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	getEndpoint(t, r, "vm-eth0")
+	if lookupEndpoint(t, r, "vm-net9") != nil {
+		t.Error("expected no DNSEndpoint for an interface without addresses")
+	}
+}
+
+func TestReconcile_InterfaceDNSMapHostnameConflict(t *testing.T) {
+	iface := kubevirtv1.VirtualMachineInstanceNetworkInterface{InterfaceName: "eth0", IPs: []string{"10.0.0.5"}, InfoSource: guestAgentInfoSource}
+	incumbent := newTestVMI("incumbent", map[string]string{annotationInterfaceDNSMap: `{"eth0":"mgmt.example.com"}`}, iface)
+	r := newTestReconciler(t, incumbent)
+	reconcileVMI(t, r, "incumbent")
+
+	newcomer := newTestVMI("newcomer", map[string]string{annotationInterfaceDNSMap: `{"eth0":"MGMT.example.com."}`}, iface)
+	if err := r.Create(context.Background(), newcomer); err != nil {
+		t.Fatal(err)
+	}
+	recordedEvents(r)
+	reconcileVMI(t, r, "newcomer")
+
+	if lookupEndpoint(t, r, "newcomer-eth0") != nil {
+		t.Error("expected no eth0 DNSEndpoint for a hostname another VMI publishes")
+	}
+	if got := endpointTargets(getEndpoint(t, r, "incumbent-eth0")); !reflect.DeepEqual(got, map[string][]string{"mgmt.example.com A": {"10.0.0.5"}}) {
+		t.Errorf("incumbent eth0 targets = %v", got)
+	}
+	assertEvents(t, recordedEvents(r), "Warning "+eventReasonHostnameConflict, "Warning "+eventReasonHostnameConflict)
+}
+
+func TestReconcile_InterfaceDNSMapCopiesVMILabels(t *testing.T) {
+	vmi := newMultiHomedVMI(map[string]string{
+		annotationInterfaceDNSMap:      `{"eth0":"mgmt.example.com"}`,
+		annotationPropagateAnnotations: "team",
+		"team":                         "platform",
+	})
+	vmi.Labels = map[string]string{"app": "web", labelInterface: "net1"}
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	mgmt := getEndpoint(t, r, "vm-eth0")
+	if mgmt.Labels["app"] != "web" {
+		t.Errorf("expected the VMI's labels on the eth0 DNSEndpoint, got %v", mgmt.Labels)
+	}
+	if mgmt.Labels[labelInterface] != "eth0" {
+		t.Errorf("%s label = %q, want the marker to win over the VMI's label", labelInterface, mgmt.Labels[labelInterface])
+	}
+	if mgmt.Annotations["team"] != "platform" {
+		t.Errorf("expected the propagated annotation on the eth0 DNSEndpoint, got %v", mgmt.Annotations)
+	}
+}
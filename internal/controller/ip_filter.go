@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	// annotationAllowedCIDRs restricts published IPs to the listed networks (comma-separated CIDRs).
+	annotationAllowedCIDRs = defaultAnnotationPrefix + "allowed-cidrs"
+	// annotationPreferredCIDR selects a single network whose addresses win over all others.
+	annotationPreferredCIDR = defaultAnnotationPrefix + "preferred-cidr"
+	// annotationInterfaceNames limits IP discovery to the named interfaces (comma-separated).
+	annotationInterfaceNames = defaultAnnotationPrefix + "interface-names"
+)
+
+// ipFilter decides which discovered addresses may be published for a VMI.
+// The zero value allows every address.
+type ipFilter struct {
+	// allowedCIDRs, when non-empty, limits addresses to these networks.
+	allowedCIDRs []*net.IPNet
+	// preferredCIDR, when set, narrows each family to matching addresses if there are any.
+	preferredCIDR *net.IPNet
+	// publicOnly drops private and loopback addresses (see isPrivateIP).
+	publicOnly bool
+	// interfaceNames, when non-empty, limits discovery to interfaces with these names.
+	interfaceNames []string
+}
+
+// selectInterfaces returns vmi with its status interfaces narrowed to
+// interfaceNames. The VMI is returned unchanged when no names are configured.
+func (f ipFilter) selectInterfaces(vmi *kubevirtv1.VirtualMachineInstance) *kubevirtv1.VirtualMachineInstance {
+	if len(f.interfaceNames) == 0 {
+		return vmi
+	}
+	selected := *vmi
+	selected.Status.Interfaces = nil
+	for _, iface := range vmi.Status.Interfaces {
+		if slices.Contains(f.interfaceNames, iface.InterfaceName) {
+			selected.Status.Interfaces = append(selected.Status.Interfaces, iface)
+		}
+	}
+	return &selected
+}
+
+// allows reports whether ip passes every configured rule.
+func (f ipFilter) allows(ip net.IP) bool {
+	if f.publicOnly && isPrivateIP(ip) {
+		return false
+	}
+	if len(f.allowedCIDRs) > 0 && !ipMatchesCIDRs(ip, f.allowedCIDRs) {
+		return false
+	}
+	return true
+}
+
+// apply drops the addresses rejected by allows and then narrows each family
+// to the preferred CIDR.
+func (f ipFilter) apply(ipv4, ipv6 []string) ([]string, []string) {
+	return filterByPreferredCIDR(f.filter(ipv4), f.preferredCIDR), filterByPreferredCIDR(f.filter(ipv6), f.preferredCIDR)
+}
+
+// filter returns the addresses in addrs that pass allows.
+func (f ipFilter) filter(addrs []string) []string {
+	var kept []string
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && f.allows(ip) {
+			kept = append(kept, addr)
+		}
+	}
+	return kept
+}
+
+// isPrivateIP reports whether ip is in an RFC 1918 (10/8, 172.16/12, 192.168/16)
+// or RFC 4193 (fc00::/7) private range, or is a loopback address.
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback()
+}
+
+// ipFilterFor builds the filter for a VMI from its annotations. Invalid entries
+// are dropped and reported through the returned error, which wraps errInvalidAnnotation.
+func ipFilterFor(annotations map[string]string, settings ControllerSettings) (ipFilter, error) {
+	var f ipFilter
+	var errs []error
+
+	cidrs, err := parseCIDRList(annotations[settings.annotationKey(annotationAllowedCIDRs)])
+	f.allowedCIDRs = cidrs
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if raw := strings.TrimSpace(annotations[settings.annotationKey(annotationPreferredCIDR)]); raw != "" {
+		_, preferred, err := net.ParseCIDR(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: invalid preferred CIDR %q", errInvalidAnnotation, raw))
+		} else {
+			f.preferredCIDR = preferred
+		}
+	}
+
+	f.interfaceNames = parseInterfaceNames(annotations[settings.annotationKey(annotationInterfaceNames)])
+
+	return f, errors.Join(errs...)
+}
+
+// parseInterfaceNames splits a comma-separated list of interface names,
+// trimming whitespace and dropping empty entries.
+func parseInterfaceNames(raw string) []string {
+	var names []string
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// filterByPreferredCIDR returns only the addresses inside cidr, or all of them
+// when none match (or cidr is nil).
+func filterByPreferredCIDR(addrs []string, cidr *net.IPNet) []string {
+	if cidr == nil {
+		return addrs
+	}
+	var preferred []string
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && cidr.Contains(ip) {
+			preferred = append(preferred, addr)
+		}
+	}
+	if len(preferred) == 0 {
+		return addrs
+	}
+	return preferred
+}
+
+// parseCIDRList parses a comma-separated list of CIDRs. Valid entries are
+// returned even when some entries fail to parse.
+func parseCIDRList(raw string) ([]*net.IPNet, error) {
+	var cidrs []*net.IPNet
+	var invalid []string
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(part)
+		if err != nil {
+			invalid = append(invalid, part)
+			continue
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	if len(invalid) > 0 {
+		return cidrs, fmt.Errorf("%w: invalid CIDRs %q", errInvalidAnnotation, invalid)
+	}
+	return cidrs, nil
+}
+
+// ipMatchesCIDRs reports whether ip is contained in any of the given networks.
+func ipMatchesCIDRs(ip net.IP, cidrs []*net.IPNet) bool {
+	for _, cidr := range cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
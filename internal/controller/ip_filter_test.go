@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+func mustParseCIDRs(t *testing.T, raw string) []*net.IPNet {
+	t.Helper()
+	cidrs, err := parseCIDRList(raw)
+	if err != nil {
+		t.Fatalf("parseCIDRList(%q): %v", raw, err)
+	}
+	return cidrs
+}
+
+func TestParseCIDRList(t *testing.T) {
+	cidrs, err := parseCIDRList(" 10.100.0.0/16 , 2001:db8::/32,,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cidrs) != 2 {
+		t.Fatalf("expected 2 CIDRs, got %v", cidrs)
+	}
+
+	cidrs, err = parseCIDRList("10.0.0.0/8,not-a-cidr,10.1.2.3")
+	if !errors.Is(err, errInvalidAnnotation) {
+		t.Errorf("expected errInvalidAnnotation, got %v", err)
+	}
+	if len(cidrs) != 1 || cidrs[0].String() != "10.0.0.0/8" {
+		t.Errorf("expected the valid entry to be kept, got %v", cidrs)
+	}
+
+	if cidrs, err := parseCIDRList(""); err != nil || len(cidrs) != 0 {
+		t.Errorf("expected empty result for empty input, got %v, %v", cidrs, err)
+	}
+}
+
+func TestIPMatchesCIDRs(t *testing.T) {
+	tests := []struct {
+		name  string
+		cidrs string
+		ip    string
+		want  bool
+	}{
+		{"ipv4 match", "10.100.0.0/16", "10.100.3.4", true},
+		{"ipv4 miss", "10.100.0.0/16", "10.101.0.1", false},
+		{"ipv6 match", "2001:db8::/32", "2001:db8:1::5", true},
+		{"ipv6 miss", "2001:db8::/32", "2001:db9::1", false},
+		{"mixed ipv4", "10.100.0.0/16,2001:db8::/32", "10.100.0.1", true},
+		{"mixed ipv6", "10.100.0.0/16,2001:db8::/32", "2001:db8::1", true},
+		{"ipv4 against ipv6 only", "2001:db8::/32", "10.100.0.1", false},
+		{"empty list", "", "10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		got := ipMatchesCIDRs(net.ParseIP(tt.ip), mustParseCIDRs(t, tt.cidrs))
+		if got != tt.want {
+			t.Errorf("%s: ipMatchesCIDRs(%s, %s) = %v, want %v", tt.name, tt.ip, tt.cidrs, got, tt.want)
+		}
+	}
+}
+
+func TestIPFilter_EmptyAllowsEverything(t *testing.T) {
+	if !(ipFilter{}).allows(net.ParseIP("192.0.2.1")) {
+		t.Error("expected zero-value filter to allow every address")
+	}
+}
+
+func TestExtractBestIPs_AllowedCIDRs(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{
+			IP:         "10.244.0.5",
+			IPs:        []string{"10.244.0.5", "10.100.0.7", "2001:db8::7", "fd00::7"},
+			InfoSource: "guest-agent, multus-status",
+		},
+	}
+	filter := ipFilter{allowedCIDRs: mustParseCIDRs(t, "10.100.0.0/16,2001:db8::/32")}
+
+	v4, v6, source := extractBestIPs(vmi, filter, nil)
+	if source != guestAgentInfoSource {
+		t.Errorf("expected guest-agent source, got %q", source)
+	}
+	if len(v4) != 1 || v4[0] != "10.100.0.7" {
+		t.Errorf("unexpected v4: %v", v4)
+	}
+	if len(v6) != 1 || v6[0] != "2001:db8::7" {
+		t.Errorf("unexpected v6: %v", v6)
+	}
+
+	// multus only reports the pod-network address, which is filtered out.
+	mV4, mV6 := filter.apply(extractMultusIPs(vmi))
+	if len(mV4) != 0 || len(mV6) != 0 {
+		t.Errorf("expected multus IPs to be filtered out, got v4=%v v6=%v", mV4, mV6)
+	}
+}
+
+func TestFilterByPreferredCIDR(t *testing.T) {
+	_, mgmt, _ := net.ParseCIDR("192.168.10.0/24")
+	addrs := []string{"10.0.0.5", "192.168.10.5", "192.168.10.6"}
+
+	got := filterByPreferredCIDR(addrs, mgmt)
+	if len(got) != 2 || got[0] != "192.168.10.5" || got[1] != "192.168.10.6" {
+		t.Errorf("expected only management addresses, got %v", got)
+	}
+
+	_, other, _ := net.ParseCIDR("172.16.0.0/12")
+	if got := filterByPreferredCIDR(addrs, other); len(got) != 3 {
+		t.Errorf("expected all addresses when nothing matches, got %v", got)
+	}
+
+	if got := filterByPreferredCIDR(addrs, nil); len(got) != 3 {
+		t.Errorf("expected all addresses without a preferred CIDR, got %v", got)
+	}
+}
+
+func TestExtractBestIPs_PreferredCIDR(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{IPs: []string{"10.0.0.5", "2001:db8::5"}, InfoSource: guestAgentInfoSource},
+		{IPs: []string{"192.168.10.5"}, InfoSource: guestAgentInfoSource},
+	}
+	filter, err := ipFilterFor(map[string]string{annotationPreferredCIDR: "192.168.10.0/24"}, DefaultSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v4, v6, _ := extractBestIPs(vmi, filter, nil)
+	if len(v4) != 1 || v4[0] != "192.168.10.5" {
+		t.Errorf("expected only the preferred IPv4, got %v", v4)
+	}
+	// No IPv6 address matches the IPv4 preferred CIDR, so all IPv6 addresses remain.
+	if len(v6) != 1 || v6[0] != "2001:db8::5" {
+		t.Errorf("expected IPv6 to be untouched, got %v", v6)
+	}
+}
+
+func TestIPFilterFor_InvalidPreferredCIDR(t *testing.T) {
+	filter, err := ipFilterFor(map[string]string{annotationPreferredCIDR: "192.168.10.0"}, DefaultSettings())
+	if !errors.Is(err, errInvalidAnnotation) {
+		t.Errorf("expected errInvalidAnnotation, got %v", err)
+	}
+	if filter.preferredCIDR != nil {
+		t.Errorf("expected no preferred CIDR, got %v", filter.preferredCIDR)
+	}
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.0.0.0", true},
+		{"10.255.255.255", true},
+		{"11.0.0.0", false},
+		{"172.15.255.255", false},
+		{"172.16.0.0", true},
+		{"172.31.255.255", true},
+		{"172.32.0.0", false},
+		{"192.167.255.255", false},
+		{"192.168.0.0", true},
+		{"192.168.255.255", true},
+		{"192.169.0.0", false},
+		{"127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"fbff:ffff::1", false},
+		{"fc00::", true},
+		{"fd12:3456::1", true},
+		{"fdff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", true},
+		{"fe00::1", false},
+		{"::1", true},
+		{"2001:db8::1", false},
+	}
+	for _, tt := range tests {
+		if got := isPrivateIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPrivateIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestExtractBestIPs_PublicOnly(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{IPs: []string{"10.0.0.5", "fd00::5"}, InfoSource: guestAgentInfoSource},
+		{IP: "192.168.1.5", InfoSource: multusInfoSource},
+		{IP: "203.0.113.5", InfoSource: multusInfoSource},
+	}
+	filter := ipFilter{publicOnly: true}
+
+	// Every guest-agent address is private, so extraction falls back to multus.
+	v4, v6, source := extractBestIPs(vmi, filter, nil)
+	if source != multusInfoSource {
+		t.Errorf("expected fallback to multus-status, got %q", source)
+	}
+	if len(v4) != 1 || v4[0] != "203.0.113.5" || len(v6) != 0 {
+		t.Errorf("expected only the public address, got v4=%v v6=%v", v4, v6)
+	}
+}
+
+func TestParseInterfaceNames(t *testing.T) {
+	if got := parseInterfaceNames(" eth0, ,net1,"); !reflect.DeepEqual(got, []string{"eth0", "net1"}) {
+		t.Errorf("unexpected names: %v", got)
+	}
+	if got := parseInterfaceNames(""); got != nil {
+		t.Errorf("expected nil for empty input, got %v", got)
+	}
+}
+
+func TestExtractBestIPs_InterfaceNames(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{InterfaceName: "eth0", IPs: []string{"10.0.0.5"}, InfoSource: guestAgentInfoSource},
+		{InterfaceName: "eth1", IPs: []string{"192.168.10.5"}, InfoSource: guestAgentInfoSource},
+		{InterfaceName: "net1", IP: "172.16.0.5", InfoSource: multusInfoSource},
+	}
+	tests := []struct {
+		name       string
+		annotation string
+		wantV4     []string
+		wantSource string
+	}{
+		{"absent annotation", "", []string{"10.0.0.5", "192.168.10.5"}, guestAgentInfoSource},
+		{"single interface", "eth1", []string{"192.168.10.5"}, guestAgentInfoSource},
+		{"multiple interfaces", "eth0, eth1", []string{"10.0.0.5", "192.168.10.5"}, guestAgentInfoSource},
+		{"multus interface", "net1", []string{"172.16.0.5"}, multusInfoSource},
+		{"no match", "eth9", nil, ""},
+	}
+	for _, tt := range tests {
+		filter, err := ipFilterFor(map[string]string{annotationInterfaceNames: tt.annotation}, DefaultSettings())
+		if err != nil {
+			t.Fatal(err)
+		}
+		v4, _, source := extractBestIPs(vmi, filter, nil)
+		if !reflect.DeepEqual(v4, tt.wantV4) || source != tt.wantSource {
+			t.Errorf("%s: got v4=%v source=%q, want v4=%v source=%q", tt.name, v4, source, tt.wantV4, tt.wantSource)
+		}
+	}
+	if len(vmi.Status.Interfaces) != 3 {
+		t.Errorf("expected the VMI's interfaces to be left untouched, got %v", vmi.Status.Interfaces)
+	}
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	// annotationStaticIP lists the addresses to publish, bypassing interface discovery.
+	annotationStaticIP = defaultAnnotationPrefix + "static-ip"
+	// staticIPSource is reported as the IP source when annotationStaticIP is used.
+	staticIPSource = "static-ip"
+	// annotationIPPool lists the addresses an IPAM controller allocated to the
+	// VMI, such as floating IPs. They are published instead of interface
+	// addresses; an empty pool falls back to interface discovery.
+	annotationIPPool = defaultAnnotationPrefix + "ip-pool"
+	// ipPoolSource is reported as the IP source when annotationIPPool is used.
+	ipPoolSource = "ip-pool"
+)
+
+// IPExtractor reads the addresses of interfaces reported through one
+// infoSource. Extract is only called for interfaces whose infoSource contains
+// Name(). Filtering (allowed CIDRs, public-only, preferred CIDR) is applied by
+// the caller, so extractors should return every usable address.
+type IPExtractor interface {
+	// Name is the infoSource value the extractor handles, e.g. "guest-agent".
+	Name() string
+	// Extract returns the IPv4 and IPv6 addresses of a single interface.
+	Extract(iface kubevirtv1.VirtualMachineInstanceNetworkInterface) (ipv4, ipv6 []string)
+}
+
+// GuestAgentExtractor reads the full iface.IPs list reported by the QEMU guest
+// agent, which includes global IPv6 unicast addresses. Link-local addresses
+// (169.254.0.0/16, fe80::/10) are skipped.
+type GuestAgentExtractor struct{}
+
+// Name implements IPExtractor.
+func (GuestAgentExtractor) Name() string { return guestAgentInfoSource }
+
+// Extract implements IPExtractor.
+func (GuestAgentExtractor) Extract(iface kubevirtv1.VirtualMachineInstanceNetworkInterface) (ipv4, ipv6 []string) {
+	for _, addr := range iface.IPs {
+		ipv4, ipv6 = appendIP(ipv4, ipv6, addr)
+	}
+	return
+}
+
+// MultusExtractor reads the single iface.IP field reported by multus-status.
+// Link-local addresses are skipped.
+type MultusExtractor struct{}
+
+// Name implements IPExtractor.
+func (MultusExtractor) Name() string { return multusInfoSource }
+
+// Extract implements IPExtractor.
+func (MultusExtractor) Extract(iface kubevirtv1.VirtualMachineInstanceNetworkInterface) (ipv4, ipv6 []string) {
+	return appendIP(nil, nil, iface.IP)
+}
+
+// SRIOVExtractor reads the addresses of SR-IOV interfaces, which KubeVirt
+// reports under either the "sriov" or the "device-info" infoSource. It reads
+// both iface.IPs and iface.IP. Link-local addresses are skipped.
+type SRIOVExtractor struct{}
+
+// Name implements IPExtractor.
+func (SRIOVExtractor) Name() string { return sriovInfoSource }
+
+// MatchesInfoSource implements infoSourceMatcher.
+func (SRIOVExtractor) MatchesInfoSource(infoSource string) bool {
+	return containsInfoSource(infoSource, sriovInfoSource) || containsInfoSource(infoSource, deviceInfoSource)
+}
+
+// Extract implements IPExtractor.
+func (SRIOVExtractor) Extract(iface kubevirtv1.VirtualMachineInstanceNetworkInterface) (ipv4, ipv6 []string) {
+	for _, addr := range iface.IPs {
+		ipv4, ipv6 = appendIP(ipv4, ipv6, addr)
+	}
+	return appendIP(ipv4, ipv6, iface.IP)
+}
+
+// infoSourceMatcher is implemented by extractors that handle interfaces
+// reported under more than one infoSource. extractIPs uses it instead of
+// matching Name().
+type infoSourceMatcher interface {
+	MatchesInfoSource(infoSource string) bool
+}
+
+// infoSourceExtractor is the generic extractor used for infoSource names
+// without a registered one. It reads iface.IPs, or iface.IP when IPs is empty.
+type infoSourceExtractor string
+
+// Name implements IPExtractor.
+func (e infoSourceExtractor) Name() string { return string(e) }
+
+// Extract implements IPExtractor.
+func (e infoSourceExtractor) Extract(iface kubevirtv1.VirtualMachineInstanceNetworkInterface) (ipv4, ipv6 []string) {
+	addrs := iface.IPs
+	if len(addrs) == 0 {
+		addrs = []string{iface.IP}
+	}
+	for _, addr := range addrs {
+		ipv4, ipv6 = appendIP(ipv4, ipv6, addr)
+	}
+	return
+}
+
+// DefaultIPSourcePriority is the infoSource order used when none is configured.
+var DefaultIPSourcePriority = []string{guestAgentInfoSource, sriovInfoSource, multusInfoSource}
+
+var (
+	ipExtractorsMu sync.RWMutex
+	ipExtractors   = map[string]IPExtractor{
+		guestAgentInfoSource: GuestAgentExtractor{},
+		sriovInfoSource:      SRIOVExtractor{},
+		multusInfoSource:     MultusExtractor{},
+	}
+)
+
+// RegisterIPExtractor registers e for the infoSource e.Name(), replacing any
+// extractor already registered under that name.
+func RegisterIPExtractor(e IPExtractor) {
+	ipExtractorsMu.Lock()
+	defer ipExtractorsMu.Unlock()
+	ipExtractors[e.Name()] = e
+}
+
+// IsRegisteredIPExtractor reports whether an extractor is registered for name.
+func IsRegisteredIPExtractor(name string) bool {
+	ipExtractorsMu.RLock()
+	defer ipExtractorsMu.RUnlock()
+	_, ok := ipExtractors[name]
+	return ok
+}
+
+// lookupIPExtractor returns the extractor registered for name, or the generic
+// infoSourceExtractor when there is none.
+func lookupIPExtractor(name string) IPExtractor {
+	ipExtractorsMu.RLock()
+	e, ok := ipExtractors[name]
+	ipExtractorsMu.RUnlock()
+	if ok {
+		return e
+	}
+	return infoSourceExtractor(name)
+}
+
+// extractIPs runs e over every interface of the VMI whose infoSource contains
+// e.Name(), or that e matches if it implements infoSourceMatcher.
+func extractIPs(vmi *kubevirtv1.VirtualMachineInstance, e IPExtractor) (ipv4, ipv6 []string) {
+	matcher, hasMatcher := e.(infoSourceMatcher)
+	for _, iface := range vmi.Status.Interfaces {
+		if hasMatcher && !matcher.MatchesInfoSource(iface.InfoSource) ||
+			!hasMatcher && !containsInfoSource(iface.InfoSource, e.Name()) {
+			continue
+		}
+		v4, v6 := e.Extract(iface)
+		ipv4 = append(ipv4, v4...)
+		ipv6 = append(ipv6, v6...)
+	}
+	return
+}
+
+// ParseIPSourcePriority parses a comma-separated list of infoSource names.
+// Empty and duplicate entries are rejected.
+func ParseIPSourcePriority(raw string) ([]string, error) {
+	var priority []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.TrimSpace(part)
+		if name == "" {
+			return nil, fmt.Errorf("empty infoSource name in %q", raw)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate infoSource name %q", name)
+		}
+		seen[name] = true
+		priority = append(priority, name)
+	}
+	return priority, nil
+}
+
+// parseStaticIPs splits a comma-separated list of addresses by family.
+// Unparseable entries are dropped and reported through the returned error,
+// which wraps errInvalidAnnotation. Link-local addresses are kept, since they
+// were set explicitly.
+func parseStaticIPs(raw string) (ipv4, ipv6 []string, err error) {
+	var invalid []string
+	for _, part := range strings.Split(raw, ",") {
+		addr := strings.TrimSpace(part)
+		if addr == "" {
+			continue
+		}
+		ip := net.ParseIP(addr)
+		switch {
+		case ip == nil:
+			invalid = append(invalid, addr)
+		case ip.To4() != nil:
+			ipv4 = append(ipv4, addr)
+		default:
+			ipv6 = append(ipv6, addr)
+		}
+	}
+	if len(invalid) > 0 {
+		err = fmt.Errorf("%w: invalid IPs %q", errInvalidAnnotation, invalid)
+	}
+	return deduplicateIPs(ipv4), deduplicateIPs(ipv6), err
+}
@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+func TestExtractBestIPs_CustomPriority(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{
+			IP:         "10.0.0.5",
+			IPs:        []string{"10.0.0.5", "192.168.1.5"},
+			InfoSource: "guest-agent, multus-status",
+		},
+	}
+	v4, _, source := extractBestIPs(vmi, ipFilter{}, []string{multusInfoSource, guestAgentInfoSource})
+	if source != multusInfoSource {
+		t.Errorf("expected multus-status to win, got %q", source)
+	}
+	if !slices.Equal(v4, []string{"10.0.0.5"}) {
+		t.Errorf("unexpected v4: %v", v4)
+	}
+}
+
+func TestExtractBestIPs_UnknownSourceUsesGenericExtractor(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{IP: "10.0.0.5", InfoSource: multusInfoSource},
+		{IPs: []string{"172.16.0.5", "fe80::5", "2001:db8::5"}, InfoSource: "ovs-cni"},
+	}
+	v4, v6, source := extractBestIPs(vmi, ipFilter{}, []string{"ovs-cni", multusInfoSource})
+	if source != "ovs-cni" {
+		t.Errorf("expected ovs-cni source, got %q", source)
+	}
+	if !slices.Equal(v4, []string{"172.16.0.5"}) || !slices.Equal(v6, []string{"2001:db8::5"}) {
+		t.Errorf("unexpected IPs: v4=%v v6=%v", v4, v6)
+	}
+
+	// A source no interface reports is skipped.
+	_, _, source = extractBestIPs(vmi, ipFilter{}, []string{"no-such-source", multusInfoSource})
+	if source != multusInfoSource {
+		t.Errorf("expected fallback to multus-status, got %q", source)
+	}
+}
+
+// fixedExtractor is a custom IPExtractor that reports a fixed address for every matching interface.
+type fixedExtractor struct {
+	name string
+	addr string
+}
+
+func (e fixedExtractor) Name() string { return e.name }
+
+func (e fixedExtractor) Extract(kubevirtv1.VirtualMachineInstanceNetworkInterface) (ipv4, ipv6 []string) {
+	return appendIP(nil, nil, e.addr)
+}
+
+// registerTestExtractor registers e for the duration of the test.
+func registerTestExtractor(t *testing.T, e IPExtractor) {
+	t.Helper()
+	RegisterIPExtractor(e)
+	t.Cleanup(func() {
+		ipExtractorsMu.Lock()
+		delete(ipExtractors, e.Name())
+		ipExtractorsMu.Unlock()
+	})
+}
+
+func TestRegisterIPExtractor(t *testing.T) {
+	const name = "test-static"
+	registerTestExtractor(t, fixedExtractor{name: name, addr: "198.51.100.7"})
+
+	if !IsRegisteredIPExtractor(name) {
+		t.Fatalf("expected %q to be registered", name)
+	}
+	if IsRegisteredIPExtractor("ovs-cni") {
+		t.Error("expected ovs-cni to have no registered extractor")
+	}
+	if _, ok := lookupIPExtractor(guestAgentInfoSource).(GuestAgentExtractor); !ok {
+		t.Errorf("expected the built-in guest-agent extractor, got %T", lookupIPExtractor(guestAgentInfoSource))
+	}
+	if e := lookupIPExtractor("ovs-cni"); e.Name() != "ovs-cni" {
+		t.Errorf("expected a generic extractor named ovs-cni, got %q", e.Name())
+	}
+}
+
+func TestExtractBestIPs_CustomExtractor(t *testing.T) {
+	const name = "test-sriov"
+	registerTestExtractor(t, fixedExtractor{name: name, addr: "198.51.100.7"})
+
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{IP: "10.0.0.5", InfoSource: multusInfoSource},
+		{InfoSource: name},
+	}
+	v4, _, source := extractBestIPs(vmi, ipFilter{}, []string{guestAgentInfoSource, name, multusInfoSource})
+	if source != name || !slices.Equal(v4, []string{"198.51.100.7"}) {
+		t.Errorf("expected registered extractor to be used, got source=%q v4=%v", source, v4)
+	}
+
+	// The extractor is only called for interfaces tagged with its infoSource.
+	vmi.Status.Interfaces = vmi.Status.Interfaces[:1]
+	_, _, source = extractBestIPs(vmi, ipFilter{}, []string{name, multusInfoSource})
+	if source != multusInfoSource {
+		t.Errorf("expected fallback to multus-status without a %s interface, got %q", name, source)
+	}
+}
+
+func TestParseIPSourcePriority(t *testing.T) {
+	got, err := ParseIPSourcePriority(" guest-agent, ovs-cni ,multus-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"guest-agent", "ovs-cni", "multus-status"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, raw := range []string{"", "guest-agent,,multus-status", "guest-agent,guest-agent"} {
+		if _, err := ParseIPSourcePriority(raw); err == nil {
+			t.Errorf("ParseIPSourcePriority(%q): expected error", raw)
+		}
+	}
+}
+
+func TestParseStaticIPs(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantV4  []string
+		wantV6  []string
+		wantErr bool
+	}{
+		{"ipv4 only", "203.0.113.10, 203.0.113.11", []string{"203.0.113.10", "203.0.113.11"}, nil, false},
+		{"ipv6 only", "2001:db8::10", nil, []string{"2001:db8::10"}, false},
+		{"mixed", "203.0.113.10,2001:db8::10", []string{"203.0.113.10"}, []string{"2001:db8::10"}, false},
+		{"invalid entries", "203.0.113.10,not-an-ip,300.1.1.1", []string{"203.0.113.10"}, nil, true},
+	}
+	for _, tt := range tests {
+		v4, v6, err := parseStaticIPs(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, errInvalidAnnotation) {
+			t.Errorf("%s: expected errInvalidAnnotation, got %v", tt.name, err)
+		}
+		if !slices.Equal(v4, tt.wantV4) || !slices.Equal(v6, tt.wantV6) {
+			t.Errorf("%s: got v4=%v v6=%v, want v4=%v v6=%v", tt.name, v4, v6, tt.wantV4, tt.wantV6)
+		}
+	}
+}
+
+func TestReconcile_StaticIPBypassesDiscovery(t *testing.T) {
+	vmi := newTestVMI("vm1", map[string]string{
+		annotationHostname: "vm1.example.com",
+		annotationStaticIP: "203.0.113.10",
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm1")
+
+	ep := getEndpoint(t, r, "vm1")
+	if len(ep.Spec.Endpoints) != 1 || !slices.Equal([]string(ep.Spec.Endpoints[0].Targets), []string{"203.0.113.10"}) {
+		t.Errorf("expected only the static IP to be published, got %v", ep.Spec.Endpoints)
+	}
+}
+
+func TestReconcile_IPPool(t *testing.T) {
+	iface := kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource}
+	tests := []struct {
+		name string
+		pool string
+		want []string
+	}{
+		{"valid pool replaces interface IPs", "203.0.113.10, 203.0.113.11", []string{"203.0.113.10", "203.0.113.11"}},
+		{"invalid entries are skipped", "203.0.113.10,not-an-ip", []string{"203.0.113.10"}},
+		{"only invalid entries fall back", "not-an-ip", []string{"10.0.0.5"}},
+		{"empty pool falls back", " ", []string{"10.0.0.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmi := newTestVMI("vm1", map[string]string{
+				annotationHostname: "vm1.example.com",
+				annotationIPPool:   tt.pool,
+			}, iface)
+			r := newTestReconciler(t, vmi)
+
+			reconcileVMI(t, r, "vm1")
+
+			ep := getEndpoint(t, r, "vm1")
+			if len(ep.Spec.Endpoints) != 1 || !slices.Equal([]string(ep.Spec.Endpoints[0].Targets), tt.want) {
+				t.Errorf("got %v, want targets %v", ep.Spec.Endpoints, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveIPs_StaticIPOverridesIPPool(t *testing.T) {
+	vmi := newTestVMI("vm1", map[string]string{
+		annotationStaticIP: "203.0.113.10",
+		annotationIPPool:   "198.51.100.10",
+	})
+	r := newTestReconciler(t, vmi)
+
+	v4, _, source := r.resolveIPs(context.Background(), vmi, r.settings())
+	if source != staticIPSource || !slices.Equal(v4, []string{"203.0.113.10"}) {
+		t.Errorf("got %v from %q, want the static IP", v4, source)
+	}
+}
@@ -0,0 +1,136 @@
+//go:build integration
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// startLifecycleManager starts a manager running the VMI controller against
+// a fresh envtest API server and returns a client for it.
+func startLifecycleManager(t *testing.T) client.Client {
+	t.Helper()
+	cfg := startTestEnv(t, &envtest.Environment{CRDs: []*apiextensionsv1.CustomResourceDefinition{vmiCRD(), dnsEndpointCRD()}})
+
+	scheme := newTestScheme(t)
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{Scheme: scheme, Metrics: metricsserver.Options{BindAddress: "0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&VirtualMachineInstanceReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("test"),
+	}).SetupWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = mgr.Start(ctx) }()
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// createLifecycleTestVMI creates a running VMI named vm1 with the given
+// hostname annotation and a single address.
+func createLifecycleTestVMI(t *testing.T, c client.Client, hostname string) *kubevirtv1.VirtualMachineInstance {
+	t.Helper()
+	vmi := &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "vm1",
+			Namespace:   "default",
+			Annotations: map[string]string{annotationHostname: hostname},
+		},
+		Status: kubevirtv1.VirtualMachineInstanceStatus{
+			Phase:      kubevirtv1.Running,
+			Interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{{IP: "10.0.0.5", InfoSource: multusInfoSource}},
+			Conditions: []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if err := c.Create(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	return vmi
+}
+
+// endpointGone reports whether the DNSEndpoint at key no longer exists.
+func endpointGone(c client.Client, key client.ObjectKey) bool {
+	err := c.Get(context.Background(), key, &dnsendpointv1alpha1.DNSEndpoint{})
+	return apierrors.IsNotFound(err)
+}
+
+func TestManager_PublishesAnnotatedVMI(t *testing.T) {
+	c := startLifecycleManager(t)
+	vmi := createLifecycleTestVMI(t, c, "vm1.example.com")
+
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	waitFor(t, 30*time.Second, "DNSEndpoint was not created", func() bool {
+		return c.Get(context.Background(), client.ObjectKeyFromObject(vmi), ep) == nil
+	})
+	if len(ep.Spec.Endpoints) != 1 {
+		t.Fatalf("expected one endpoint, got %v", ep.Spec.Endpoints)
+	}
+	got := ep.Spec.Endpoints[0]
+	if got.DNSName != "vm1.example.com" || got.RecordType != "A" || got.RecordTTL != defaultTTL ||
+		len(got.Targets) != 1 || got.Targets[0] != "10.0.0.5" {
+		t.Errorf("unexpected endpoint %v", got)
+	}
+	if owner := metav1.GetControllerOf(ep); owner == nil || owner.Name != vmi.Name {
+		t.Errorf("expected the VMI as controller owner, got %v", ep.OwnerReferences)
+	}
+}
+
+func TestManager_DeletesEndpointWithVMI(t *testing.T) {
+	c := startLifecycleManager(t)
+	vmi := createLifecycleTestVMI(t, c, "vm1.example.com")
+	key := client.ObjectKeyFromObject(vmi)
+	waitFor(t, 30*time.Second, "DNSEndpoint was not created", func() bool { return !endpointGone(c, key) })
+
+	// envtest runs no garbage collector, so the cleanup finalizer alone must
+	// remove the DNSEndpoint.
+	if err := c.Delete(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 30*time.Second, "DNSEndpoint was not deleted with the VMI", func() bool { return endpointGone(c, key) })
+	waitFor(t, 30*time.Second, "VMI finalizer was not released", func() bool {
+		return apierrors.IsNotFound(c.Get(context.Background(), key, &kubevirtv1.VirtualMachineInstance{}))
+	})
+}
+
+func TestManager_DeletesEndpointWhenAnnotationRemoved(t *testing.T) {
+	c := startLifecycleManager(t)
+	vmi := createLifecycleTestVMI(t, c, "vm1.example.com")
+	key := client.ObjectKeyFromObject(vmi)
+	waitFor(t, 30*time.Second, "DNSEndpoint was not created", func() bool { return !endpointGone(c, key) })
+
+	if err := c.Patch(context.Background(), vmi, client.RawPatch(types.MergePatchType,
+		[]byte(`{"metadata":{"annotations":{"`+annotationHostname+`":null}}}`))); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 30*time.Second, "DNSEndpoint was not deleted after the annotation was removed", func() bool { return endpointGone(c, key) })
+	waitFor(t, 30*time.Second, "cleanup finalizer was not dropped", func() bool {
+		got := &kubevirtv1.VirtualMachineInstance{}
+		return c.Get(context.Background(), key, got) == nil && len(got.Finalizers) == 0
+	})
+}
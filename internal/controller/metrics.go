@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// metricsNamespace prefixes every metric exported by this controller.
+	metricsNamespace = "externaldns_kubevirt"
+
+	// Values for the "result" label.
+	resultSuccess = "success"
+	resultError   = "error"
+	resultSkipped = "skipped"
+
+	// Values for the "reason" label of the error counter.
+	errorReasonAPI               = "api_error"
+	errorReasonEndpointConflict  = "endpoint_conflict"
+	errorReasonInvalidAnnotation = "invalid_annotation"
+	errorReasonIPUnavailable     = "ip_unavailable"
+	errorReasonTimeout           = "timeout"
+)
+
+var (
+	// errInvalidAnnotation marks errors caused by an unparseable VMI annotation.
+	errInvalidAnnotation = errors.New("invalid annotation")
+	// errIPsUnavailable marks reconciles where the VMI reports no usable IPs yet.
+	errIPsUnavailable = errors.New("no IP addresses available")
+	// errReconcileTimeout marks reconciles cut short by the reconcile timeout.
+	errReconcileTimeout = errors.New("reconcile timed out")
+)
+
+var (
+	// reconcileDuration tracks how long each VMI reconcile takes, by namespace and outcome.
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of VirtualMachineInstance reconciles in seconds.",
+			Buckets:   []float64{0.001, 0.01, 0.1, 1, 10},
+		},
+		[]string{"namespace", "result"},
+	)
+
+	// reconcileErrors counts reconcile failures by category.
+	reconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconcile_errors_total",
+			Help:      "Total number of VirtualMachineInstance reconcile errors by reason.",
+		},
+		[]string{"reason"},
+	)
+
+	// vmiReconciles counts reconciles per VMI and outcome.
+	//
+	// It has one series per VMI and result, so its cardinality grows with the
+	// number of VMIs. Do not enable it in clusters with more than about 1000
+	// VMIs unless Prometheus can handle that many series; --disable-per-vmi-metrics
+	// turns it off. Series are dropped once the VMI is deleted.
+	vmiReconciles = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "vmi_reconciles_total",
+			Help:      "Total number of reconciles of each VirtualMachineInstance by result.",
+		},
+		[]string{"namespace", "name", "result"},
+	)
+
+	// watchEventLag is how long after it was written the most recently
+	// reconciled VMI version was first reconciled. Objects carry no timestamp
+	// for their resourceVersion, so the newest managedFields entry, or else the
+	// creation time, stands in for it. Requeues and resyncs of a version already
+	// reconciled leave it unchanged, so they do not report the VMI's age.
+	watchEventLag = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "watch_event_lag_seconds",
+			Help:      "Seconds between the last write to a VirtualMachineInstance and the first reconcile of that version.",
+		},
+	)
+
+	// managedEndpoints reports how many DNSEndpoints are owned by VMIs, per namespace.
+	managedEndpoints = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "managed_endpoints_total",
+			Help:      "Number of DNSEndpoint objects currently managed by the controller.",
+		},
+		[]string{"namespace"},
+	)
+)
+
+func init() {
+	// Register with the controller-runtime registry so the metrics are served
+	// by the manager's existing metrics endpoint.
+	metrics.Registry.MustRegister(reconcileDuration, reconcileErrors, vmiReconciles, watchEventLag, managedEndpoints, queueDepth)
+}
+
+// lastWriteTime returns when obj was last written: the newest managedFields
+// timestamp, or the creation timestamp when there are none.
+func lastWriteTime(obj metav1.Object) time.Time {
+	last := obj.GetCreationTimestamp().Time
+	for _, f := range obj.GetManagedFields() {
+		if f.Time != nil && f.Time.After(last) {
+			last = f.Time.Time
+		}
+	}
+	return last
+}
+
+// observeWatchEventLag sets watchEventLag from obj's last write time. Objects
+// without any timestamp are ignored.
+func observeWatchEventLag(obj metav1.Object, now time.Time) {
+	last := lastWriteTime(obj)
+	if last.IsZero() {
+		return
+	}
+	watchEventLag.Set(now.Sub(last).Seconds())
+}
+
+// categorizeError maps an error to one of the reason label values of reconcileErrors.
+func categorizeError(err error) string {
+	var alreadyOwned *controllerutil.AlreadyOwnedError
+	switch {
+	case errors.Is(err, errInvalidAnnotation):
+		return errorReasonInvalidAnnotation
+	case errors.Is(err, errIPsUnavailable):
+		return errorReasonIPUnavailable
+	case errors.Is(err, errReconcileTimeout):
+		return errorReasonTimeout
+	case errors.Is(err, errHostnameConflict), errors.Is(err, errEndpointOwnedByOther), apierrors.IsConflict(err), apierrors.IsAlreadyExists(err), errors.As(err, &alreadyOwned):
+		return errorReasonEndpointConflict
+	default:
+		return errorReasonAPI
+	}
+}
+
+// countReconcileError increments the error counter for err's category.
+func countReconcileError(err error) {
+	reconcileErrors.WithLabelValues(categorizeError(err)).Inc()
+}
@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// histogramCount returns the number of observations recorded for the given labels.
+func histogramCount(t *testing.T, vec *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := vec.WithLabelValues(labels...).(prometheus.Histogram).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestReconcileDuration_Registered(t *testing.T) {
+	// Touch a series so the vector shows up in Gather output.
+	reconcileDuration.WithLabelValues("registered-check", resultSuccess)
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == "externaldns_kubevirt_reconcile_duration_seconds" {
+			return
+		}
+	}
+	t.Errorf("reconcile duration histogram not found in controller-runtime registry")
+}
+
+func TestReconcileDuration_ObservedPerResult(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	idle := newTestVMI("idle", nil)
+	r := newTestReconciler(t, vmi, idle)
+
+	successBefore := histogramCount(t, reconcileDuration, "default", resultSuccess)
+	skippedBefore := histogramCount(t, reconcileDuration, "default", resultSkipped)
+
+	reconcileVMI(t, r, "vm")
+	reconcileVMI(t, r, "idle")
+
+	if got := histogramCount(t, reconcileDuration, "default", resultSuccess) - successBefore; got != 1 {
+		t.Errorf("expected 1 success observation, got %d", got)
+	}
+	if got := histogramCount(t, reconcileDuration, "default", resultSkipped) - skippedBefore; got != 1 {
+		t.Errorf("expected 1 skipped observation, got %d", got)
+	}
+}
+
+func TestCategorizeError(t *testing.T) {
+	gr := schema.GroupResource{Group: "externaldns.k8s.io", Resource: "dnsendpoints"}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"invalid annotation", fmt.Errorf("%w: bad ttl", errInvalidAnnotation), errorReasonInvalidAnnotation},
+		{"ips unavailable", errIPsUnavailable, errorReasonIPUnavailable},
+		{"conflict", apierrors.NewConflict(gr, "vm", errors.New("stale")), errorReasonEndpointConflict},
+		{"already exists", apierrors.NewAlreadyExists(gr, "vm"), errorReasonEndpointConflict},
+		{"already owned", &controllerutil.AlreadyOwnedError{}, errorReasonEndpointConflict},
+		{"server timeout", apierrors.NewServerTimeout(gr, "get", 1), errorReasonAPI},
+		{"hostname conflict", fmt.Errorf("%w: vm.example.com", errHostnameConflict), errorReasonEndpointConflict},
+		{"reconcile timeout", fmt.Errorf("%w after 1s: %w", errReconcileTimeout, context.DeadlineExceeded), errorReasonTimeout},
+		{"generic", errors.New("boom"), errorReasonAPI},
+	}
+	for _, tt := range tests {
+		if got := categorizeError(tt.err); got != tt.want {
+			t.Errorf("%s: categorizeError() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReconcileErrors_CountedByReason(t *testing.T) {
+	iface := kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource}
+	badTTL := newTestVMI("bad-ttl", map[string]string{annotationHostname: "vm.example.com", annotationTTL: "soon"}, iface)
+	noIPs := newTestVMI("no-ips", map[string]string{annotationHostname: "vm2.example.com"})
+	r := newTestReconciler(t, badTTL, noIPs)
+
+	invalidBefore := testutil.ToFloat64(reconcileErrors.WithLabelValues(errorReasonInvalidAnnotation))
+	unavailableBefore := testutil.ToFloat64(reconcileErrors.WithLabelValues(errorReasonIPUnavailable))
+
+	reconcileVMI(t, r, "bad-ttl")
+	reconcileVMI(t, r, "no-ips")
+
+	if got := testutil.ToFloat64(reconcileErrors.WithLabelValues(errorReasonInvalidAnnotation)) - invalidBefore; got != 1 {
+		t.Errorf("expected invalid_annotation to increase by 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(reconcileErrors.WithLabelValues(errorReasonIPUnavailable)) - unavailableBefore; got != 1 {
+		t.Errorf("expected ip_unavailable to increase by 1, got %v", got)
+	}
+}
+
+func TestReconcileErrors_APIError(t *testing.T) {
+	s := newTestScheme(t)
+	c := fake.NewClientBuilder().WithScheme(s).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return apierrors.NewServiceUnavailable("apiserver down")
+		},
+	}).Build()
+	r := &VirtualMachineInstanceReconciler{Client: c, Scheme: s, Recorder: record.NewFakeRecorder(10)}
+
+	before := testutil.ToFloat64(reconcileErrors.WithLabelValues(errorReasonAPI))
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "vm"}}); err == nil {
+		t.Fatal("expected Reconcile to return the API error")
+	}
+	if got := testutil.ToFloat64(reconcileErrors.WithLabelValues(errorReasonAPI)) - before; got != 1 {
+		t.Errorf("expected api_error to increase by 1, got %v", got)
+	}
+}
+
+// vmiReconcileSeries returns the number of vmiReconciles series for the named VMI.
+func vmiReconcileSeries(t *testing.T, name string) int {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 100)
+	vmiReconciles.Collect(ch)
+	close(ch)
+	n := 0
+	for m := range ch {
+		pb := &dto.Metric{}
+		if err := m.Write(pb); err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range pb.GetLabel() {
+			if l.GetName() == "name" && l.GetValue() == name {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestVMIReconciles_CountedPerVMI(t *testing.T) {
+	vmi := newTestVMI("counted", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	idle := newTestVMI("counted-idle", nil)
+	r := newTestReconciler(t, vmi, idle)
+
+	reconcileVMI(t, r, "counted")
+	reconcileVMI(t, r, "counted-idle")
+
+	if got := testutil.ToFloat64(vmiReconciles.WithLabelValues("default", "counted", resultSuccess)); got != 1 {
+		t.Errorf("success count for counted = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(vmiReconciles.WithLabelValues("default", "counted-idle", resultSkipped)); got != 1 {
+		t.Errorf("skipped count for counted-idle = %v, want 1", got)
+	}
+
+	// Deleting the VMI drops its series.
+	if err := r.Delete(context.Background(), idle); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "counted-idle")
+	if got := vmiReconcileSeries(t, "counted-idle"); got != 0 {
+		t.Errorf("expected no series for a deleted VMI, got %d", got)
+	}
+}
+
+func TestVMIReconciles_Disabled(t *testing.T) {
+	vmi := newTestVMI("uncounted", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.DisablePerVMIMetrics = true
+
+	reconcileVMI(t, r, "uncounted")
+
+	if got := vmiReconcileSeries(t, "uncounted"); got != 0 {
+		t.Errorf("expected no series with per-VMI metrics disabled, got %d", got)
+	}
+}
+
+func TestLastWriteTime(t *testing.T) {
+	created := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	vmi := newTestVMI("vm", nil)
+	if got := lastWriteTime(vmi); !got.IsZero() {
+		t.Errorf("lastWriteTime without timestamps = %v, want zero", got)
+	}
+
+	vmi.CreationTimestamp = metav1.NewTime(created)
+	if got := lastWriteTime(vmi); !got.Equal(created) {
+		t.Errorf("lastWriteTime = %v, want the creation time %v", got, created)
+	}
+
+	vmi.ManagedFields = []metav1.ManagedFieldsEntry{
+		{Manager: "virt-handler", Time: ptr.To(metav1.NewTime(created.Add(2 * time.Minute)))},
+		{Manager: "kubectl", Time: ptr.To(metav1.NewTime(created.Add(time.Minute)))},
+		{Manager: "no-time"},
+	}
+	if got := lastWriteTime(vmi); !got.Equal(created.Add(2 * time.Minute)) {
+		t.Errorf("lastWriteTime = %v, want the newest managedFields time", got)
+	}
+}
+
+func TestReconcile_ObservesWatchEventLag(t *testing.T) {
+	written := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	vmi := newTestVMI("vm", nil)
+	vmi.CreationTimestamp = metav1.NewTime(written.Add(-time.Hour))
+	vmi.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "virt-handler", Time: ptr.To(metav1.NewTime(written))}}
+	r := newTestReconciler(t, vmi)
+	now := written.Add(45 * time.Second)
+	r.now = func() time.Time { return now }
+
+	reconcileVMI(t, r, "vm")
+	if got := testutil.ToFloat64(watchEventLag); got != 45 {
+		t.Errorf("watch event lag = %v, want 45", got)
+	}
+
+	// A requeue or resync of the same version does not report the VMI's age.
+	now = now.Add(time.Hour)
+	reconcileVMI(t, r, "vm")
+	if got := testutil.ToFloat64(watchEventLag); got != 45 {
+		t.Errorf("watch event lag after a requeue = %v, want it unchanged at 45", got)
+	}
+
+	// A new version is measured again.
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(vmi), vmi); err != nil {
+		t.Fatal(err)
+	}
+	vmi.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "virt-handler", Time: ptr.To(metav1.NewTime(now.Add(-2 * time.Second)))}}
+	if err := r.Update(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+	if got := testutil.ToFloat64(watchEventLag); got != 2 {
+		t.Errorf("watch event lag for a new version = %v, want 2", got)
+	}
+}
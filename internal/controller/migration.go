@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// migratedVMIRequests maps a VirtualMachineInstanceMigration to a reconcile
+// of the VMI it moved, since the VMI's addresses may change with the node it
+// runs on.
+func migratedVMIRequests(_ context.Context, obj client.Object) []reconcile.Request {
+	migration, ok := obj.(*kubevirtv1.VirtualMachineInstanceMigration)
+	if !ok || migration.Spec.VMIName == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: migration.Namespace, Name: migration.Spec.VMIName}}}
+}
+
+// migrationSucceededPredicate passes only updates that move a migration into
+// the Succeeded phase. Migrations that already succeeded when the cache starts
+// are ignored, since their VMIs are reconciled at startup anyway.
+func migrationSucceededPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldMigration, ok1 := e.ObjectOld.(*kubevirtv1.VirtualMachineInstanceMigration)
+			newMigration, ok2 := e.ObjectNew.(*kubevirtv1.VirtualMachineInstanceMigration)
+			if !ok1 || !ok2 {
+				return false
+			}
+			return oldMigration.Status.Phase != kubevirtv1.MigrationSucceeded && newMigration.Status.Phase == kubevirtv1.MigrationSucceeded
+		},
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+	}
+}
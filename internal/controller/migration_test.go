@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// newTestMigration returns a migration of vmiName in the default namespace in the given phase.
+func newTestMigration(name, vmiName string, phase kubevirtv1.VirtualMachineInstanceMigrationPhase) *kubevirtv1.VirtualMachineInstanceMigration {
+	return &kubevirtv1.VirtualMachineInstanceMigration{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name + "-uid")},
+		Spec:       kubevirtv1.VirtualMachineInstanceMigrationSpec{VMIName: vmiName},
+		Status:     kubevirtv1.VirtualMachineInstanceMigrationStatus{Phase: phase},
+	}
+}
+
+func TestMigratedVMIRequests(t *testing.T) {
+	got := migratedVMIRequests(context.Background(), newTestMigration("migration", "vm", kubevirtv1.MigrationSucceeded))
+	want := types.NamespacedName{Namespace: "default", Name: "vm"}
+	if len(got) != 1 || got[0].NamespacedName != want {
+		t.Errorf("expected a request for %v, got %v", want, got)
+	}
+	if got := migratedVMIRequests(context.Background(), newTestMigration("migration", "", kubevirtv1.MigrationSucceeded)); len(got) != 0 {
+		t.Errorf("expected no request for a migration without a VMI name, got %v", got)
+	}
+}
+
+func TestMigrationSucceededPredicate(t *testing.T) {
+	p := migrationSucceededPredicate()
+	running := newTestMigration("migration", "vm", kubevirtv1.MigrationRunning)
+	succeeded := newTestMigration("migration", "vm", kubevirtv1.MigrationSucceeded)
+	failed := newTestMigration("migration", "vm", kubevirtv1.MigrationFailed)
+
+	if !p.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: succeeded}) {
+		t.Error("expected the transition to Succeeded to pass")
+	}
+	if p.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: failed}) {
+		t.Error("expected the transition to Failed to be filtered")
+	}
+	if p.Update(event.UpdateEvent{ObjectOld: succeeded, ObjectNew: succeeded.DeepCopy()}) {
+		t.Error("expected updates of an already succeeded migration to be filtered")
+	}
+	if p.Create(event.CreateEvent{Object: succeeded}) {
+		t.Error("expected create events to be filtered")
+	}
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// NamespaceConfigCache caches the data of the per-namespace config ConfigMaps.
+// Their values are merged over the global settings for VMIs in that namespace.
+// It is safe for concurrent use.
+type NamespaceConfigCache struct {
+	// Name is the ConfigMap looked up in each VMI's namespace.
+	Name string
+
+	// entries maps a namespace to its ConfigMap data; a nil map records that
+	// the namespace has no ConfigMap.
+	entries sync.Map
+}
+
+// NewNamespaceConfigCache returns an empty cache for ConfigMaps called name.
+func NewNamespaceConfigCache(name string) *NamespaceConfigCache {
+	return &NamespaceConfigCache{Name: name}
+}
+
+// Settings returns global with the namespace's ConfigMap merged over it. The
+// ConfigMap is fetched through reader on the first lookup and cached until
+// Invalidate is called for the namespace. A missing ConfigMap yields global.
+func (c *NamespaceConfigCache) Settings(ctx context.Context, reader client.Reader, namespace string, global ControllerSettings) (ControllerSettings, error) {
+	data, ok := c.load(namespace)
+	if !ok {
+		cm := &corev1.ConfigMap{}
+		err := reader.Get(ctx, client.ObjectKey{Name: c.Name, Namespace: namespace}, cm)
+		switch {
+		case apierrors.IsNotFound(err):
+			data = nil
+		case err != nil:
+			return global, err
+		default:
+			data = cm.Data
+			if data == nil {
+				data = map[string]string{}
+			}
+		}
+		c.entries.Store(namespace, data)
+	}
+	if data == nil {
+		return global, nil
+	}
+	return parseSettings(data, global, func(key, value string) {
+		log.FromContext(ctx).Info("ignoring invalid namespace config value",
+			"configmap", client.ObjectKey{Name: c.Name, Namespace: namespace}, "key", key, "value", value)
+	}), nil
+}
+
+// Cached is like Settings but never calls the API server and does not log
+// invalid values; namespaces that have not been looked up yet use global.
+func (c *NamespaceConfigCache) Cached(namespace string, global ControllerSettings) ControllerSettings {
+	data, _ := c.load(namespace)
+	if data == nil {
+		return global
+	}
+	return parseSettings(data, global, func(string, string) {})
+}
+
+// Invalidate drops the cached ConfigMap data for namespace.
+func (c *NamespaceConfigCache) Invalidate(namespace string) {
+	c.entries.Delete(namespace)
+}
+
+func (c *NamespaceConfigCache) load(namespace string) (map[string]string, bool) {
+	v, ok := c.entries.Load(namespace)
+	if !ok {
+		return nil, false
+	}
+	return v.(map[string]string), true
+}
+
+// NamespaceConfigMapReconciler invalidates NamespaceConfigCache entries when a
+// per-namespace config ConfigMap changes.
+type NamespaceConfigMapReconciler struct {
+	Cache *NamespaceConfigCache
+}
+
+// Reconcile drops the cached settings for the ConfigMap's namespace; the next
+// VMI reconcile in that namespace reloads them.
+func (r *NamespaceConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log.FromContext(ctx).V(1).Info("namespace config changed, invalidating cache", "configmap", req.NamespacedName)
+	r.Cache.Invalidate(req.Namespace)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager registers the namespace ConfigMap controller with the manager.
+func (r *NamespaceConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isConfigMap := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetName() == r.Cache.Name
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("namespace-configmap").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(isConfigMap)).
+		Complete(r)
+}
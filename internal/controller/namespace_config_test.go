@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+func TestNamespaceConfigCache_OverrideAndFallback(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultConfigMapName, Namespace: "team-a"},
+		Data:       map[string]string{configKeyDefaultTTL: "60", configKeyIPFamily: "bogus"},
+	}
+	r := newTestReconciler(t, cm)
+	cache := NewNamespaceConfigCache(DefaultConfigMapName)
+	global := DefaultSettings()
+	global.IPFamily = ipFamilyIPv4
+
+	got, err := cache.Settings(context.Background(), r.Client, "team-a", global)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DefaultTTL != 60 || got.IPFamily != ipFamilyIPv4 || got.AnnotationPrefix != defaultAnnotationPrefix {
+		t.Errorf("expected the namespace TTL merged over global settings, got %+v", got)
+	}
+
+	got, err = cache.Settings(context.Background(), r.Client, "team-b", global)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != global {
+		t.Errorf("expected global settings without a namespace ConfigMap, got %+v", got)
+	}
+}
+
+func TestNamespaceConfigCache_Invalidate(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultConfigMapName, Namespace: "default"},
+		Data:       map[string]string{configKeyDefaultTTL: "60"},
+	}
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, cm, vmi)
+	r.NamespaceConfig = NewNamespaceConfigCache(DefaultConfigMapName)
+	ctx := context.Background()
+
+	reconcileVMI(t, r, "vm")
+	if ttl := getEndpoint(t, r, "vm").Spec.Endpoints[0].RecordTTL; ttl != 60 {
+		t.Fatalf("expected the namespace TTL (60), got %d", ttl)
+	}
+
+	cm.Data[configKeyDefaultTTL] = "90"
+	if err := r.Update(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+	// Without invalidation the cached value is still used.
+	reconcileVMI(t, r, "vm")
+	if ttl := getEndpoint(t, r, "vm").Spec.Endpoints[0].RecordTTL; ttl != 60 {
+		t.Fatalf("expected the cached TTL (60), got %d", ttl)
+	}
+
+	nr := &NamespaceConfigMapReconciler{Cache: r.NamespaceConfig}
+	if _, err := nr.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: DefaultConfigMapName}}); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+	if ttl := getEndpoint(t, r, "vm").Spec.Endpoints[0].RecordTTL; ttl != 90 {
+		t.Errorf("expected the reloaded TTL (90), got %d", ttl)
+	}
+}
@@ -0,0 +1,91 @@
+//go:build integration
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestManager_WatchesOnlyConfiguredNamespaces(t *testing.T) {
+	cfg := startTestEnv(t, &envtest.Environment{CRDs: []*apiextensionsv1.CustomResourceDefinition{vmiCRD(), dnsEndpointCRD()}})
+
+	scheme := newTestScheme(t)
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+		Cache: cache.Options{DefaultNamespaces: map[string]cache.Config{
+			"team-a": {},
+			"team-b": {},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&VirtualMachineInstanceReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("test"),
+	}).SetupWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = mgr.Start(ctx) }()
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ns := range []string{"team-a", "team-b", "team-c"} {
+		if err := c.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}); err != nil {
+			t.Fatal(err)
+		}
+		vmi := &kubevirtv1.VirtualMachineInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "vm1",
+				Namespace:   ns,
+				Annotations: map[string]string{annotationHostname: "vm1." + ns + ".example.com"},
+			},
+			Status: kubevirtv1.VirtualMachineInstanceStatus{
+				Phase:      kubevirtv1.Running,
+				Interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{{IP: "10.0.0.5", InfoSource: multusInfoSource}},
+				Conditions: []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionTrue}},
+			},
+		}
+		if err := c.Create(ctx, vmi); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	endpointExists := func(ns string) bool {
+		err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: "vm1"}, &dnsendpointv1alpha1.DNSEndpoint{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			t.Fatal(err)
+		}
+		return err == nil
+	}
+
+	waitFor(t, 30*time.Second, "DNSEndpoints were not created in the watched namespaces", func() bool {
+		return endpointExists("team-a") && endpointExists("team-b")
+	})
+	if endpointExists("team-c") {
+		t.Error("expected no DNSEndpoint in the unwatched namespace team-c")
+	}
+}
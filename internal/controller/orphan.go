@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// endpointOwnerUIDIndex is the cache field index of the UID of the VMI owning
+// a DNSEndpoint, whether through its owner reference or, across namespaces and
+// clusters, through the owner-uid label.
+const endpointOwnerUIDIndex = "metadata.ownerReferences.uid"
+
+// indexEndpointOwnerUID is the endpointOwnerUIDIndex indexer. DNSEndpoints
+// not owned by a VMI are not indexed.
+func indexEndpointOwnerUID(obj client.Object) []string {
+	ep, ok := obj.(*dnsendpointv1alpha1.DNSEndpoint)
+	if !ok {
+		return nil
+	}
+	if _, uid, ok := endpointOwner(ep); ok {
+		return []string{string(uid)}
+	}
+	return nil
+}
+
+// CleanupOrphanedEndpoints deletes the DNSEndpoints in namespaces whose owning
+// VMI no longer exists, or has been replaced by a VMI of the same name. A nil
+// namespaces slice checks every namespace. DNSEndpoints not owned by a VMI are
+// left alone, since they cannot be told apart from ones created by hand.
+//
+// VMIs are listed through vmis, usually the manager's cache, and DNSEndpoints
+// listed and deleted through endpoints; the two differ when DNSEndpoints live
+// in a remote cluster. Before an owner's DNSEndpoints are deleted, the owner
+// is fetched again through live, an uncached reader, so a VMI the cache has
+// not seen yet keeps its records. endpoints must serve the
+// endpointOwnerUIDIndex registered by SetupWithManager, through which all
+// DNSEndpoints of an orphaned owner are found at once. It returns the number
+// of DNSEndpoints deleted.
+func CleanupOrphanedEndpoints(ctx context.Context, vmis, live client.Reader, endpoints client.Client, namespaces []string) (int, error) {
+	logger := log.FromContext(ctx).WithName("orphan-cleanup")
+	if namespaces == nil {
+		namespaces = []string{""}
+	}
+
+	listed := map[types.UID]bool{}
+	for _, ns := range namespaces {
+		list := &kubevirtv1.VirtualMachineInstanceList{}
+		if err := vmis.List(ctx, list, client.InNamespace(ns)); err != nil {
+			return 0, err
+		}
+		for _, vmi := range list.Items {
+			listed[vmi.UID] = true
+		}
+	}
+
+	deleted := 0
+	for _, ns := range namespaces {
+		cleaned := map[types.UID]bool{}
+		list := &dnsendpointv1alpha1.DNSEndpointList{}
+		if err := endpoints.List(ctx, list, client.InNamespace(ns)); err != nil {
+			return deleted, err
+		}
+		for i := range list.Items {
+			owner, uid, ok := endpointOwner(&list.Items[i])
+			if !ok || listed[uid] || cleaned[uid] {
+				continue
+			}
+			alive, err := ownerExists(ctx, live, owner, uid)
+			if err != nil {
+				return deleted, err
+			}
+			if alive {
+				// The cache has not caught up with this VMI yet.
+				listed[uid] = true
+				continue
+			}
+			n, err := deleteOwnedEndpoints(ctx, endpoints, ns, uid)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+			cleaned[uid] = true
+			logger.Info("deleted orphaned DNSEndpoints", "namespace", ns, "vmi", owner, "deleted", n)
+		}
+	}
+	return deleted, nil
+}
+
+// ownerExists fetches the VMI at owner through live and reports whether it is
+// still the VMI with uid.
+func ownerExists(ctx context.Context, live client.Reader, owner types.NamespacedName, uid types.UID) (bool, error) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := live.Get(ctx, owner, vmi); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return vmi.UID == uid, nil
+}
+
+// deleteOwnedEndpoints deletes the DNSEndpoints in namespace owned by the VMI
+// with uid, looked up through endpointOwnerUIDIndex, and returns how many it deleted.
+func deleteOwnedEndpoints(ctx context.Context, endpoints client.Client, namespace string, uid types.UID) (int, error) {
+	list := &dnsendpointv1alpha1.DNSEndpointList{}
+	if err := endpoints.List(ctx, list, client.InNamespace(namespace), client.MatchingFields{endpointOwnerUIDIndex: string(uid)}); err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for i := range list.Items {
+		if err := endpoints.Delete(ctx, &list.Items[i]); client.IgnoreNotFound(err) != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// newOwnedEndpoint returns a DNSEndpoint in namespace controlled by a VMI with the given name and UID.
+func newOwnedEndpoint(namespace, name, uid string) *dnsendpointv1alpha1.DNSEndpoint {
+	ep := &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	ep.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: kubevirtv1.SchemeGroupVersion.String(),
+		Kind:       "VirtualMachineInstance",
+		Name:       name,
+		UID:        types.UID(uid),
+		Controller: ptr.To(true),
+	}}
+	return ep
+}
+
+// newOrphanTestClient returns a fake client seeded with objs that serves the
+// DNSEndpoint owner UID index.
+func newOrphanTestClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	return fake.NewClientBuilder().WithScheme(newTestScheme(t)).
+		WithIndex(&dnsendpointv1alpha1.DNSEndpoint{}, endpointOwnerUIDIndex, indexEndpointOwnerUID).
+		WithObjects(objs...).Build()
+}
+
+func TestIndexEndpointOwnerUID(t *testing.T) {
+	crossEP := &dnsendpointv1alpha1.DNSEndpoint{}
+	setCrossNamespaceOwner(crossEP, newTestVMI("vm", nil))
+	tests := []struct {
+		name string
+		ep   *dnsendpointv1alpha1.DNSEndpoint
+		want []string
+	}{
+		{"owner reference", newOwnedEndpoint("default", "vm", "vm-uid"), []string{"vm-uid"}},
+		{"cross-namespace owner", crossEP, []string{"vm-uid"}},
+		{"unmanaged", &dnsendpointv1alpha1.DNSEndpoint{}, nil},
+	}
+	for _, tt := range tests {
+		if got := indexEndpointOwnerUID(tt.ep); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: indexEndpointOwnerUID = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCleanupOrphanedEndpoints(t *testing.T) {
+	live := newTestVMI("live", nil)
+	replaced := newTestVMI("replaced", nil)
+	crossOwner := newTestVMI("vm", nil)
+
+	liveEP := newOwnedEndpoint("default", "live", "live-uid")
+	goneEP := newOwnedEndpoint("default", "gone", "gone-uid")
+	// A VMI recreated under the same name has a new UID.
+	replacedEP := newOwnedEndpoint("default", "replaced", "old-uid")
+	manual := &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "manual"}}
+	crossEP := &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "dns-management", Name: "default-vm"}}
+	setCrossNamespaceOwner(crossEP, crossOwner)
+	crossGoneEP := &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "dns-management", Name: "default-gone"}}
+	setCrossNamespaceOwner(crossGoneEP, newTestVMI("gone", nil))
+
+	// Secondary DNSEndpoints of a gone VMI are found through the owner UID index.
+	goneInternalEP := newOwnedEndpoint("default", "gone", "gone-uid")
+	goneInternalEP.Name = "gone-internal"
+	c := newOrphanTestClient(t, live, replaced, crossOwner, liveEP, goneEP, goneInternalEP, replacedEP, manual, crossEP, crossGoneEP)
+
+	deleted, err := CleanupOrphanedEndpoints(context.Background(), c, c, c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 4 {
+		t.Errorf("deleted = %d, want 4", deleted)
+	}
+	for _, ep := range []*dnsendpointv1alpha1.DNSEndpoint{goneEP, goneInternalEP, replacedEP, crossGoneEP} {
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(ep), &dnsendpointv1alpha1.DNSEndpoint{}); !apierrors.IsNotFound(err) {
+			t.Errorf("expected orphan %s to be deleted, got err=%v", client.ObjectKeyFromObject(ep), err)
+		}
+	}
+	for _, ep := range []*dnsendpointv1alpha1.DNSEndpoint{liveEP, manual, crossEP} {
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(ep), &dnsendpointv1alpha1.DNSEndpoint{}); err != nil {
+			t.Errorf("expected %s to be kept: %v", client.ObjectKeyFromObject(ep), err)
+		}
+	}
+}
+
+func TestCleanupOrphanedEndpoints_OnlyListedNamespaces(t *testing.T) {
+	orphan := newOwnedEndpoint("default", "gone", "gone-uid")
+	other := newOwnedEndpoint("other", "gone", "gone-uid")
+	c := newOrphanTestClient(t, orphan, other)
+
+	deleted, err := CleanupOrphanedEndpoints(context.Background(), c, c, c, []string{"default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(other), &dnsendpointv1alpha1.DNSEndpoint{}); err != nil {
+		t.Errorf("expected DNSEndpoint outside the listed namespaces to be kept: %v", err)
+	}
+}
+
+func TestCleanupOrphanedEndpoints_ChecksLiveOwner(t *testing.T) {
+	// The VMI exists but the cache has not seen it yet.
+	vmi := newTestVMI("vm", nil)
+	ep := newOwnedEndpoint("default", "vm", "vm-uid")
+	cached := newOrphanTestClient(t, ep)
+	live := newOrphanTestClient(t, vmi)
+
+	deleted, err := CleanupOrphanedEndpoints(context.Background(), cached, live, cached, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 0 {
+		t.Errorf("deleted = %d, want 0", deleted)
+	}
+	if err := cached.Get(context.Background(), client.ObjectKeyFromObject(ep), &dnsendpointv1alpha1.DNSEndpoint{}); err != nil {
+		t.Errorf("expected the DNSEndpoint of the live VMI to be kept: %v", err)
+	}
+}
+
+func TestReconcile_WaitsForStartAfter(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.1"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+	cleanupDone := make(chan struct{})
+	r.StartAfter = cleanupDone
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(vmi)}
+	if _, err := r.Reconcile(ctx, req); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the reconcile to wait until cancelled, got %v", err)
+	}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(vmi), &dnsendpointv1alpha1.DNSEndpoint{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected no DNSEndpoint before StartAfter is closed, got err=%v", err)
+	}
+
+	close(cleanupDone)
+	reconcileVMI(t, r, "vm")
+	getEndpoint(t, r, "vm")
+}
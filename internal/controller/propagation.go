@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+// defaultPropagationFinalizer holds back a deleted VMI until
+// DNSPropagationDelay has passed since its DNSEndpoint was removed, so
+// resolvers see NXDOMAIN before the VMI's address can be reused. It pairs with
+// DefaultFinalizerName; other finalizer names get their own (see
+// propagationFinalizerFor).
+const defaultPropagationFinalizer = "external-dns.alpha.kubernetes.io/dns-propagation"
+
+// propagationRemaining returns how much longer the deleted VMI at key must be
+// held back for DNS propagation. The wait starts on the first call, which is
+// made once its DNSEndpoints are gone, and is zero when the VMI carries none
+// of the controller's propagation finalizers, current or previous.
+func (r *VirtualMachineInstanceReconciler) propagationRemaining(key types.NamespacedName, vmi *kubevirtv1.VirtualMachineInstance) time.Duration {
+	held := controllerutil.ContainsFinalizer(vmi, r.propagationFinalizer())
+	for _, name := range r.PreviousFinalizerNames {
+		held = held || controllerutil.ContainsFinalizer(vmi, propagationFinalizerFor(name))
+	}
+	if !held {
+		return 0
+	}
+	now := r.clock()
+	started := now
+	if v, loaded := r.propagationStarted.LoadOrStore(key, now); loaded {
+		started = v.(time.Time)
+	}
+	remaining := r.DNSPropagationDelay - now.Sub(started)
+	if remaining <= 0 {
+		r.propagationStarted.Delete(key)
+		return 0
+	}
+	return remaining
+}
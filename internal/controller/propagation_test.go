@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestReconcile_DeletionWaitsForDNSPropagation(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.DNSPropagationDelay = time.Minute
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	reconcileVMI(t, r, "vm")
+
+	key := client.ObjectKey{Namespace: "default", Name: "vm"}
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), key, got); err != nil {
+		t.Fatal(err)
+	}
+	if !controllerutil.ContainsFinalizer(got, defaultPropagationFinalizer) {
+		t.Fatalf("expected finalizer %q, got %v", defaultPropagationFinalizer, got.Finalizers)
+	}
+	if err := r.Delete(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != time.Minute {
+		t.Errorf("expected a requeue after the full delay, got %v", res)
+	}
+	if err := r.Get(context.Background(), key, &dnsendpointv1alpha1.DNSEndpoint{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the DNSEndpoint to be deleted before the wait, got err=%v", err)
+	}
+
+	now = now.Add(40 * time.Second)
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != 20*time.Second {
+		t.Errorf("expected a requeue after the remaining delay, got %v", res)
+	}
+	if err := r.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("expected the VMI to be held back during the delay, got %v", err)
+	}
+
+	now = now.Add(20 * time.Second)
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != 0 {
+		t.Errorf("expected no requeue once the delay elapsed, got %v", res)
+	}
+	if err := r.Get(context.Background(), key, got); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the VMI to be gone once the delay elapsed, got err=%v finalizers=%v", err, got.Finalizers)
+	}
+}
+
+func TestReconcile_NoPropagationFinalizerWithoutDelay(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	reconcileVMI(t, r, "vm")
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, got); err != nil {
+		t.Fatal(err)
+	}
+	if controllerutil.ContainsFinalizer(got, defaultPropagationFinalizer) {
+		t.Errorf("expected no %q finalizer with the delay disabled, got %v", defaultPropagationFinalizer, got.Finalizers)
+	}
+}
+
+func TestReconcile_PropagationFinalizerReleasedOnceDelayDisabled(t *testing.T) {
+	now := metav1.Now()
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	vmi.Finalizers = []string{defaultPropagationFinalizer}
+	vmi.DeletionTimestamp = &now
+	r := newTestReconciler(t, vmi)
+
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != 0 {
+		t.Errorf("expected no requeue with the delay disabled, got %v", res)
+	}
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, got); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the VMI to be gone, got err=%v finalizers=%v", err, got.Finalizers)
+	}
+}
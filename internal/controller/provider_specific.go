@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// annotationHealthCheckID attaches a Route 53 health check to the VMI's records.
+	annotationHealthCheckID = defaultAnnotationPrefix + "health-check-id"
+	// providerSpecificHealthCheckID is the External-DNS provider-specific
+	// property holding the Route 53 health check ID.
+	providerSpecificHealthCheckID = "aws/health-check-id"
+	// annotationProviderSpecificPrefix prefixes annotations that are passed
+	// through as provider-specific properties, e.g. provider-aws-failover
+	// becomes aws/failover.
+	annotationProviderSpecificPrefix = defaultAnnotationPrefix + "provider-"
+)
+
+// parseProviderSpecific returns the provider-specific properties set through
+// provider-<provider>-<property> annotations, sorted by name. The first dash
+// after the prefix becomes a slash, so provider-aws-health-check-id yields
+// aws/health-check-id; a name without a dash is used as is. Annotations with
+// an empty name or value are skipped.
+func parseProviderSpecific(annotations map[string]string, settings ControllerSettings) []dnsendpointv1alpha1.ProviderSpecificProperty {
+	prefix := settings.annotationKey(annotationProviderSpecificPrefix)
+	var props []dnsendpointv1alpha1.ProviderSpecificProperty
+	for key, value := range annotations {
+		name, ok := strings.CutPrefix(key, prefix)
+		value = strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			continue
+		}
+		if provider, property, found := strings.Cut(name, "-"); found && provider != "" && property != "" {
+			name = provider + "/" + property
+		}
+		props = append(props, dnsendpointv1alpha1.ProviderSpecificProperty{Name: name, Value: value})
+	}
+	slices.SortFunc(props, func(a, b dnsendpointv1alpha1.ProviderSpecificProperty) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return props
+}
+
+// withProviderProperty sets the property name to value in props, replacing
+// an existing property of that name.
+func withProviderProperty(props []dnsendpointv1alpha1.ProviderSpecificProperty, name, value string) []dnsendpointv1alpha1.ProviderSpecificProperty {
+	for i := range props {
+		if props[i].Name == name {
+			props[i].Value = value
+			return props
+		}
+	}
+	return append(props, dnsendpointv1alpha1.ProviderSpecificProperty{Name: name, Value: value})
+}
+
+// providerSpecificFor returns the provider-specific properties of the VMI's
+// records: the provider-* annotations, overridden by the health-check-id
+// annotation and by weight, the parsed weight annotation.
+func providerSpecificFor(vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings, weight string) []dnsendpointv1alpha1.ProviderSpecificProperty {
+	props := parseProviderSpecific(vmi.Annotations, settings)
+	if id := strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationHealthCheckID)]); id != "" {
+		props = withProviderProperty(props, providerSpecificHealthCheckID, id)
+	}
+	if weight != "" {
+		props = withProviderProperty(props, providerSpecificWeight, weight)
+	}
+	return props
+}
+
+// applyProviderSpecific sets props on every endpoint. Each endpoint gets its
+// own copy, so later changes to one do not leak into the others.
+func applyProviderSpecific(endpoints []*dnsendpointv1alpha1.Endpoint, props []dnsendpointv1alpha1.ProviderSpecificProperty) {
+	if len(props) == 0 {
+		return
+	}
+	for _, ep := range endpoints {
+		ep.ProviderSpecific = slices.Clone(props)
+	}
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestParseProviderSpecific(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        []dnsendpointv1alpha1.ProviderSpecificProperty
+	}{
+		{name: "none", annotations: map[string]string{annotationHostname: "vm.example.com"}},
+		{
+			name:        "single",
+			annotations: map[string]string{annotationProviderSpecificPrefix + "aws-failover": "PRIMARY"},
+			want:        []dnsendpointv1alpha1.ProviderSpecificProperty{{Name: "aws/failover", Value: "PRIMARY"}},
+		},
+		{
+			name: "multiple sorted by name",
+			annotations: map[string]string{
+				annotationProviderSpecificPrefix + "aws-health-check-id": "abc-123",
+				annotationProviderSpecificPrefix + "aws-failover":        "SECONDARY",
+				annotationProviderSpecificPrefix + "proxied":             "true",
+			},
+			want: []dnsendpointv1alpha1.ProviderSpecificProperty{
+				{Name: "aws/failover", Value: "SECONDARY"},
+				{Name: "aws/health-check-id", Value: "abc-123"},
+				{Name: "proxied", Value: "true"},
+			},
+		},
+		{
+			name: "empty name or value skipped",
+			annotations: map[string]string{
+				annotationProviderSpecificPrefix:                  "x",
+				annotationProviderSpecificPrefix + "aws-failover": " ",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseProviderSpecific(tt.annotations, DefaultSettings())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProviderSpecific_CustomPrefix(t *testing.T) {
+	settings := DefaultSettings()
+	settings.AnnotationPrefix = "dns.example.com/"
+	got := parseProviderSpecific(map[string]string{
+		"dns.example.com/provider-aws-failover":         "PRIMARY",
+		annotationProviderSpecificPrefix + "aws-region": "eu-west-1",
+	}, settings)
+	want := []dnsendpointv1alpha1.ProviderSpecificProperty{{Name: "aws/failover", Value: "PRIMARY"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReconcile_HealthCheckID(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{
+		annotationHostname:      "vm.example.com",
+		annotationHealthCheckID: "hc-1",
+		annotationWeight:        "5",
+		annotationProviderSpecificPrefix + "aws-health-check-id": "hc-ignored",
+		annotationProviderSpecificPrefix + "aws-failover":        "PRIMARY",
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.1", "2001:db8::1"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	want := dnsendpointv1alpha1.ProviderSpecific{
+		{Name: "aws/failover", Value: "PRIMARY"},
+		{Name: providerSpecificHealthCheckID, Value: "hc-1"},
+		{Name: providerSpecificWeight, Value: "5"},
+	}
+	for _, e := range getEndpoint(t, r, "vm").Spec.Endpoints {
+		if !reflect.DeepEqual(e.ProviderSpecific, want) {
+			t.Errorf("%s %s: provider-specific = %v, want %v", e.DNSName, e.RecordType, e.ProviderSpecific, want)
+		}
+	}
+}
+
+func TestReconcile_ProviderSpecificOnEveryRecord(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{
+		annotationHostname:   "vm.example.com,alias.example.com",
+		annotationRecordType: "CNAME",
+		annotationProviderSpecificPrefix + "cloudflare-proxied": "true",
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	// Properties are applied after the record type, so the CNAME gets them too.
+	want := dnsendpointv1alpha1.ProviderSpecific{{Name: "cloudflare/proxied", Value: "true"}}
+	eps := getEndpoint(t, r, "vm").Spec.Endpoints
+	if len(eps) != 2 {
+		t.Fatalf("expected an A record and a CNAME, got %v", eps)
+	}
+	for _, e := range eps {
+		if !reflect.DeepEqual(e.ProviderSpecific, want) {
+			t.Errorf("%s %s: provider-specific = %v, want %v", e.DNSName, e.RecordType, e.ProviderSpecific, want)
+		}
+	}
+}
+
+func TestApplyProviderSpecific_CopiesPerEndpoint(t *testing.T) {
+	eps := buildEndpoints([]string{"vm.example.com"}, []string{"10.0.0.1"}, []string{"2001:db8::1"}, 300, nil, nil, "")
+	props := []dnsendpointv1alpha1.ProviderSpecificProperty{{Name: "aws/failover", Value: "PRIMARY"}}
+	applyProviderSpecific(eps, props)
+
+	eps[0].ProviderSpecific[0].Value = "SECONDARY"
+	if eps[1].ProviderSpecific[0].Value != "PRIMARY" || props[0].Value != "PRIMARY" {
+		t.Errorf("expected every endpoint to get its own copy, got %v and %v", eps[1].ProviderSpecific, props)
+	}
+}
+
+func TestWatchedAnnotationsChanged_ProviderSpecific(t *testing.T) {
+	settings := DefaultSettings()
+	key := annotationProviderSpecificPrefix + "aws-failover"
+	tests := []struct {
+		name     string
+		old, new map[string]string
+		want     bool
+	}{
+		{"added", nil, map[string]string{key: "PRIMARY"}, true},
+		{"removed", map[string]string{key: "PRIMARY"}, nil, true},
+		{"changed", map[string]string{key: "PRIMARY"}, map[string]string{key: "SECONDARY"}, true},
+		{"unchanged", map[string]string{key: "PRIMARY"}, map[string]string{key: "PRIMARY"}, false},
+		{"unrelated", map[string]string{"team": "a"}, map[string]string{"team": "b"}, false},
+	}
+	for _, tt := range tests {
+		if got := watchedAnnotationsChanged(settings, tt.old, tt.new); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
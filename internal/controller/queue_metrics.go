@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// queueDepth reports how many VMIs are waiting in the VMI controller's workqueue.
+//
+// controller-runtime installs its own workqueue.MetricsProvider through
+// workqueue.SetProvider during init, and only the first call takes effect, so
+// the gauge is fed by wrapping the queue instead. The wrapped queue keeps the
+// controller-runtime provider, so the workqueue_* metrics are unaffected.
+var queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "queue_depth",
+	Help:      "Number of VirtualMachineInstances waiting to be reconciled.",
+})
+
+// depthTrackingQueue sets depth to the queue length after every operation that
+// can change it. Items added with a delay are counted once they become ready,
+// on the next operation.
+type depthTrackingQueue struct {
+	workqueue.TypedRateLimitingInterface[reconcile.Request]
+	depth prometheus.Gauge
+}
+
+// newDepthTrackingQueue returns a controller.Options.NewQueue function whose
+// queues report their length on depth.
+func newDepthTrackingQueue(depth prometheus.Gauge) func(string, workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+	return func(controllerName string, rateLimiter workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		return &depthTrackingQueue{
+			TypedRateLimitingInterface: workqueue.NewTypedRateLimitingQueueWithConfig(rateLimiter,
+				workqueue.TypedRateLimitingQueueConfig[reconcile.Request]{Name: controllerName}),
+			depth: depth,
+		}
+	}
+}
+
+func (q *depthTrackingQueue) update() {
+	q.depth.Set(float64(q.Len()))
+}
+
+func (q *depthTrackingQueue) Add(item reconcile.Request) {
+	q.TypedRateLimitingInterface.Add(item)
+	q.update()
+}
+
+func (q *depthTrackingQueue) AddAfter(item reconcile.Request, duration time.Duration) {
+	q.TypedRateLimitingInterface.AddAfter(item, duration)
+	q.update()
+}
+
+func (q *depthTrackingQueue) AddRateLimited(item reconcile.Request) {
+	q.TypedRateLimitingInterface.AddRateLimited(item)
+	q.update()
+}
+
+func (q *depthTrackingQueue) Get() (reconcile.Request, bool) {
+	item, shutdown := q.TypedRateLimitingInterface.Get()
+	q.update()
+	return item, shutdown
+}
+
+func (q *depthTrackingQueue) Done(item reconcile.Request) {
+	q.TypedRateLimitingInterface.Done(item)
+	q.update()
+}
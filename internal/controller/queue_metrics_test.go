@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestDepthTrackingQueue(t *testing.T) {
+	depth := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_queue_depth"})
+	q := newDepthTrackingQueue(depth)("test", workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer q.ShutDown()
+
+	a := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "a"}}
+	b := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "b"}}
+	q.Add(a)
+	q.Add(b)
+	q.Add(a) // already queued
+	if got := testutil.ToFloat64(depth); got != 2 {
+		t.Fatalf("depth after adds = %v, want 2", got)
+	}
+
+	item, _ := q.Get()
+	if got := testutil.ToFloat64(depth); got != 1 {
+		t.Errorf("depth after Get = %v, want 1", got)
+	}
+	q.Done(item)
+	item, _ = q.Get()
+	q.Done(item)
+	if got := testutil.ToFloat64(depth); got != 0 {
+		t.Errorf("depth after draining = %v, want 0", got)
+	}
+}
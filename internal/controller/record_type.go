@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// annotationRecordType forces the record type published for a VMI: "A" or
+// "AAAA" publish only that family, "CNAME" aliases every hostname after the
+// first to the first one.
+const annotationRecordType = defaultAnnotationPrefix + "record-type"
+
+// annotationTTLA and annotationTTLAAAA override the TTL of the VMI's A and
+// AAAA records respectively.
+const (
+	annotationTTLA    = annotationTTL + "-" + dnsendpointv1alpha1.RecordTypeA
+	annotationTTLAAAA = annotationTTL + "-" + dnsendpointv1alpha1.RecordTypeAAAA
+)
+
+// parsePerTypeTTL returns the TTL of each address record type: the value of
+// its ttl-A or ttl-AAAA annotation, or defaultTTL. Invalid values fall back to
+// defaultTTL and are reported through the returned error, which wraps
+// errInvalidAnnotation.
+func parsePerTypeTTL(annotations map[string]string, settings ControllerSettings, defaultTTL dnsendpointv1alpha1.TTL) (map[string]dnsendpointv1alpha1.TTL, error) {
+	ttls := map[string]dnsendpointv1alpha1.TTL{}
+	var errs []error
+	for recordType, key := range map[string]string{
+		dnsendpointv1alpha1.RecordTypeA:    annotationTTLA,
+		dnsendpointv1alpha1.RecordTypeAAAA: annotationTTLAAAA,
+	} {
+		key = settings.annotationKey(key)
+		ttl, err := ttlFromAnnotation(strings.TrimSpace(annotations[key]), defaultTTL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+		ttls[recordType] = ttl
+	}
+	return ttls, errors.Join(errs...)
+}
+
+// parseRecordType validates the record-type annotation. An empty value means
+// no override. Invalid values yield an error wrapping errInvalidAnnotation.
+func parseRecordType(raw string) (string, error) {
+	recordType := strings.ToUpper(strings.TrimSpace(raw))
+	switch recordType {
+	case "", dnsendpointv1alpha1.RecordTypeA, dnsendpointv1alpha1.RecordTypeAAAA, dnsendpointv1alpha1.RecordTypeCNAME:
+		return recordType, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported record type %q (want A, AAAA or CNAME)", errInvalidAnnotation, raw)
+	}
+}
+
+// applyRecordType rewrites endpoints built by buildEndpoints for recordType.
+// A and AAAA drop the endpoints of the other family. CNAME keeps the address
+// records of hostnames[0] and replaces those of every other hostname with a
+// CNAME to hostnames[0]. It fails, leaving endpoints unchanged, when
+// hostnames[0] is an IP address and so cannot be a CNAME target.
+func applyRecordType(endpoints []*dnsendpointv1alpha1.Endpoint, hostnames []string, recordType string) ([]*dnsendpointv1alpha1.Endpoint, error) {
+	switch recordType {
+	case dnsendpointv1alpha1.RecordTypeA, dnsendpointv1alpha1.RecordTypeAAAA:
+		var kept []*dnsendpointv1alpha1.Endpoint
+		for _, ep := range endpoints {
+			if ep.RecordType == recordType {
+				kept = append(kept, ep)
+			}
+		}
+		return kept, nil
+	case dnsendpointv1alpha1.RecordTypeCNAME:
+		if len(hostnames) == 0 {
+			return endpoints, nil
+		}
+		target := hostnames[0]
+		if net.ParseIP(target) != nil {
+			return endpoints, fmt.Errorf("%w: CNAME target %q is an IP address, not a domain name", errInvalidAnnotation, target)
+		}
+		var result []*dnsendpointv1alpha1.Endpoint
+		aliased := map[string]bool{}
+		for _, ep := range endpoints {
+			if ep.DNSName == target {
+				result = append(result, ep)
+				continue
+			}
+			if aliased[ep.DNSName] {
+				continue
+			}
+			aliased[ep.DNSName] = true
+			result = append(result, &dnsendpointv1alpha1.Endpoint{
+				DNSName:       ep.DNSName,
+				RecordType:    dnsendpointv1alpha1.RecordTypeCNAME,
+				Targets:       dnsendpointv1alpha1.Targets{target},
+				RecordTTL:     ep.RecordTTL,
+				SetIdentifier: ep.SetIdentifier,
+			})
+		}
+		slices.SortStableFunc(result, compareEndpoints)
+		return result, nil
+	default:
+		return endpoints, nil
+	}
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestParseRecordType(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"A", "A", false},
+		{" aaaa ", "AAAA", false},
+		{"cname", "CNAME", false},
+		{"TXT", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseRecordType(tt.raw)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseRecordType(%q) = %q, %v; want %q, error=%v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, errInvalidAnnotation) {
+			t.Errorf("parseRecordType(%q): expected errInvalidAnnotation, got %v", tt.raw, err)
+		}
+	}
+}
+
+// endpointTypes returns "<type> <name> <targets...>" for each endpoint.
+func endpointTypes(endpoints []*dnsendpointv1alpha1.Endpoint) []string {
+	var out []string
+	for _, ep := range endpoints {
+		out = append(out, ep.RecordType+" "+ep.DNSName+" "+ep.Targets.String())
+	}
+	return out
+}
+
+func TestApplyRecordType(t *testing.T) {
+	hostnames := []string{"vm.example.com", "www.example.com"}
+	tests := []struct {
+		recordType string
+		want       []string
+	}{
+		{"", []string{"A vm.example.com 10.0.0.1", "A www.example.com 10.0.0.1", "AAAA vm.example.com 2001:db8::1", "AAAA www.example.com 2001:db8::1"}},
+		{"A", []string{"A vm.example.com 10.0.0.1", "A www.example.com 10.0.0.1"}},
+		{"AAAA", []string{"AAAA vm.example.com 2001:db8::1", "AAAA www.example.com 2001:db8::1"}},
+		{"CNAME", []string{"A vm.example.com 10.0.0.1", "AAAA vm.example.com 2001:db8::1", "CNAME www.example.com vm.example.com"}},
+	}
+	for _, tt := range tests {
+		endpoints := buildEndpoints(hostnames, []string{"10.0.0.1"}, []string{"2001:db8::1"}, 300, nil, nil, "")
+		got, err := applyRecordType(endpoints, hostnames, tt.recordType)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.recordType, err)
+		}
+		if gotTypes := endpointTypes(got); !slices.Equal(gotTypes, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.recordType, gotTypes, tt.want)
+		}
+	}
+}
+
+func TestApplyRecordType_CNAMETargetIsIP(t *testing.T) {
+	hostnames := []string{"10.0.0.9", "www.example.com"}
+	endpoints := buildEndpoints(hostnames, []string{"10.0.0.1"}, nil, 300, nil, nil, "")
+
+	got, err := applyRecordType(endpoints, hostnames, dnsendpointv1alpha1.RecordTypeCNAME)
+	if !errors.Is(err, errInvalidAnnotation) {
+		t.Errorf("expected errInvalidAnnotation, got %v", err)
+	}
+	if len(got) != len(endpoints) {
+		t.Errorf("expected endpoints to be left unchanged, got %v", endpointTypes(got))
+	}
+}
+
+func TestReconcile_InvalidRecordTypeIgnored(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com", annotationRecordType: "MX"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.1", "2001:db8::1"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+	if got := getEndpoint(t, r, "vm").Spec.Endpoints; len(got) != 2 {
+		t.Errorf("expected both A and AAAA records with an invalid record type, got %v", endpointTypes(got))
+	}
+}
+
+func TestReconcile_RecordTypeA(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com", annotationRecordType: "A"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.1", "2001:db8::1"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+	got := getEndpoint(t, r, "vm").Spec.Endpoints
+	if len(got) != 1 || got[0].RecordType != dnsendpointv1alpha1.RecordTypeA {
+		t.Errorf("expected only an A record, got %v", endpointTypes(got))
+	}
+}
+
+func TestParsePerTypeTTL(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantA       dnsendpointv1alpha1.TTL
+		wantAAAA    dnsendpointv1alpha1.TTL
+		wantErr     bool
+	}{
+		{"A only", map[string]string{annotationTTLA: "30"}, 30, 300, false},
+		{"AAAA only", map[string]string{annotationTTLAAAA: "1h"}, 300, 3600, false},
+		{"both", map[string]string{annotationTTLA: "30", annotationTTLAAAA: "3600"}, 30, 3600, false},
+		{"missing", map[string]string{annotationTTL: "120"}, 300, 300, false},
+		{"invalid", map[string]string{annotationTTLA: "soon", annotationTTLAAAA: "60"}, 300, 60, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttls, err := parsePerTypeTTL(tt.annotations, DefaultSettings(), 300)
+			if tt.wantErr != errors.Is(err, errInvalidAnnotation) {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ttls[dnsendpointv1alpha1.RecordTypeA] != tt.wantA || ttls[dnsendpointv1alpha1.RecordTypeAAAA] != tt.wantAAAA {
+				t.Errorf("got %v, want A=%d AAAA=%d", ttls, tt.wantA, tt.wantAAAA)
+			}
+		})
+	}
+}
+
+func TestBuildEndpoints_PerTypeTTL(t *testing.T) {
+	typeTTLs := map[string]dnsendpointv1alpha1.TTL{dnsendpointv1alpha1.RecordTypeA: 30, dnsendpointv1alpha1.RecordTypeAAAA: 3600}
+	eps := buildEndpoints([]string{"a.example.com", "b.example.com"}, []string{"10.0.0.1"}, []string{"2001:db8::1"}, 300,
+		map[string]dnsendpointv1alpha1.TTL{"b.example.com": 90}, typeTTLs, "")
+	for _, ep := range eps {
+		want := typeTTLs[ep.RecordType]
+		if ep.DNSName == "b.example.com" {
+			// Per-hostname TTLs take precedence over per-type ones.
+			want = 90
+		}
+		if ep.RecordTTL != want {
+			t.Errorf("%s %s: RecordTTL = %d, want %d", ep.RecordType, ep.DNSName, ep.RecordTTL, want)
+		}
+	}
+}
+
+func TestReconcile_PerTypeTTL(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{
+		annotationHostname: "vm.example.com",
+		annotationTTL:      "120",
+		annotationTTLAAAA:  "3600",
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.1", "2001:db8::1"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+	reconcileVMI(t, r, "vm")
+
+	want := map[string]dnsendpointv1alpha1.TTL{dnsendpointv1alpha1.RecordTypeA: 120, dnsendpointv1alpha1.RecordTypeAAAA: 3600}
+	eps := getEndpoint(t, r, "vm").Spec.Endpoints
+	if len(eps) != 2 {
+		t.Fatalf("expected A and AAAA endpoints, got %v", eps)
+	}
+	for _, ep := range eps {
+		if ep.RecordTTL != want[ep.RecordType] {
+			t.Errorf("%s: RecordTTL = %d, want %d", ep.RecordType, ep.RecordTTL, want[ep.RecordType])
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package controller
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// endpoints returns the client for DNSEndpoint reads and writes: EndpointClient
+// when DNSEndpoints live in a remote cluster, the manager's client otherwise.
+func (r *VirtualMachineInstanceReconciler) endpoints() client.Client {
+	if r.EndpointClient != nil {
+		return r.EndpointClient
+	}
+	return r.Client
+}
+
+// remoteEndpoints reports whether DNSEndpoints are written to a remote cluster.
+// Owner references cannot point into another cluster, so remote DNSEndpoints
+// record their owner like those in a target namespace, and are deleted through
+// the cleanup finalizer rather than by garbage collection.
+func (r *VirtualMachineInstanceReconciler) remoteEndpoints() bool {
+	return r.EndpointClient != nil
+}
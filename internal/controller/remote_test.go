@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// newRemoteTestReconciler returns a reconciler reading VMIs from a local fake
+// client and writing DNSEndpoints to a separate remote one.
+func newRemoteTestReconciler(t *testing.T, objs ...client.Object) (*VirtualMachineInstanceReconciler, client.Client) {
+	t.Helper()
+	r := newTestReconciler(t, objs...)
+	remote := fake.NewClientBuilder().WithScheme(r.Scheme).Build()
+	r.EndpointClient = remote
+	return r, remote
+}
+
+func TestReconcile_RemoteEndpointClient(t *testing.T) {
+	ctx := context.Background()
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r, remote := newRemoteTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	key := client.ObjectKeyFromObject(vmi)
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	if err := remote.Get(ctx, key, ep); err != nil {
+		t.Fatalf("expected the DNSEndpoint in the remote cluster: %v", err)
+	}
+	if err := r.Get(ctx, key, &dnsendpointv1alpha1.DNSEndpoint{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected no DNSEndpoint in the local cluster, got %v", err)
+	}
+	if len(ep.OwnerReferences) != 0 {
+		t.Errorf("expected no owner reference across clusters, got %v", ep.OwnerReferences)
+	}
+	if owner, uid, ok := endpointOwner(ep); !ok || owner != key || uid != vmi.UID {
+		t.Errorf("endpointOwner = %v, %v, %v; want %v, %v", owner, uid, ok, key, vmi.UID)
+	}
+}
+
+func TestReconcile_RemoteEndpointDeletedWithAnnotation(t *testing.T) {
+	ctx := context.Background()
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r, remote := newRemoteTestReconciler(t, vmi)
+	reconcileVMI(t, r, "vm")
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	delete(got.Annotations, annotationHostname)
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+
+	if err := remote.Get(ctx, client.ObjectKeyFromObject(vmi), &dnsendpointv1alpha1.DNSEndpoint{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the remote DNSEndpoint to be deleted, got %v", err)
+	}
+	assertEvents(t, recordedEvents(r), "Normal "+eventReasonEndpointCreated, "Normal "+eventReasonEndpointDeleted)
+}
+
+func TestReconcile_RemoteUnavailable(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	unavailable := apierrors.NewServiceUnavailable("remote cluster down")
+	r.EndpointClient = fake.NewClientBuilder().WithScheme(r.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+			return unavailable
+		},
+		List: func(context.Context, client.WithWatch, client.ObjectList, ...client.ListOption) error {
+			return unavailable
+		},
+	}).Build()
+
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "vm"}})
+	if !apierrors.IsServiceUnavailable(err) {
+		t.Fatalf("expected the remote error to be returned for a retry, got %v", err)
+	}
+	// The VMI is still read from the local cluster.
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(vmi), &kubevirtv1.VirtualMachineInstance{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,120 @@
+//go:build integration
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// startResyncManager starts a manager running the VMI controller with the
+// given informer resync period, and returns a client and the controller's
+// configuration.
+func startResyncManager(t *testing.T, syncPeriod time.Duration) (client.Client, *ControllerConfig) {
+	t.Helper()
+	cfg := startTestEnv(t, &envtest.Environment{CRDs: []*apiextensionsv1.CustomResourceDefinition{vmiCRD(), dnsEndpointCRD()}})
+
+	scheme := newTestScheme(t)
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+		Cache:   cache.Options{SyncPeriod: ptr.To(syncPeriod)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := NewControllerConfig(DefaultSettings())
+	if err := (&VirtualMachineInstanceReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("test"),
+		Config:   config,
+	}).SetupWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = mgr.Start(ctx) }()
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, config
+}
+
+// createResyncTestVMI creates a running, annotated VMI named vm1 and returns a
+// function reporting the TTL of its DNSEndpoint, or 0 when there is none.
+func createResyncTestVMI(t *testing.T, c client.Client) func() dnsendpointv1alpha1.TTL {
+	t.Helper()
+	ctx := context.Background()
+	vmi := &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "vm1",
+			Namespace:   "default",
+			Annotations: map[string]string{annotationHostname: "vm1.example.com"},
+		},
+		Status: kubevirtv1.VirtualMachineInstanceStatus{
+			Phase:      kubevirtv1.Running,
+			Interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{{IP: "10.0.0.5", InfoSource: multusInfoSource}},
+			Conditions: []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if err := c.Create(ctx, vmi); err != nil {
+		t.Fatal(err)
+	}
+
+	return func() dnsendpointv1alpha1.TTL {
+		ep := &dnsendpointv1alpha1.DNSEndpoint{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(vmi), ep); err != nil || len(ep.Spec.Endpoints) == 0 {
+			return 0
+		}
+		return ep.Spec.Endpoints[0].RecordTTL
+	}
+}
+
+func TestManager_ResyncPeriodRepairsDrift(t *testing.T) {
+	c, config := startResyncManager(t, 2*time.Second)
+	endpointTTL := createResyncTestVMI(t, c)
+	waitFor(t, 30*time.Second, "DNSEndpoint was not created", func() bool { return endpointTTL() == defaultTTL })
+
+	// Changing the settings directly produces no watch event, so only a resync
+	// brings the DNSEndpoint up to date.
+	settings := DefaultSettings()
+	settings.DefaultTTL = 60
+	config.Set(settings)
+	waitFor(t, 30*time.Second, "resync did not apply the new default TTL", func() bool { return endpointTTL() == 60 })
+
+	// A manually deleted DNSEndpoint is re-created.
+	if err := c.Delete(context.Background(), &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Name: "vm1", Namespace: "default"}}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 30*time.Second, "DNSEndpoint was not re-created", func() bool { return endpointTTL() == 60 })
+}
+
+func TestManager_ResyncRecreatesDeletedEndpoint(t *testing.T) {
+	c, _ := startResyncManager(t, time.Second)
+	endpointTTL := createResyncTestVMI(t, c)
+	waitFor(t, 30*time.Second, "DNSEndpoint was not created", func() bool { return endpointTTL() == defaultTTL })
+
+	if err := c.Delete(context.Background(), &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Name: "vm1", Namespace: "default"}}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 2*time.Second, "DNSEndpoint was not re-created within 2s", func() bool { return endpointTTL() == defaultTTL })
+}
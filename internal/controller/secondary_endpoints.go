@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// Secondary DNSEndpoints are the ones a VMI publishes besides the DNSEndpoint
+// of its hostname annotation: split-horizon views and per-interface records.
+// They live in the VMI's namespace and are told apart by a marker label.
+
+// isSecondaryEndpoint reports whether ep is a split-horizon or per-interface DNSEndpoint.
+func isSecondaryEndpoint(ep *dnsendpointv1alpha1.DNSEndpoint) bool {
+	_, horizon := ep.Labels[labelHorizon]
+	_, iface := ep.Labels[labelInterface]
+	return horizon || iface
+}
+
+// secondaryHostnames parses and renders the hostnames of the secondary
+// DNSEndpoint at key and runs them through the hostname policy of Reconcile,
+// with the VMI's hostname prefix and suffix. Entries that fail are logged and
+// skipped.
+func (r *VirtualMachineInstanceReconciler) secondaryHostnames(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings, key client.ObjectKey, raw string) []string {
+	hostnames, err := renderHostnames(parseHostnames(raw), vmi, r.hostnameTemplates())
+	if err != nil {
+		log.FromContext(ctx).Info("skipping hostnames whose template failed to render", "vmi", client.ObjectKeyFromObject(vmi), "endpoint", key, "error", err.Error())
+		countReconcileError(err)
+	}
+	return r.hostnamePolicy().filter(ctx, vmi, "vmi", hostnames,
+		strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationHostnamePrefix)]),
+		strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationHostnameSuffix)]))
+}
+
+// secondaryHostnameConflict runs the conflict checks of Reconcile on the
+// hostnames of the secondary DNSEndpoint at key and reports a conflict the
+// same way. It returns true when the DNSEndpoint must be left as it is.
+func (r *VirtualMachineInstanceReconciler) secondaryHostnameConflict(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, key client.ObjectKey, hostnames []string) (bool, error) {
+	if len(hostnames) == 0 {
+		return false, nil
+	}
+	logger := log.FromContext(ctx)
+	vmiKey := client.ObjectKeyFromObject(vmi)
+	vmiConflict, err := r.findVMIHostnameConflict(ctx, vmi, key.Namespace, hostnames, "")
+	if err != nil {
+		return false, err
+	}
+	if vmiConflict != nil {
+		logger.Info("hostname also requested by another VMI, skipping", "vmi", vmiKey, "endpoint", key,
+			"hostname", vmiConflict.Hostname, "other", vmiConflict.Owner)
+		countReconcileError(errHostnameConflict)
+		r.reportVMIHostnameConflict(ctx, vmi, vmiConflict)
+		return true, nil
+	}
+	conflict, err := r.findHostnameConflict(ctx, vmi, key.Namespace, hostnames, "")
+	if err != nil {
+		return false, err
+	}
+	if conflict != nil {
+		logger.Info("hostname already claimed by another VMI, skipping", "vmi", vmiKey, "endpoint", key,
+			"hostname", conflict.Hostname, "owner", conflict.Owner)
+		countReconcileError(errHostnameConflict)
+		return true, r.reportHostnameConflict(ctx, vmi, key, conflict)
+	}
+	return false, nil
+}
+
+// writeSecondaryEndpoint creates or updates the secondary DNSEndpoint at key
+// with endpoints. Like the main DNSEndpoint it carries the VMI's labels and
+// propagated annotations; labels are merged on top, so the marker labels win.
+func (r *VirtualMachineInstanceReconciler) writeSecondaryEndpoint(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings, key client.ObjectKey, labels map[string]string, endpoints []*dnsendpointv1alpha1.Endpoint) error {
+	desired := &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	var previous []*dnsendpointv1alpha1.Endpoint
+	op, err := controllerutil.CreateOrUpdate(ctx, r.endpoints(), desired, func() error {
+		previous = desired.Spec.Endpoints
+		if r.Version != "" {
+			labels[labelControllerVersion] = r.Version
+		}
+		copyLabels(desired, vmi.Labels)
+		copyLabels(desired, labels)
+		setOwnerVMILabel(desired, vmi)
+		copyAnnotations(desired, vmi.Annotations, vmi.Annotations[settings.annotationKey(annotationPropagateAnnotations)], settings)
+		desired.Spec = dnsendpointv1alpha1.DNSEndpointSpec{Endpoints: endpoints}
+		if r.remoteEndpoints() {
+			return setCrossNamespaceOwner(desired, vmi)
+		}
+		return controllerutil.SetControllerReference(vmi, desired, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.recordEndpointWrite(ctx, vmi, desired, op, previous, true)
+	return nil
+}
+
+// deleteSecondaryEndpoint deletes the secondary DNSEndpoint at key, if it exists.
+func (r *VirtualMachineInstanceReconciler) deleteSecondaryEndpoint(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, key client.ObjectKey) error {
+	endpoint := &dnsendpointv1alpha1.DNSEndpoint{}
+	if err := r.endpoints().Get(ctx, key, endpoint); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return r.deleteEndpoints(ctx, vmi, endpoint)
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// annotationInternalHostname lists the hostnames published to internal DNS
+	// in split-horizon mode.
+	annotationInternalHostname = defaultAnnotationPrefix + "internal-hostname"
+	// annotationExternalHostname lists the hostnames published to external DNS
+	// in split-horizon mode.
+	annotationExternalHostname = defaultAnnotationPrefix + "external-hostname"
+	// annotationInternalAllowedCIDRs limits the internal records to these
+	// networks. Unset publishes every address.
+	annotationInternalAllowedCIDRs = defaultAnnotationPrefix + "internal-allowed-cidrs"
+	// annotationExternalAllowedCIDRs limits the external records to these
+	// networks. Unset publishes only public addresses.
+	annotationExternalAllowedCIDRs = defaultAnnotationPrefix + "external-allowed-cidrs"
+
+	// labelHorizon marks a split-horizon DNSEndpoint with its view, so External-DNS
+	// instances can select the view they serve.
+	labelHorizon = managedAnnotationPrefix + "horizon"
+)
+
+// horizonView is one side of split-horizon DNS.
+type horizonView struct {
+	// name is the view, used as the DNSEndpoint name suffix and labelHorizon value.
+	name string
+	// hostnameAnnotation and cidrsAnnotation hold the view's hostnames and networks.
+	hostnameAnnotation, cidrsAnnotation string
+	// publicOnly restricts the view to public addresses when cidrsAnnotation is unset.
+	publicOnly bool
+}
+
+// horizonViews are the split-horizon views, each published in its own DNSEndpoint.
+var horizonViews = []horizonView{
+	{name: "internal", hostnameAnnotation: annotationInternalHostname, cidrsAnnotation: annotationInternalAllowedCIDRs},
+	{name: "external", hostnameAnnotation: annotationExternalHostname, cidrsAnnotation: annotationExternalAllowedCIDRs, publicOnly: true},
+}
+
+// horizonKey returns the DNSEndpoint key of the VMI's view: <vmi-name>-<view>
+// in the VMI's namespace.
+func horizonKey(vmi *kubevirtv1.VirtualMachineInstance, view horizonView) client.ObjectKey {
+	return client.ObjectKey{Namespace: vmi.Namespace, Name: vmi.Name + "-" + view.name}
+}
+
+// hasHorizonHostnames reports whether the VMI requests any split-horizon records.
+func hasHorizonHostnames(vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings) bool {
+	for _, view := range horizonViews {
+		if strings.TrimSpace(vmi.Annotations[settings.annotationKey(view.hostnameAnnotation)]) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// filterIPs returns the addresses the view publishes. Invalid CIDRs are
+// reported through the returned error, which wraps errInvalidAnnotation.
+func (v horizonView) filterIPs(annotations map[string]string, settings ControllerSettings, ipv4, ipv6 []string) ([]string, []string, error) {
+	cidrs, err := parseCIDRList(annotations[settings.annotationKey(v.cidrsAnnotation)])
+	allows := func(ip net.IP) bool {
+		if len(cidrs) > 0 {
+			return ipMatchesCIDRs(ip, cidrs)
+		}
+		return !v.publicOnly || !isPrivateIP(ip)
+	}
+	keep := func(addrs []string) []string {
+		var kept []string
+		for _, addr := range addrs {
+			if ip := net.ParseIP(addr); ip != nil && allows(ip) {
+				kept = append(kept, addr)
+			}
+		}
+		return kept
+	}
+	return keep(ipv4), keep(ipv6), err
+}
+
+// reconcileSplitHorizon creates or updates one DNSEndpoint per split-horizon
+// view with hostnames and addresses, and deletes the DNSEndpoints of views
+// that have neither. A view whose hostnames another VMI has the better claim
+// to is reported and left as it is. ipv4 and ipv6 are the VMI's resolved
+// addresses.
+func (r *VirtualMachineInstanceReconciler) reconcileSplitHorizon(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings, ipv4, ipv6 []string, ttl dnsendpointv1alpha1.TTL) error {
+	logger := log.FromContext(ctx)
+	var errs []error
+	for _, view := range horizonViews {
+		key := horizonKey(vmi, view)
+		hostnames := r.secondaryHostnames(ctx, vmi, settings, key, vmi.Annotations[settings.annotationKey(view.hostnameAnnotation)])
+		viewIPv4, viewIPv6, err := view.filterIPs(vmi.Annotations, settings, ipv4, ipv6)
+		if err != nil {
+			logger.Info("ignoring invalid split-horizon CIDRs", "vmi", client.ObjectKeyFromObject(vmi), "view", view.name, "error", err.Error())
+			countReconcileError(err)
+		}
+
+		if len(hostnames) == 0 || len(viewIPv4)+len(viewIPv6) == 0 {
+			errs = append(errs, r.deleteSecondaryEndpoint(ctx, vmi, key))
+			continue
+		}
+		if conflicted, err := r.secondaryHostnameConflict(ctx, vmi, key, hostnames); conflicted || err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, r.writeSecondaryEndpoint(ctx, vmi, settings, key, map[string]string{labelHorizon: view.name},
+			buildEndpoints(hostnames, viewIPv4, viewIPv6, ttl, nil, nil, "")))
+	}
+	return errors.Join(errs...)
+}
+
+// deleteHorizonEndpoints deletes the DNSEndpoints of every view of the VMI.
+func (r *VirtualMachineInstanceReconciler) deleteHorizonEndpoints(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance) error {
+	var errs []error
+	for _, view := range horizonViews {
+		errs = append(errs, r.deleteSecondaryEndpoint(ctx, vmi, horizonKey(vmi, view)))
+	}
+	return errors.Join(errs...)
+}
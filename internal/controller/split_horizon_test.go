@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// newSplitHorizonVMI returns a VMI with a private and a public address.
+func newSplitHorizonVMI(annotations map[string]string) *kubevirtv1.VirtualMachineInstance {
+	return newTestVMI("vm", annotations, kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		IPs: []string{"10.0.0.1", "203.0.113.10"}, InfoSource: guestAgentInfoSource,
+	})
+}
+
+// lookupEndpoint returns the named DNSEndpoint from the default namespace, or nil if it does not exist.
+func lookupEndpoint(t *testing.T, r *VirtualMachineInstanceReconciler, name string) *dnsendpointv1alpha1.DNSEndpoint {
+	t.Helper()
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, ep)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ep
+}
+
+// endpointTargets maps each DNS name and record type in ep to its targets.
+func endpointTargets(ep *dnsendpointv1alpha1.DNSEndpoint) map[string][]string {
+	targets := map[string][]string{}
+	for _, e := range ep.Spec.Endpoints {
+		targets[e.DNSName+" "+e.RecordType] = e.Targets
+	}
+	return targets
+}
+
+func TestReconcile_SplitHorizonCreatesViews(t *testing.T) {
+	vmi := newSplitHorizonVMI(map[string]string{
+		annotationHostname:         "vm.example.com",
+		annotationInternalHostname: "vm.corp.example.com",
+		annotationExternalHostname: "vm.example.org",
+	})
+	r := newTestReconciler(t, vmi)
+	r.SplitHorizon = true
+
+	reconcileVMI(t, r, "vm")
+
+	if getEndpoint(t, r, "vm") == nil {
+		t.Fatal("expected the hostname annotation's DNSEndpoint to be unchanged")
+	}
+	internal := lookupEndpoint(t, r, "vm-internal")
+	if internal == nil {
+		t.Fatal("expected the internal DNSEndpoint")
+	}
+	if got := endpointTargets(internal)["vm.corp.example.com A"]; len(got) != 2 {
+		t.Errorf("internal targets = %v, want both addresses", got)
+	}
+	if internal.Labels[labelHorizon] != "internal" {
+		t.Errorf("internal %s label = %q", labelHorizon, internal.Labels[labelHorizon])
+	}
+	if len(internal.OwnerReferences) != 1 || internal.OwnerReferences[0].UID != vmi.UID {
+		t.Errorf("expected the VMI to own the internal DNSEndpoint, got %v", internal.OwnerReferences)
+	}
+
+	external := lookupEndpoint(t, r, "vm-external")
+	if external == nil {
+		t.Fatal("expected the external DNSEndpoint")
+	}
+	if got := endpointTargets(external)["vm.example.org A"]; len(got) != 1 || got[0] != "203.0.113.10" {
+		t.Errorf("external targets = %v, want only the public address", got)
+	}
+}
+
+func TestReconcile_SplitHorizonAllowedCIDRs(t *testing.T) {
+	vmi := newSplitHorizonVMI(map[string]string{
+		annotationInternalHostname:     "vm.corp.example.com",
+		annotationInternalAllowedCIDRs: "10.0.0.0/8",
+	})
+	r := newTestReconciler(t, vmi)
+	r.SplitHorizon = true
+
+	reconcileVMI(t, r, "vm")
+
+	internal := lookupEndpoint(t, r, "vm-internal")
+	if internal == nil {
+		t.Fatal("expected the internal DNSEndpoint")
+	}
+	if got := endpointTargets(internal)["vm.corp.example.com A"]; len(got) != 1 || got[0] != "10.0.0.1" {
+		t.Errorf("internal targets = %v, want only 10.0.0.1", got)
+	}
+	if lookupEndpoint(t, r, "vm-external") != nil {
+		t.Error("expected no external DNSEndpoint without external hostnames")
+	}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(vmi), &dnsendpointv1alpha1.DNSEndpoint{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected no DNSEndpoint without the hostname annotation, got %v", err)
+	}
+}
+
+func TestReconcile_SplitHorizonDeletesViews(t *testing.T) {
+	ctx := context.Background()
+	vmi := newSplitHorizonVMI(map[string]string{
+		annotationInternalHostname: "vm.corp.example.com",
+		annotationExternalHostname: "vm.example.org",
+	})
+	r := newTestReconciler(t, vmi)
+	r.SplitHorizon = true
+	reconcileVMI(t, r, "vm")
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	delete(got.Annotations, annotationExternalHostname)
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+
+	if lookupEndpoint(t, r, "vm-external") != nil {
+		t.Error("expected the external DNSEndpoint to be deleted with its annotation")
+	}
+	if lookupEndpoint(t, r, "vm-internal") == nil {
+		t.Error("expected the internal DNSEndpoint to remain")
+	}
+
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	delete(got.Annotations, annotationInternalHostname)
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+
+	if lookupEndpoint(t, r, "vm-internal") != nil {
+		t.Error("expected the internal DNSEndpoint to be deleted with its annotation")
+	}
+	assertEvents(t, recordedEvents(r),
+		"Normal "+eventReasonEndpointCreated, "Normal "+eventReasonEndpointCreated,
+		"Normal "+eventReasonEndpointDeleted, "Normal "+eventReasonEndpointDeleted)
+}
+
+func TestReconcile_SplitHorizonDisabled(t *testing.T) {
+	vmi := newSplitHorizonVMI(map[string]string{
+		annotationHostname:         "vm.example.com",
+		annotationInternalHostname: "vm.corp.example.com",
+	})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	if getEndpoint(t, r, "vm") == nil {
+		t.Fatal("expected the hostname annotation's DNSEndpoint")
+	}
+	if lookupEndpoint(t, r, "vm-internal") != nil {
+		t.Error("expected no internal DNSEndpoint without --split-horizon")
+	}
+}
+
+func TestReconcile_SplitHorizonAppliesHostnamePolicy(t *testing.T) {
+	vmi := newSplitHorizonVMI(map[string]string{
+		annotationHostnamePrefix:   "prod-",
+		annotationInternalHostname: "vm.corp.example.com,*.corp.example.com",
+		annotationExternalHostname: "vm.example.org",
+	})
+	r := newTestReconciler(t, vmi)
+	r.SplitHorizon = true
+	r.RejectWildcards = true
+	r.ZoneDenylist = []string{"example.org"}
+
+	reconcileVMI(t, r, "vm")
+
+	internal := lookupEndpoint(t, r, "vm-internal")
+	if internal == nil {
+		t.Fatal("expected the internal DNSEndpoint")
+	}
+	if got := endpointTargets(internal); len(got) != 1 || got["prod-vm.corp.example.com A"] == nil {
+		t.Errorf("internal records = %v, want only the prefixed non-wildcard hostname", got)
+	}
+	if lookupEndpoint(t, r, "vm-external") != nil {
+		t.Error("expected no external DNSEndpoint for a hostname in a denied zone")
+	}
+}
+
+func TestReconcile_SplitHorizonHostnameConflict(t *testing.T) {
+	iface := kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.5"}, InfoSource: guestAgentInfoSource}
+	incumbent := newTestVMI("incumbent", map[string]string{annotationHostname: "vm.corp.example.com"}, iface)
+	r := newTestReconciler(t, incumbent)
+	r.SplitHorizon = true
+	reconcileVMI(t, r, "incumbent")
+
+	newcomer := newTestVMI("newcomer", map[string]string{annotationInternalHostname: "vm.corp.example.com"}, iface)
+	if err := r.Create(context.Background(), newcomer); err != nil {
+		t.Fatal(err)
+	}
+	recordedEvents(r)
+	reconcileVMI(t, r, "newcomer")
+
+	if lookupEndpoint(t, r, "newcomer-internal") != nil {
+		t.Error("expected no internal DNSEndpoint for a hostname another VMI publishes")
+	}
+	assertEvents(t, recordedEvents(r), "Warning "+eventReasonHostnameConflict, "Warning "+eventReasonHostnameConflict)
+}
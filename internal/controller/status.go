@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// managedAnnotationPrefix is used for annotations the controller writes on DNSEndpoints.
+	managedAnnotationPrefix = "external-dns.kubevirt.io/"
+	// annotationConditions holds the JSON-encoded reconcile conditions on a managed DNSEndpoint.
+	// The DNSEndpoint status only carries observedGeneration, so conditions live in an annotation.
+	annotationConditions = managedAnnotationPrefix + "conditions"
+	// labelLastReconcileTime holds the time of the last reconcile that changed a
+	// managed DNSEndpoint. Label values cannot contain colons, so it uses
+	// lastReconcileTimeLabelFormat; annotationLastReconcileTime has the same time
+	// in RFC 3339.
+	labelLastReconcileTime = managedAnnotationPrefix + "last-reconcile-time"
+	// annotationLastReconcileTime holds the RFC 3339 time of the last reconcile
+	// that changed the DNSEndpoint.
+	annotationLastReconcileTime = managedAnnotationPrefix + "last-reconcile-time"
+	// labelControllerVersion holds the version of the controller that last wrote a managed DNSEndpoint.
+	labelControllerVersion = managedAnnotationPrefix + "controller-version"
+	// lastReconcileTimeLabelFormat is RFC 3339 in UTC with the colons replaced by
+	// dashes. Values sort lexically in time order.
+	lastReconcileTimeLabelFormat = "2006-01-02T15-04-05Z"
+
+	// conditionReady reports whether the DNSEndpoint reflects the VMI's desired state.
+	conditionReady = "Ready"
+	// conditionIPsResolved reports whether IP addresses could be resolved for the VMI.
+	conditionIPsResolved = "IPsResolved"
+
+	reasonSynced          = "Synced"
+	reasonSyncFailed      = "SyncFailed"
+	reasonIPsAvailable    = "IPsAvailable"
+	reasonIPsNotAvailable = "IPsNotAvailable"
+)
+
+// endpointConditions decodes the conditions stored on the DNSEndpoint.
+// A missing or malformed annotation yields an empty list.
+func endpointConditions(ep *dnsendpointv1alpha1.DNSEndpoint) []metav1.Condition {
+	raw, ok := ep.Annotations[annotationConditions]
+	if !ok || raw == "" {
+		return nil
+	}
+	var conditions []metav1.Condition
+	if err := json.Unmarshal([]byte(raw), &conditions); err != nil {
+		return nil
+	}
+	return conditions
+}
+
+// setEndpointConditions merges the given conditions into the DNSEndpoint's
+// conditions annotation using meta.SetStatusCondition, so LastTransitionTime
+// only moves when a condition's status actually changes.
+func setEndpointConditions(ep *dnsendpointv1alpha1.DNSEndpoint, conditions ...metav1.Condition) error {
+	current := endpointConditions(ep)
+	for _, c := range conditions {
+		meta.SetStatusCondition(&current, c)
+	}
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	if ep.Annotations == nil {
+		ep.Annotations = map[string]string{}
+	}
+	ep.Annotations[annotationConditions] = string(raw)
+	return nil
+}
+
+// setLastReconcileTime stamps ep with the time of the current reconcile. It is
+// only called for reconciles that change ep otherwise.
+func setLastReconcileTime(ep *dnsendpointv1alpha1.DNSEndpoint, now time.Time) {
+	now = now.UTC()
+	if ep.Labels == nil {
+		ep.Labels = map[string]string{}
+	}
+	ep.Labels[labelLastReconcileTime] = now.Format(lastReconcileTimeLabelFormat)
+	if ep.Annotations == nil {
+		ep.Annotations = map[string]string{}
+	}
+	ep.Annotations[annotationLastReconcileTime] = now.Format(time.RFC3339)
+}
+
+// changedBesidesReconcileTime reports whether before and after differ in
+// anything other than the last-reconcile-time stamp.
+func changedBesidesReconcileTime(before, after *dnsendpointv1alpha1.DNSEndpoint) bool {
+	before, after = before.DeepCopy(), after.DeepCopy()
+	for _, ep := range []*dnsendpointv1alpha1.DNSEndpoint{before, after} {
+		delete(ep.Labels, labelLastReconcileTime)
+		delete(ep.Annotations, annotationLastReconcileTime)
+	}
+	return !equality.Semantic.DeepEqual(before, after)
+}
+
+// newCondition builds a condition stamped with the VMI's generation.
+func newCondition(vmi *kubevirtv1.VirtualMachineInstance, conditionType string, status metav1.ConditionStatus, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: vmi.Generation,
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// patchEndpointConditions records conditions on the existing DNSEndpoint at key.
+// It is a no-op when the endpoint does not exist yet.
+func (r *VirtualMachineInstanceReconciler) patchEndpointConditions(ctx context.Context, key client.ObjectKey, conditions ...metav1.Condition) error {
+	endpoint := &dnsendpointv1alpha1.DNSEndpoint{}
+	err := r.endpoints().Get(ctx, key, endpoint)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(endpoint.DeepCopy())
+	if err := setEndpointConditions(endpoint, conditions...); err != nil {
+		return err
+	}
+	return r.endpoints().Patch(ctx, endpoint, patch)
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestSetEndpointConditions_PreservesTransitionTime(t *testing.T) {
+	vmi := newTestVMI("vm", nil)
+	vmi.Generation = 3
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+
+	if err := setEndpointConditions(ep, newCondition(vmi, conditionReady, metav1.ConditionTrue, reasonSynced, "")); err != nil {
+		t.Fatal(err)
+	}
+	first := meta.FindStatusCondition(endpointConditions(ep), conditionReady)
+	if first == nil || first.ObservedGeneration != 3 {
+		t.Fatalf("expected Ready condition with observedGeneration 3, got %+v", first)
+	}
+
+	if err := setEndpointConditions(ep, newCondition(vmi, conditionReady, metav1.ConditionTrue, reasonSynced, "")); err != nil {
+		t.Fatal(err)
+	}
+	second := meta.FindStatusCondition(endpointConditions(ep), conditionReady)
+	if !second.LastTransitionTime.Equal(&first.LastTransitionTime) {
+		t.Errorf("expected LastTransitionTime to be preserved, got %v then %v", first.LastTransitionTime, second.LastTransitionTime)
+	}
+}
+
+func TestEndpointConditions_MalformedAnnotation(t *testing.T) {
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	ep.Annotations = map[string]string{annotationConditions: "not-json"}
+	if got := endpointConditions(ep); got != nil {
+		t.Errorf("expected no conditions for malformed annotation, got %v", got)
+	}
+}
+
+func TestReconcile_SetsReadyCondition(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	conditions := endpointConditions(getEndpoint(t, r, "vm"))
+	if !meta.IsStatusConditionTrue(conditions, conditionReady) {
+		t.Errorf("expected Ready=True, got %v", conditions)
+	}
+	if !meta.IsStatusConditionTrue(conditions, conditionIPsResolved) {
+		t.Errorf("expected IPsResolved=True, got %v", conditions)
+	}
+}
+
+func TestReconcile_IPsResolvedFalseWhenIPsDisappear(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	existing := &dnsendpointv1alpha1.DNSEndpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: "vm", Namespace: "default"},
+	}
+	r := newTestReconciler(t, vmi, existing)
+
+	reconcileVMI(t, r, "vm")
+
+	conditions := endpointConditions(getEndpoint(t, r, "vm"))
+	c := meta.FindStatusCondition(conditions, conditionIPsResolved)
+	if c == nil || c.Status != metav1.ConditionFalse || c.Reason != reasonIPsNotAvailable {
+		t.Errorf("expected IPsResolved=False/%s, got %+v", reasonIPsNotAvailable, c)
+	}
+}
+
+func TestReconcile_LastReconcileTime(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	reconcileVMI(t, r, "vm")
+	ep := getEndpoint(t, r, "vm")
+	if got := ep.Labels[labelLastReconcileTime]; got != "2026-10-16T12-00-00Z" {
+		t.Errorf("label after create = %q", got)
+	}
+	if got := ep.Annotations[annotationLastReconcileTime]; got != "2026-10-16T12:00:00Z" {
+		t.Errorf("annotation after create = %q", got)
+	}
+
+	// An idle VMI reconciled later is not written again.
+	version := ep.ResourceVersion
+	now = now.Add(90 * time.Second)
+	reconcileVMI(t, r, "vm")
+	ep = getEndpoint(t, r, "vm")
+	if ep.ResourceVersion != version {
+		t.Errorf("expected no write for an unchanged DNSEndpoint, resourceVersion %s -> %s", version, ep.ResourceVersion)
+	}
+	if got := ep.Labels[labelLastReconcileTime]; got != "2026-10-16T12-00-00Z" {
+		t.Errorf("label after an idle reconcile = %q", got)
+	}
+	assertEvents(t, recordedEvents(r), "Normal "+eventReasonEndpointCreated)
+
+	// A change refreshes the stamp.
+	vmi = &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, vmi); err != nil {
+		t.Fatal(err)
+	}
+	vmi.Status.Interfaces[0].IP = "10.0.0.2"
+	if err := r.Update(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(90 * time.Second)
+	reconcileVMI(t, r, "vm")
+	ep = getEndpoint(t, r, "vm")
+	if got := ep.Labels[labelLastReconcileTime]; got != "2026-10-16T12-03-00Z" {
+		t.Errorf("label after a change = %q", got)
+	}
+	if got := ep.Annotations[annotationLastReconcileTime]; got != "2026-10-16T12:03:00Z" {
+		t.Errorf("annotation after a change = %q", got)
+	}
+}
+
+func TestReconcile_ControllerVersionLabel(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.Version = "v1.2.3-4-gabcdef0"
+
+	reconcileVMI(t, r, "vm")
+	if got := getEndpoint(t, r, "vm").Labels[labelControllerVersion]; got != "v1.2.3-4-gabcdef0" {
+		t.Errorf("controller-version label = %q, want the injected version", got)
+	}
+
+	r.Version = "v1.3.0"
+	r.now = func() time.Time { return time.Now().Add(time.Hour) }
+	reconcileVMI(t, r, "vm")
+	if got := getEndpoint(t, r, "vm").Labels[labelControllerVersion]; got != "v1.3.0" {
+		t.Errorf("controller-version label after upgrade = %q, want v1.3.0", got)
+	}
+}
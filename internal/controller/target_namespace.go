@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// annotationTargetNamespace moves the VMI's DNSEndpoint into another namespace.
+	annotationTargetNamespace = defaultAnnotationPrefix + "target-namespace"
+	// labelOwnerUID holds the UID of the VMI that owns a DNSEndpoint created in
+	// another namespace, where an owner reference cannot point at the VMI.
+	labelOwnerUID = managedAnnotationPrefix + "owner-uid"
+	// labelOwnerVMI holds the name of the VMI that owns a DNSEndpoint, so
+	// its DNSEndpoints can be found whatever they are named. It is left off
+	// when the VMI name is too long for a label value.
+	labelOwnerVMI = managedAnnotationPrefix + "owner-vmi"
+	// annotationOwner holds the "namespace/name" of the VMI that owns a
+	// DNSEndpoint created in another namespace.
+	annotationOwner = managedAnnotationPrefix + "owner"
+	// eventReasonEndpointOwnedByOther is emitted when the VMI's DNSEndpoint
+	// already exists and belongs to another VMI.
+	eventReasonEndpointOwnedByOther = "DNSEndpointOwnedByOther"
+)
+
+// errEndpointOwnedByOther marks writes refused because the DNSEndpoint at the
+// VMI's key belongs to another VMI.
+var errEndpointOwnedByOther = errors.New("DNSEndpoint is owned by another VirtualMachineInstance")
+
+// endpointKey returns where the VMI's DNSEndpoint named name lives. Without
+// the target-namespace annotation it is in the VMI's namespace. In another
+// namespace it is named <vmi-namespace>-<name>-<hash>, where the hash of
+// "<vmi-namespace>/<name>" keeps VMIs from different namespaces apart even
+// when the dashes line up (namespace a-b with VMI c, namespace a with VMI b-c).
+// A namespace that is invalid, or not in allowed when allowed is non-empty,
+// falls back to the VMI's own and is reported through an error wrapping
+// errInvalidAnnotation.
+func endpointKey(vmi *kubevirtv1.VirtualMachineInstance, name string, settings ControllerSettings, allowed []string) (client.ObjectKey, error) {
+	local := client.ObjectKey{Namespace: vmi.Namespace, Name: name}
+	ns := strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationTargetNamespace)])
+	if ns == "" || ns == vmi.Namespace {
+		return local, nil
+	}
+	if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+		return local, fmt.Errorf("%w: target namespace %q: %s", errInvalidAnnotation, ns, strings.Join(errs, "; "))
+	}
+	if len(allowed) > 0 && !slices.Contains(allowed, ns) {
+		return local, fmt.Errorf("%w: target namespace %q is not in --allowed-target-namespaces", errInvalidAnnotation, ns)
+	}
+	sum := sha256.Sum256([]byte(vmi.Namespace + "/" + name))
+	return client.ObjectKey{Namespace: ns, Name: vmi.Namespace + "-" + name + "-" + hex.EncodeToString(sum[:4])}, nil
+}
+
+// setCrossNamespaceOwner records the VMI as the owner of a DNSEndpoint in
+// another namespace, in place of the owner reference. It refuses, with an
+// error wrapping errEndpointOwnedByOther, to take over a DNSEndpoint that
+// already belongs to another VMI.
+func setCrossNamespaceOwner(ep *dnsendpointv1alpha1.DNSEndpoint, vmi *kubevirtv1.VirtualMachineInstance) error {
+	if owner, uid, ok := endpointOwner(ep); ok && uid != vmi.UID {
+		return fmt.Errorf("%w: DNSEndpoint %s/%s belongs to %s", errEndpointOwnedByOther, ep.Namespace, ep.Name, owner)
+	}
+	if ep.Labels == nil {
+		ep.Labels = map[string]string{}
+	}
+	ep.Labels[labelOwnerUID] = string(vmi.UID)
+	if ep.Annotations == nil {
+		ep.Annotations = map[string]string{}
+	}
+	ep.Annotations[annotationOwner] = vmi.Namespace + "/" + vmi.Name
+	return nil
+}
+
+// setOwnerVMILabel labels ep with the name of the VMI that owns it.
+func setOwnerVMILabel(ep *dnsendpointv1alpha1.DNSEndpoint, vmi *kubevirtv1.VirtualMachineInstance) {
+	if len(validation.IsValidLabelValue(vmi.Name)) > 0 {
+		return
+	}
+	if ep.Labels == nil {
+		ep.Labels = map[string]string{}
+	}
+	ep.Labels[labelOwnerVMI] = vmi.Name
+}
+
+// endpointOwner returns the VMI that owns the DNSEndpoint, either through its
+// controller reference or, for endpoints in another namespace, through the
+// owner label and annotation. ok is false for endpoints not owned by a VMI.
+func endpointOwner(ep *dnsendpointv1alpha1.DNSEndpoint) (owner types.NamespacedName, uid types.UID, ok bool) {
+	if ref := metav1.GetControllerOf(ep); ref != nil {
+		if ref.Kind != "VirtualMachineInstance" || ref.APIVersion != kubevirtv1.SchemeGroupVersion.String() {
+			return types.NamespacedName{}, "", false
+		}
+		return types.NamespacedName{Namespace: ep.Namespace, Name: ref.Name}, ref.UID, true
+	}
+	ns, name, found := strings.Cut(ep.Annotations[annotationOwner], "/")
+	if !found || ns == "" || name == "" || ep.Labels[labelOwnerUID] == "" {
+		return types.NamespacedName{}, "", false
+	}
+	return types.NamespacedName{Namespace: ns, Name: name}, types.UID(ep.Labels[labelOwnerUID]), true
+}
+
+// crossNamespaceOwnerRequests maps a DNSEndpoint in another namespace to a
+// reconcile of its owning VMI, so drift is repaired like it is for endpoints
+// found through their owner reference.
+func crossNamespaceOwnerRequests(_ context.Context, obj client.Object) []reconcile.Request {
+	ep, ok := obj.(*dnsendpointv1alpha1.DNSEndpoint)
+	if !ok || metav1.GetControllerOf(ep) != nil {
+		return nil
+	}
+	owner, _, ok := endpointOwner(ep)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: owner}}
+}
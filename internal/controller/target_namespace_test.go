@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func newCrossNamespaceVMI(targetNamespace string) *kubevirtv1.VirtualMachineInstance {
+	return newTestVMI("vm", map[string]string{
+		annotationHostname:        "vm.example.com",
+		annotationTargetNamespace: targetNamespace,
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+}
+
+func TestEndpointKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		want    client.ObjectKey
+		wantErr bool
+	}{
+		{"absent", "", client.ObjectKey{Namespace: "default", Name: "vm"}, false},
+		{"own namespace", "default", client.ObjectKey{Namespace: "default", Name: "vm"}, false},
+		{"other namespace", " dns-management ", client.ObjectKey{Namespace: "dns-management", Name: "default-vm-37b132b9"}, false},
+		{"invalid", "DNS_Management", client.ObjectKey{Namespace: "default", Name: "vm"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := endpointKey(newCrossNamespaceVMI(tt.target), "vm", DefaultSettings(), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("endpointKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointKey_AllowedTargetNamespaces(t *testing.T) {
+	vmi := newCrossNamespaceVMI("dns-management")
+	if got, err := endpointKey(vmi, "vm", DefaultSettings(), []string{"dns-management"}); err != nil || got.Namespace != "dns-management" {
+		t.Errorf("expected an allowed target namespace to be used, got %v, %v", got, err)
+	}
+	got, err := endpointKey(vmi, "vm", DefaultSettings(), []string{"dns"})
+	if !errors.Is(err, errInvalidAnnotation) {
+		t.Errorf("expected errInvalidAnnotation, got %v", err)
+	}
+	if want := (client.ObjectKey{Namespace: "default", Name: "vm"}); got != want {
+		t.Errorf("endpointKey() = %v, want the fallback %v", got, want)
+	}
+}
+
+func TestEndpointKey_NamespacesDoNotCollide(t *testing.T) {
+	a := newTestVMI("c", map[string]string{annotationTargetNamespace: "dns"})
+	a.Namespace = "a-b"
+	b := newTestVMI("b-c", map[string]string{annotationTargetNamespace: "dns"})
+	b.Namespace = "a"
+
+	keyA, _ := endpointKey(a, a.Name, DefaultSettings(), nil)
+	keyB, _ := endpointKey(b, b.Name, DefaultSettings(), nil)
+	if keyA == keyB {
+		t.Errorf("expected a-b/c and a/b-c to get different DNSEndpoints, both got %v", keyA)
+	}
+}
+
+func TestReconcile_TargetNamespaceRefusesOtherVMIsEndpoint(t *testing.T) {
+	vmi := newCrossNamespaceVMI("dns-management")
+	key, _ := endpointKey(vmi, "vm", DefaultSettings(), nil)
+	taken := &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	taken.Spec.Endpoints = []*dnsendpointv1alpha1.Endpoint{{DNSName: "other.example.com", RecordType: "A", Targets: []string{"10.0.0.9"}}}
+	other := newTestVMI("other", nil)
+	other.Namespace = "team-b"
+	if err := setCrossNamespaceOwner(taken, other); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestReconciler(t, vmi, taken)
+
+	reconcileVMI(t, r, "vm")
+
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	if err := r.Get(context.Background(), key, ep); err != nil {
+		t.Fatal(err)
+	}
+	if owner, _, _ := endpointOwner(ep); owner != (types.NamespacedName{Namespace: "team-b", Name: "other"}) {
+		t.Errorf("expected the DNSEndpoint to keep its owner, got %v", owner)
+	}
+	if len(ep.Spec.Endpoints) != 1 || ep.Spec.Endpoints[0].DNSName != "other.example.com" {
+		t.Errorf("expected the DNSEndpoint to be left untouched, got %v", ep.Spec.Endpoints)
+	}
+	assertEvents(t, recordedEvents(r), "Warning "+eventReasonEndpointOwnedByOther)
+}
+
+func TestReconcile_TargetNamespace(t *testing.T) {
+	r := newTestReconciler(t, newCrossNamespaceVMI("dns-management"))
+
+	reconcileVMI(t, r, "vm")
+
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "dns-management", Name: "default-vm-37b132b9"}, ep); err != nil {
+		t.Fatalf("expected DNSEndpoint in the target namespace: %v", err)
+	}
+	if len(ep.OwnerReferences) != 0 {
+		t.Errorf("expected no owner references across namespaces, got %v", ep.OwnerReferences)
+	}
+	owner, uid, ok := endpointOwner(ep)
+	if !ok || owner != (types.NamespacedName{Namespace: "default", Name: "vm"}) || uid != "vm-uid" {
+		t.Errorf("endpointOwner() = %v, %q, %v", owner, uid, ok)
+	}
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected no DNSEndpoint in the VMI's namespace, got err=%v", err)
+	}
+	assertEvents(t, recordedEvents(r), "Normal "+eventReasonEndpointCreated)
+}
+
+func TestReconcile_TargetNamespaceDeletedByFinalizer(t *testing.T) {
+	r := newTestReconciler(t, newCrossNamespaceVMI("dns-management"))
+	reconcileVMI(t, r, "vm")
+
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, vmi); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "dns-management", Name: "default-vm-37b132b9"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the cross-namespace DNSEndpoint to be deleted, got err=%v", err)
+	}
+	err = r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, vmi)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected VMI to be gone once the finalizer was released, got err=%v", err)
+	}
+}
+
+func TestReconcile_TargetNamespaceChangeDeletesOldEndpoint(t *testing.T) {
+	r := newTestReconciler(t, newCrossNamespaceVMI(""))
+	reconcileVMI(t, r, "vm")
+	getEndpoint(t, r, "vm")
+
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, vmi); err != nil {
+		t.Fatal(err)
+	}
+	vmi.Annotations[annotationTargetNamespace] = "dns-management"
+	if err := r.Update(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the old DNSEndpoint to be deleted, got err=%v", err)
+	}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "dns-management", Name: "default-vm-37b132b9"}, &dnsendpointv1alpha1.DNSEndpoint{}); err != nil {
+		t.Errorf("expected DNSEndpoint in the target namespace: %v", err)
+	}
+	assertEvents(t, recordedEvents(r),
+		"Normal "+eventReasonEndpointCreated,
+		"Normal "+eventReasonEndpointCreated,
+		"Normal "+eventReasonEndpointDeleted)
+}
+
+func TestCrossNamespaceOwnerRequests(t *testing.T) {
+	ep := &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "dns-management", Name: "default-vm-37b132b9"}}
+	if got := crossNamespaceOwnerRequests(context.Background(), ep); len(got) != 0 {
+		t.Errorf("expected no requests for an unowned DNSEndpoint, got %v", got)
+	}
+
+	setCrossNamespaceOwner(ep, newCrossNamespaceVMI("dns-management"))
+	got := crossNamespaceOwnerRequests(context.Background(), ep)
+	if len(got) != 1 || got[0].NamespacedName != (types.NamespacedName{Namespace: "default", Name: "vm"}) {
+		t.Errorf("crossNamespaceOwnerRequests() = %v, want default/vm", got)
+	}
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans emitted by this package.
+const tracerName = "github.com/michaeltrip/external-dns-kubevirt/internal/controller"
+
+// tracer returns the tracer for reconcile spans. A nil TracerProvider uses the
+// global provider, which is a no-op unless one has been registered.
+func (r *VirtualMachineInstanceReconciler) tracer() trace.Tracer {
+	if r.TracerProvider == nil {
+		return otel.Tracer(tracerName)
+	}
+	return r.TracerProvider.Tracer(tracerName)
+}
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
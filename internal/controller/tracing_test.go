@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+func TestReconcile_Spans(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	exporter := tracetest.NewInMemoryExporter()
+	r.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	reconcileVMI(t, r, "vm")
+
+	spans := exporter.GetSpans()
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name)
+	}
+	// Spans are exported as they end, so children come before their parent.
+	if want := []string{"extractBestIPs", "buildEndpoints", "CreateOrUpdate", "Reconcile"}; !slices.Equal(names, want) {
+		t.Fatalf("spans = %v, want %v", names, want)
+	}
+
+	root := spans[len(spans)-1]
+	for _, s := range spans[:len(spans)-1] {
+		if s.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("span %s is not a child of Reconcile", s.Name)
+		}
+	}
+	wantAttrs := []attribute.KeyValue{
+		attribute.String("vmi.namespace", "default"),
+		attribute.String("vmi.name", "vm"),
+		attribute.String("vmi.phase", string(kubevirtv1.Running)),
+	}
+	for _, want := range wantAttrs {
+		if !slices.Contains(root.Attributes, want) {
+			t.Errorf("Reconcile span attributes %v missing %v", root.Attributes, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"fmt"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// DefaultMinTTL is the default lower bound of a VMI's TTL.
+	DefaultMinTTL dnsendpointv1alpha1.TTL = 1
+	// DefaultMaxTTL is the default upper bound of a VMI's TTL, one day. Some
+	// DNS providers reject or misbehave with longer TTLs.
+	DefaultMaxTTL dnsendpointv1alpha1.TTL = 86400
+)
+
+// ValidateTTLBounds checks that min and max form a non-empty range of
+// positive TTLs.
+func ValidateTTLBounds(min, max dnsendpointv1alpha1.TTL) error {
+	if min < 1 {
+		return fmt.Errorf("minimum TTL %d must be at least 1", min)
+	}
+	if max < min {
+		return fmt.Errorf("maximum TTL %d is below the minimum TTL %d", max, min)
+	}
+	return nil
+}
+
+// clampTTL limits ttl to [MinTTL, MaxTTL] and reports whether it changed.
+// A zero bound is not enforced.
+func (r *VirtualMachineInstanceReconciler) clampTTL(ttl dnsendpointv1alpha1.TTL) (dnsendpointv1alpha1.TTL, bool) {
+	return clampTTLTo(ttl, r.MinTTL, r.MaxTTL)
+}
+
+// clampTTL limits ttl to [MinTTL, MaxTTL] and reports whether it changed.
+// A zero bound is not enforced.
+func (r *VirtualMachineInstanceReplicaSetReconciler) clampTTL(ttl dnsendpointv1alpha1.TTL) (dnsendpointv1alpha1.TTL, bool) {
+	return clampTTLTo(ttl, r.MinTTL, r.MaxTTL)
+}
+
+// clampTTLTo limits ttl to [min, max] and reports whether it changed. A zero
+// bound is not enforced.
+func clampTTLTo(ttl, min, max dnsendpointv1alpha1.TTL) (dnsendpointv1alpha1.TTL, bool) {
+	switch {
+	case min > 0 && ttl < min:
+		return min, true
+	case max > 0 && ttl > max:
+		return max, true
+	}
+	return ttl, false
+}
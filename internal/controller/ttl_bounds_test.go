@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestReconcile_ClampsTTLToBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  string
+		want dnsendpointv1alpha1.TTL
+	}{
+		{"below minimum", "10", 60},
+		{"above maximum", "86400", 3600},
+		{"within range", "600", 600},
+		{"default within range", "", defaultTTL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{annotationHostname: "vm.example.com"}
+			if tt.ttl != "" {
+				annotations[annotationTTL] = tt.ttl
+			}
+			vmi := newTestVMI("vm", annotations,
+				kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+			r := newTestReconciler(t, vmi)
+			r.MinTTL, r.MaxTTL = 60, 3600
+			reconcileVMI(t, r, "vm")
+
+			ep := getEndpoint(t, r, "vm")
+			if got := ep.Spec.Endpoints[0].RecordTTL; got != tt.want {
+				t.Errorf("RecordTTL = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcile_CapsTTLAtDefaultMaximum(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com", annotationTTL: "999999999"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.MinTTL, r.MaxTTL = DefaultMinTTL, DefaultMaxTTL
+
+	var logs []string
+	logger := funcr.New(func(prefix, args string) { logs = append(logs, args) }, funcr.Options{})
+	ctx := ctrl.LoggerInto(context.Background(), logger)
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "vm"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := getEndpoint(t, r, "vm").Spec.Endpoints[0].RecordTTL; got != DefaultMaxTTL {
+		t.Errorf("RecordTTL = %d, want %d", got, DefaultMaxTTL)
+	}
+	var clampLogs []string
+	for _, line := range logs {
+		if strings.Contains(line, `"msg"="clamping TTL to the allowed range"`) {
+			clampLogs = append(clampLogs, line)
+		}
+	}
+	if len(clampLogs) != 1 {
+		t.Fatalf("expected one clamping log line, got %v", logs)
+	}
+	for _, want := range []string{`"requested"=999999999`, `"max"=86400`, `"vmi"={"name"="vm" "namespace"="default"}`} {
+		if !strings.Contains(clampLogs[0], want) {
+			t.Errorf("clamping log %s does not contain %s", clampLogs[0], want)
+		}
+	}
+}
+
+func TestReconcile_ClampsPerHostnameTTL(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{
+		annotationHostname:                "vm.example.com",
+		annotationTTL + "-vm.example.com": "5",
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.MinTTL = 60
+	reconcileVMI(t, r, "vm")
+
+	if got := getEndpoint(t, r, "vm").Spec.Endpoints[0].RecordTTL; got != 60 {
+		t.Errorf("RecordTTL = %d, want 60", got)
+	}
+}
+
+func TestValidateTTLBounds(t *testing.T) {
+	tests := []struct {
+		min, max dnsendpointv1alpha1.TTL
+		wantErr  bool
+	}{
+		{DefaultMinTTL, DefaultMaxTTL, false},
+		{60, 60, false},
+		{0, 3600, true},
+		{3600, 60, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateTTLBounds(tt.min, tt.max); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTTLBounds(%d, %d) error = %v, wantErr %v", tt.min, tt.max, err, tt.wantErr)
+		}
+	}
+}
@@ -1,34 +1,69 @@
 package controller
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
 	"net"
 	"reflect"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+
+	"github.com/michaeltrip/external-dns-kubevirt/internal/feature"
 )
 
 const (
+	// defaultAnnotationPrefix is the prefix shared by all annotations the controller reads.
+	// It can be replaced at runtime through ControllerSettings.AnnotationPrefix.
+	defaultAnnotationPrefix = "external-dns.alpha.kubernetes.io/"
 	// annotationHostname is the External-DNS annotation for hostnames (comma-separated).
-	annotationHostname = "external-dns.alpha.kubernetes.io/hostname"
+	annotationHostname = defaultAnnotationPrefix + "hostname"
 	// annotationTTL is the External-DNS annotation for record TTL in seconds.
-	annotationTTL = "external-dns.alpha.kubernetes.io/ttl"
+	annotationTTL = defaultAnnotationPrefix + "ttl"
+	// annotationPropagateAnnotations lists VMI annotation keys to copy onto the DNSEndpoint.
+	annotationPropagateAnnotations = defaultAnnotationPrefix + "propagate-annotations"
+	// annotationForceReconcile, when set to any non-empty value, forces a reconcile
+	// of the VMI. The controller removes it once the reconcile succeeds.
+	annotationForceReconcile = defaultAnnotationPrefix + "force-reconcile"
+	// annotationPaused, when "true", freezes the VMI's DNSEndpoint: reconciles
+	// leave it untouched until the annotation is removed. Deletion still cleans up.
+	annotationPaused = defaultAnnotationPrefix + "paused"
 	// defaultTTL is used when the TTL annotation is absent or invalid.
 	defaultTTL = dnsendpointv1alpha1.TTL(300)
 	// multusInfoSource is the infoSource value that indicates multus-status IPs.
@@ -36,167 +71,1108 @@ const (
 	// guestAgentInfoSource is the infoSource value set by the QEMU guest agent.
 	// It provides a richer IP list (iface.IPs) including IPv6 global unicast addresses.
 	guestAgentInfoSource = "guest-agent"
+	// sriovInfoSource is the infoSource value some KubeVirt versions set on SR-IOV interfaces.
+	sriovInfoSource = "sriov"
+	// deviceInfoSource is the infoSource value set on SR-IOV interfaces described
+	// by the network device-info annotation.
+	deviceInfoSource = "device-info"
+	// DefaultFinalizerName is the finalizer added to annotated VMIs so the
+	// DNSEndpoint is removed explicitly on deletion, even where owner-reference
+	// GC cannot apply. FinalizerName replaces it.
+	DefaultFinalizerName = "external-dns.alpha.kubernetes.io/cleanup"
+)
+
+// Event reasons emitted on the VMI for DNSEndpoint lifecycle transitions.
+const (
+	eventReasonEndpointCreated      = "DNSEndpointCreated"
+	eventReasonEndpointUpdated      = "DNSEndpointUpdated"
+	eventReasonEndpointDeleted      = "DNSEndpointDeleted"
+	eventReasonIPsNotYetAvailable   = "IPsNotYetAvailable"
+	eventReasonHostnameNotLowercase = "HostnameNotLowercase"
+	eventReasonVMINotRunning        = "VMINotRunning"
+	eventReasonZeroTTLIgnored       = "ZeroTTLIgnored"
 )
 
 // AddDNSEndpointToScheme registers the DNSEndpoint CRD types with the given scheme.
 func AddDNSEndpointToScheme(s *runtime.Scheme) error {
-	s.AddKnownTypes(
-		schema.GroupVersion{Group: "externaldns.k8s.io", Version: "v1alpha1"},
+	return AddDNSEndpointToSchemeVersion(s, "v1alpha1")
+}
+
+// AddDNSEndpointToSchemeVersion registers the DNSEndpoint CRD types under the
+// given externaldns.k8s.io version. A scheme must hold only one version: the
+// Go types are shared, so clients could not tell which one to send.
+func AddDNSEndpointToSchemeVersion(s *runtime.Scheme, version string) error {
+	gv := schema.GroupVersion{Group: "externaldns.k8s.io", Version: version}
+	s.AddKnownTypes(gv,
 		&dnsendpointv1alpha1.DNSEndpoint{},
 		&dnsendpointv1alpha1.DNSEndpointList{},
 	)
-	metav1.AddToGroupVersion(s, schema.GroupVersion{Group: "externaldns.k8s.io", Version: "v1alpha1"})
+	metav1.AddToGroupVersion(s, gv)
 	return nil
 }
 
 // VirtualMachineInstanceReconciler reconciles VirtualMachineInstance objects.
 type VirtualMachineInstanceReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// RateLimiter controls how quickly failed requests are retried.
+	// Nil uses the controller-runtime default.
+	RateLimiter workqueue.TypedRateLimiter[reconcile.Request]
+	// MaxConcurrentReconciles is the number of VMIs reconciled in parallel. Defaults to 1.
+	MaxConcurrentReconciles int
+	// Config supplies runtime-tunable settings. Nil uses DefaultSettings.
+	Config *ControllerConfig
+	// PublicIPsOnly drops RFC 1918, RFC 4193 and loopback addresses from every VMI.
+	PublicIPsOnly bool
+	// IPv4Only suppresses AAAA records regardless of the configured IP family.
+	IPv4Only bool
+	// IPv6Only suppresses A records regardless of the configured IP family.
+	IPv6Only bool
+	// IPSourcePriority lists infoSource names in the order they are tried.
+	// Nil uses DefaultIPSourcePriority.
+	IPSourcePriority []string
+	// ZoneAllowlist, when non-empty, limits hostnames to these zones.
+	ZoneAllowlist []string
+	// ZoneDenylist drops hostnames in these zones.
+	ZoneDenylist []string
+	// ReconcileTimeout bounds every API call made by a single reconcile. Zero disables the timeout.
+	ReconcileTimeout time.Duration
+	// InFlight, when set, tracks running reconciles so shutdown can wait for them.
+	InFlight *InFlightTracker
+	// StartAfter, when set, holds back every reconcile until it is closed, so
+	// startup work such as the orphan cleanup finishes before any DNSEndpoint
+	// is written.
+	StartAfter <-chan struct{}
+	// DebounceWindow delays a reconcile that starts less than this long after
+	// the previous one for the same VMI finished, so bursts of edits collapse
+	// into a single DNSEndpoint write. Zero disables debouncing.
+	DebounceWindow time.Duration
+	// CoalesceWindow makes a reconcile that starts less than this long after
+	// the previous one for the same VMI completed sleep for the rest of the
+	// window first. Watch events arriving meanwhile are merged by the work
+	// queue into a single follow-up reconcile. Zero disables coalescing.
+	CoalesceWindow time.Duration
+	// ConflictRetryBase is the requeue delay, give or take 20%, after a
+	// DNSEndpoint write fails with a resource-version conflict. Zero uses
+	// DefaultConflictRetryBase.
+	ConflictRetryBase time.Duration
+	// FinalizerName is the cleanup finalizer added to annotated VMIs (see
+	// ValidateFinalizerName). Empty uses DefaultFinalizerName. Give each
+	// controller deployed side by side its own name.
+	FinalizerName string
+	// PreviousFinalizerNames lists cleanup finalizers this controller used
+	// before FinalizerName was changed. VMIs still carrying them, or their DNS
+	// propagation finalizers, are cleaned up and released when deleted, and
+	// moved to the current finalizers otherwise.
+	PreviousFinalizerNames []string
+	// DNSPropagationDelay, when positive, holds back the deletion of a VMI for
+	// this long after its DNSEndpoint is removed, through the propagation
+	// finalizer paired with FinalizerName, so clients stop resolving the name
+	// before its address goes away.
+	DNSPropagationDelay time.Duration
+	// MinTTL and MaxTTL bound the TTL of every record, whether it comes from
+	// an annotation or the default; values outside are clamped with a
+	// warning. Zero leaves that side unbounded.
+	MinTTL dnsendpointv1alpha1.TTL
+	MaxTTL dnsendpointv1alpha1.TTL
+	// Backoff, when BaseDelay is set, requeues failed reconciles with a per-VMI
+	// exponential backoff instead of returning the error to the RateLimiter.
+	Backoff BackoffConfig
+	// NamespaceConfig, when set, merges a config ConfigMap in the VMI's
+	// namespace over Config.
+	NamespaceConfig *NamespaceConfigCache
+	// NoIPRetryInterval is the first requeue delay for an annotated VMI that
+	// reports no IPs yet. It doubles on every further attempt up to
+	// NoIPRetryMaxInterval. Zero disables the requeue and waits for the next watch event.
+	NoIPRetryInterval time.Duration
+	// NoIPRetryMaxInterval caps the no-IP requeue delay. Zero leaves it uncapped.
+	NoIPRetryMaxInterval time.Duration
+	// PhaseRetryInterval is the requeue delay for an annotated VMI that is not
+	// Running, or not Ready, yet. Zero disables the requeue and waits for the next watch event.
+	PhaseRetryInterval time.Duration
+	// PhaseWarningThreshold is the number of consecutive not-Running reconciles
+	// after which a Warning event is emitted on the VMI. Zero disables the warning.
+	PhaseWarningThreshold int
+	// TracerProvider supplies the tracer for reconcile spans. Nil uses the
+	// global provider, which is a no-op unless one has been registered.
+	TracerProvider trace.TracerProvider
+	// DisablePerVMIMetrics suppresses the per-VMI reconcile counter, whose
+	// cardinality grows with the number of VMIs.
+	DisablePerVMIMetrics bool
+	// Audit, when set, logs every DNS record the controller creates, updates or deletes.
+	Audit *AuditLogger
+	// DryRun reports that Client and EndpointClient come from NewDryRunClient.
+	// Writes they only log are left out of Events, the audit log and metrics.
+	DryRun bool
+	// ExpandShortHostnames expands single-label hostnames such as "myvm" to
+	// myvm.<namespace>.svc.<ClusterDomain>.
+	ExpandShortHostnames bool
+	// ClusterDomain is the cluster's DNS domain. Empty uses DefaultClusterDomain.
+	ClusterDomain string
+	// EndpointClient, when set, is used for every DNSEndpoint read and write
+	// instead of Client, so DNSEndpoints can be published to a remote cluster
+	// where External-DNS runs. VMIs are always read through Client.
+	EndpointClient client.Client
+	// EndpointCache is the cache EndpointClient reads from. When set,
+	// SetupWithManager watches DNSEndpoints there instead of in the manager's cluster.
+	EndpointCache cache.Cache
+	// SplitHorizon publishes the internal-hostname and external-hostname
+	// annotations as separate <vmi-name>-internal and <vmi-name>-external
+	// DNSEndpoints, each with its own addresses.
+	SplitHorizon bool
+	// EndpointNameFormat, when set, renders the name of each VMI's DNSEndpoint
+	// (see ParseEndpointNameFormat). Nil names it after the VMI.
+	EndpointNameFormat *template.Template
+	// AllowedTargetNamespaces, when non-empty, limits the namespaces the
+	// target-namespace annotation may move a DNSEndpoint into. Empty allows any.
+	AllowedTargetNamespaces []string
+	// RejectWildcards skips wildcard hostnames such as *.example.com, for DNS
+	// providers that do not support wildcard records.
+	RejectWildcards bool
+	// TemplateBased reconciles a VMI whenever its labels change, so hostname
+	// templates that reference {{ .Labels }} stay current. Off by default, as
+	// label changes are otherwise irrelevant and would only add reconciles.
+	TemplateBased bool
+	// Features gates optional behaviour. Nil enables the feature defaults.
+	Features *feature.FeatureGate
+	// Version is the controller version, stamped on every DNSEndpoint it writes
+	// in the controller-version label. Empty leaves the label unset.
+	Version string
+
+	// noIPAttempts counts consecutive no-IP reconciles per VMI (types.NamespacedName -> int).
+	noIPAttempts sync.Map
+	// lastReconciled holds when each VMI was last reconciled (types.NamespacedName -> time.Time).
+	lastReconciled sync.Map
+	// lastCompleted holds when each VMI's last reconcile completed, for
+	// CoalesceWindow (types.NamespacedName -> time.Time).
+	lastCompleted sync.Map
+	// lagObserved holds the resourceVersion of each VMI whose watch event lag
+	// was last observed (types.NamespacedName -> string).
+	lagObserved sync.Map
+	// errorAttempts counts consecutive failed reconciles per VMI (types.NamespacedName -> int).
+	errorAttempts sync.Map
+	// phaseWaits counts consecutive reconciles that found the VMI not yet
+	// Running (types.NamespacedName -> int).
+	phaseWaits sync.Map
+	// propagationStarted holds when the DNS propagation wait of each deleted
+	// VMI began (types.NamespacedName -> time.Time).
+	propagationStarted sync.Map
+	// now returns the current time; nil uses time.Now. Tests replace it.
+	now func() time.Time
+	// caseWarned remembers the hostname annotation each VMI was last warned
+	// about for uppercase letters (types.NamespacedName -> string), so the
+	// warning is emitted once per annotation value rather than on every reconcile.
+	caseWarned sync.Map
+}
+
+// clock returns the current time.
+func (r *VirtualMachineInstanceReconciler) clock() time.Time {
+	if r.now == nil {
+		return time.Now()
+	}
+	return r.now()
+}
+
+// hostnameTemplates reports whether hostname entries may be Go templates.
+func (r *VirtualMachineInstanceReconciler) hostnameTemplates() bool {
+	return r.Features.Enabled(feature.FeatureTemplateHostnames)
+}
+
+// clusterDomain returns the domain short hostnames are expanded under.
+func (r *VirtualMachineInstanceReconciler) clusterDomain() string {
+	if r.ClusterDomain == "" {
+		return DefaultClusterDomain
+	}
+	return r.ClusterDomain
+}
+
+// settings returns the settings to use for the current reconcile.
+func (r *VirtualMachineInstanceReconciler) settings() ControllerSettings {
+	if r.Config == nil {
+		return DefaultSettings()
+	}
+	return r.Config.Get()
+}
+
+// settingsFor returns the settings for a VMI in namespace: the global settings
+// with the namespace's config ConfigMap, if any, merged over them.
+func (r *VirtualMachineInstanceReconciler) settingsFor(ctx context.Context, namespace string) (ControllerSettings, error) {
+	if r.NamespaceConfig == nil {
+		return r.settings(), nil
+	}
+	return r.NamespaceConfig.Settings(ctx, r.Client, namespace, r.settings())
 }
 
-// +kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachineinstances,verbs=get;list;watch
+// cachedSettingsFor is like settingsFor but never calls the API server, for use in predicates.
+func (r *VirtualMachineInstanceReconciler) cachedSettingsFor(namespace string) ControllerSettings {
+	if r.NamespaceConfig == nil {
+		return r.settings()
+	}
+	return r.NamespaceConfig.Cached(namespace, r.settings())
+}
+
+// These markers generate a ClusterRole. When the controller runs with
+// --namespace, the VMI, DNSEndpoint and event rules can instead be granted by a
+// Role (plus RoleBinding) in each watched namespace; the DNSEndpoint rule is also
+// needed in every namespace named by a target-namespace annotation.
+// +kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachineinstances,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachineinstancemigrations,verbs=get;list;watch
 
 // Reconcile reads the state of the VirtualMachineInstance and creates/updates/deletes a DNSEndpoint accordingly.
-func (r *VirtualMachineInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *VirtualMachineInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	logger := log.FromContext(ctx)
 
+	if r.StartAfter != nil {
+		select {
+		case <-r.StartAfter:
+		case <-ctx.Done():
+			return ctrl.Result{}, ctx.Err()
+		}
+	}
+	if wait := r.coalesceRemaining(req.NamespacedName); wait > 0 {
+		logger.V(1).Info("coalescing reconcile", "vmi", req.NamespacedName, "wait", wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctrl.Result{}, ctx.Err()
+		}
+	}
+
+	// Registered first so it runs last, after the error has been recorded in metrics.
+	defer func() {
+		result, err = r.backoffOnError(ctx, req.NamespacedName, result, err)
+	}()
+
+	ctx, span := r.tracer().Start(ctx, "Reconcile", trace.WithAttributes(
+		attribute.String("vmi.namespace", req.Namespace),
+		attribute.String("vmi.name", req.Name),
+	))
+	// Runs before backoffOnError, so the span records the original error.
+	defer func() { endSpan(span, err) }()
+
+	if r.InFlight != nil {
+		defer r.InFlight.Begin(req.NamespacedName)()
+	}
+
+	if r.ReconcileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.ReconcileTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	outcome := resultSuccess
+	vmiGone := false
+	defer func() {
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %s: %w", errReconcileTimeout, r.ReconcileTimeout, err)
+		}
+		if err != nil {
+			outcome = resultError
+			countReconcileError(err)
+		}
+		reconcileDuration.WithLabelValues(req.Namespace, outcome).Observe(time.Since(start).Seconds())
+		if !r.DisablePerVMIMetrics {
+			if vmiGone {
+				vmiReconciles.DeletePartialMatch(prometheus.Labels{"namespace": req.Namespace, "name": req.Name})
+			} else {
+				vmiReconciles.WithLabelValues(req.Namespace, req.Name, outcome).Inc()
+			}
+		}
+	}()
+
+	if remaining := r.debounceRemaining(req.NamespacedName); remaining > 0 {
+		logger.V(1).Info("debouncing reconcile", "vmi", req.NamespacedName, "requeueAfter", remaining)
+		outcome = resultSkipped
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
 	vmi := &kubevirtv1.VirtualMachineInstance{}
 	if err := r.Get(ctx, req.NamespacedName, vmi); err != nil {
 		if apierrors.IsNotFound(err) {
-			// VMI was deleted; DNSEndpoint is cleaned up via OwnerReference GC.
+			// VMI is gone; the finalizer or OwnerReference GC has already cleaned up the DNSEndpoint.
+			outcome = resultSkipped
+			vmiGone = true
+			r.noIPAttempts.Delete(req.NamespacedName)
+			r.phaseWaits.Delete(req.NamespacedName)
+			r.caseWarned.Delete(req.NamespacedName)
+			r.lastReconciled.Delete(req.NamespacedName)
+			r.lastCompleted.Delete(req.NamespacedName)
+			r.lagObserved.Delete(req.NamespacedName)
+			r.propagationStarted.Delete(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	span.SetAttributes(attribute.String("vmi.phase", string(vmi.Status.Phase)))
+	if seen, _ := r.lagObserved.Swap(req.NamespacedName, vmi.ResourceVersion); seen != vmi.ResourceVersion {
+		// Only the first reconcile of a version measures how late its event arrived.
+		observeWatchEventLag(vmi, r.clock())
+	}
+	if r.CoalesceWindow > 0 {
+		defer func() { r.lastCompleted.Store(req.NamespacedName, time.Now()) }()
+	}
+	if r.DebounceWindow > 0 {
+		// Only successful reconciles open a debounce window; failures are
+		// retried on the error backoff schedule instead.
+		defer func() {
+			if err == nil {
+				r.lastReconciled.Store(req.NamespacedName, time.Now())
+			}
+		}()
+	}
+
+	// VMI is being deleted — remove the DNSEndpoint before releasing the finalizers.
+	if !vmi.DeletionTimestamp.IsZero() {
+		if !r.hasOwnFinalizer(vmi) {
 			return ctrl.Result{}, nil
 		}
+		logger.Info("VMI is being deleted, cleaning up DNSEndpoint", "vmi", req.NamespacedName)
+		if err := r.deleteEndpointIfExists(ctx, vmi); err != nil {
+			return ctrl.Result{}, err
+		}
+		if remaining := r.propagationRemaining(req.NamespacedName, vmi); remaining > 0 {
+			logger.Info("waiting for DNS propagation before releasing the VMI", "vmi", req.NamespacedName, "requeueAfter", remaining)
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+		return ctrl.Result{}, r.removeFinalizer(ctx, vmi)
+	}
+
+	settings, err := r.settingsFor(ctx, vmi.Namespace)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if vmi.Annotations[settings.annotationKey(annotationPaused)] == "true" {
+		logger.Info("VMI is paused, leaving DNSEndpoint untouched", "vmi", req.NamespacedName)
+		outcome = resultSkipped
+		return ctrl.Result{}, nil
+	}
+
+	defer func() {
+		if err == nil {
+			err = r.clearForceReconcile(ctx, vmi, settings)
+		}
+	}()
+
 	// If the hostname annotation is absent, clean up any existing DNSEndpoint.
-	hostname, hasAnnotation := vmi.Annotations[annotationHostname]
+	// Split-horizon and per-interface hostnames alone still publish their
+	// secondary DNSEndpoints.
+	hostname, hasAnnotation := vmi.Annotations[settings.annotationKey(annotationHostname)]
 	hostname = strings.TrimSpace(hostname)
-	if !hasAnnotation || hostname == "" {
+	secondary := (r.SplitHorizon && hasHorizonHostnames(vmi, settings)) || hasInterfaceDNSMap(vmi, settings)
+	if (!hasAnnotation || hostname == "") && !secondary {
 		logger.Info("hostname annotation absent, ensuring DNSEndpoint is deleted", "vmi", req.NamespacedName)
-		return ctrl.Result{}, r.deleteEndpointIfExists(ctx, vmi)
+		outcome = resultSkipped
+		r.noIPAttempts.Delete(req.NamespacedName)
+		if err := r.deleteEndpointIfExists(ctx, vmi); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.removeFinalizer(ctx, vmi)
+	}
+
+	// IPs reported before the VMI is Running are not reliable yet. Leave any
+	// existing DNSEndpoint alone. The phase change normally re-triggers
+	// reconciliation, but a missed transition would stall the VMI, so VMIs that
+	// can still start are also polled.
+	if vmi.Status.Phase != kubevirtv1.Running {
+		logger.V(1).Info("VMI is not running yet, skipping", "vmi", req.NamespacedName, "phase", vmi.Status.Phase)
+		outcome = resultSkipped
+		if vmi.IsFinal() {
+			r.phaseWaits.Delete(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{RequeueAfter: r.phaseWaitRequeueAfter(vmi)}, nil
+	}
+	r.phaseWaits.Delete(req.NamespacedName)
+
+	// A Running VMI may still be bringing up its network (guest agent not
+	// started, Multus status not written yet), and addresses it reports in the
+	// meantime can be ephemeral. Wait until its Ready condition is true.
+	if !isVMIReady(vmi) {
+		logger.V(1).Info("VMI is not ready yet, skipping", "vmi", req.NamespacedName)
+		outcome = resultSkipped
+		return ctrl.Result{RequeueAfter: r.PhaseRetryInterval}, nil
+	}
+
+	// Annotation is present — make sure the finalizers are in place before
+	// publishing anything, replacing those of PreviousFinalizerNames.
+	finalizersChanged := controllerutil.AddFinalizer(vmi, r.finalizerName())
+	if r.DNSPropagationDelay > 0 && controllerutil.AddFinalizer(vmi, r.propagationFinalizer()) {
+		finalizersChanged = true
+	}
+	for _, previous := range r.previousFinalizers() {
+		if controllerutil.RemoveFinalizer(vmi, previous) {
+			finalizersChanged = true
+		}
+	}
+	if finalizersChanged {
+		if err := r.Update(ctx, vmi); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	name, nameErr := r.endpointName(vmi)
+	if nameErr != nil {
+		logger.Info("endpoint name format failed to render, naming the DNSEndpoint after the VMI", "vmi", req.NamespacedName, "error", nameErr.Error())
+	}
+	key, keyErr := endpointKey(vmi, name, settings, r.AllowedTargetNamespaces)
+	if keyErr != nil {
+		logger.Info("ignoring target-namespace annotation, using the VMI's namespace", "vmi", req.NamespacedName, "error", keyErr.Error())
+		countReconcileError(keyErr)
 	}
 
 	// Annotation is present — collect the best available IPs.
-	// guest-agent IPs are preferred (richer data); multus-status is the fallback.
-	// If neither source yields IPs yet, do nothing: neither create nor delete.
-	ipv4Addrs, ipv6Addrs, ipSource := extractBestIPs(vmi)
+	// If no source yields IPs yet, do nothing: neither create nor delete.
+	ipv4Addrs, ipv6Addrs, ipSource := r.resolveIPs(ctx, vmi, settings)
+	ipv4Addrs, ipv6Addrs = settings.filterIPFamily(ipv4Addrs, ipv6Addrs)
+	if r.IPv4Only && len(ipv6Addrs) > 0 {
+		logger.V(1).Info("suppressing IPv6 addresses because --ipv4-only is set", "vmi", req.NamespacedName, "ipv6", ipv6Addrs)
+		ipv6Addrs = nil
+	}
+	if r.IPv6Only && len(ipv4Addrs) > 0 {
+		logger.V(1).Info("suppressing IPv4 addresses because --ipv6-only is set", "vmi", req.NamespacedName, "ipv4", ipv4Addrs)
+		ipv4Addrs = nil
+	}
 	if len(ipv4Addrs) == 0 && len(ipv6Addrs) == 0 {
 		logger.Info("hostname annotation present but no IPs available yet, skipping", "vmi", req.NamespacedName)
-		return ctrl.Result{}, nil
+		outcome = resultSkipped
+		countReconcileError(errIPsUnavailable)
+		r.Recorder.Event(vmi, corev1.EventTypeWarning, eventReasonIPsNotYetAvailable, "Hostname annotation present but no IP addresses are available yet")
+		cond := newCondition(vmi, conditionIPsResolved, metav1.ConditionFalse, reasonIPsNotAvailable, "no IP addresses reported by any supported infoSource yet")
+		// Guest agents can come up without triggering a watch event, so poll until IPs appear.
+		return ctrl.Result{RequeueAfter: r.noIPRequeueAfter(req.NamespacedName)}, r.patchEndpointConditions(ctx, key, cond)
 	}
+	r.noIPAttempts.Delete(req.NamespacedName)
 	logger.Info("resolved IPs", "vmi", req.NamespacedName, "source", ipSource, "ipv4", ipv4Addrs, "ipv6", ipv6Addrs)
 
-	ttl := parseTTL(vmi.Annotations[annotationTTL])
-	hostnames := parseHostnames(hostname)
-	endpoints := buildEndpoints(hostnames, ipv4Addrs, ipv6Addrs, ttl)
+	ttl, ttlErr := ttlFromAnnotation(vmi.Annotations[settings.annotationKey(annotationTTL)], settings.DefaultTTL)
+	switch {
+	case errors.Is(ttlErr, errZeroTTL):
+		logger.Info("ignoring zero TTL annotation, using default", "vmi", req.NamespacedName, "default", settings.DefaultTTL)
+		r.Recorder.Eventf(vmi, corev1.EventTypeWarning, eventReasonZeroTTLIgnored,
+			"TTL annotation is 0, which some DNS providers reject; using the default TTL of %d", settings.DefaultTTL)
+		countReconcileError(ttlErr)
+	case ttlErr != nil:
+		logger.Info("ignoring TTL annotation, using default", "vmi", req.NamespacedName, "error", ttlErr.Error(), "default", settings.DefaultTTL)
+		countReconcileError(ttlErr)
+	case vmi.Annotations[settings.annotationKey(annotationTTL)] == "":
+		logger.V(1).Info("no TTL annotation, using default", "vmi", req.NamespacedName, "default", settings.DefaultTTL)
+	}
+	if clamped, ok := r.clampTTL(ttl); ok {
+		logger.Info("clamping TTL to the allowed range", "vmi", req.NamespacedName, "requested", ttl, "clamped", clamped, "min", r.MinTTL, "max", r.MaxTTL)
+		ttl = clamped
+	}
+	if r.SplitHorizon {
+		if err := r.reconcileSplitHorizon(ctx, vmi, settings, ipv4Addrs, ipv6Addrs, ttl); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.reconcileInterfaceEndpoints(ctx, vmi, settings, ttl); err != nil {
+		return ctrl.Result{}, err
+	}
+	if hostname == "" {
+		// Only secondary hostnames are set; drop the main DNSEndpoint.
+		return ctrl.Result{}, r.deleteEndpointsExcept(ctx, vmi, client.ObjectKey{})
+	}
+	if hasUppercaseHostnames(hostname) {
+		if warned, _ := r.caseWarned.Load(req.NamespacedName); warned != hostname {
+			r.caseWarned.Store(req.NamespacedName, hostname)
+			r.Recorder.Eventf(vmi, corev1.EventTypeWarning, eventReasonHostnameNotLowercase,
+				"Hostname annotation %q contains uppercase letters; publishing lowercased names", hostname)
+		}
+	}
+	hostnames, hostnameErr := renderHostnames(parseHostnames(hostname), vmi, r.hostnameTemplates())
+	if hostnameErr != nil {
+		logger.Info("skipping hostnames whose template failed to render", "vmi", req.NamespacedName, "error", hostnameErr.Error())
+		countReconcileError(hostnameErr)
+	}
+	hostnames = r.hostnamePolicy().filter(ctx, vmi, "vmi", hostnames,
+		strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationHostnamePrefix)]),
+		strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationHostnameSuffix)]))
+	hostnameTTLs, hostnameTTLErr := parsePerHostnameTTL(vmi.Annotations, settings)
+	if hostnameTTLErr != nil {
+		logger.Info("ignoring invalid per-hostname TTL annotations", "vmi", req.NamespacedName, "error", hostnameTTLErr.Error())
+		countReconcileError(hostnameTTLErr)
+	}
+	for h, hostTTL := range hostnameTTLs {
+		if clamped, ok := r.clampTTL(hostTTL); ok {
+			logger.Info("clamping per-hostname TTL to the allowed range", "vmi", req.NamespacedName, "hostname", h, "requested", hostTTL, "clamped", clamped, "min", r.MinTTL, "max", r.MaxTTL)
+			hostnameTTLs[h] = clamped
+		}
+	}
+	typeTTLs, typeTTLErr := parsePerTypeTTL(vmi.Annotations, settings, ttl)
+	if typeTTLErr != nil {
+		logger.Info("ignoring invalid per-record-type TTL annotations", "vmi", req.NamespacedName, "error", typeTTLErr.Error())
+		countReconcileError(typeTTLErr)
+	}
+	for recordType, typeTTL := range typeTTLs {
+		if clamped, ok := r.clampTTL(typeTTL); ok {
+			logger.Info("clamping per-record-type TTL to the allowed range", "vmi", req.NamespacedName, "recordType", recordType, "requested", typeTTL, "clamped", clamped, "min", r.MinTTL, "max", r.MaxTTL)
+			typeTTLs[recordType] = clamped
+		}
+	}
+	weight, weightErr := parseWeight(vmi.Annotations[settings.annotationKey(annotationWeight)])
+	if weightErr != nil {
+		logger.Info("ignoring weight annotation", "vmi", req.NamespacedName, "error", weightErr.Error())
+		countReconcileError(weightErr)
+	}
+	setIdentifier, setIdentifierErr := parseSetIdentifier(vmi.Annotations, settings)
+	if setIdentifierErr != nil {
+		logger.Info("ignoring set-identifier annotation", "vmi", req.NamespacedName, "error", setIdentifierErr.Error())
+		countReconcileError(setIdentifierErr)
+	}
+	setIdentifier = setIdentifierFor(vmi, setIdentifier, weight)
+	vmiConflict, err := r.findVMIHostnameConflict(ctx, vmi, key.Namespace, hostnames, setIdentifier)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if vmiConflict != nil {
+		// The other VMI has the better claim; leave the DNSEndpoint as it is
+		// and do not requeue until one of them changes.
+		logger.Info("hostname also requested by another VMI, skipping", "vmi", req.NamespacedName,
+			"hostname", vmiConflict.Hostname, "other", vmiConflict.Owner)
+		outcome = resultSkipped
+		countReconcileError(errHostnameConflict)
+		r.reportVMIHostnameConflict(ctx, vmi, vmiConflict)
+		return ctrl.Result{}, nil
+	}
+	conflict, err := r.findHostnameConflict(ctx, vmi, key.Namespace, hostnames, setIdentifier)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if conflict != nil {
+		// Publishing anyway would make the two VMIs flap over the same record.
+		logger.Info("hostname already claimed by another VMI, skipping", "vmi", req.NamespacedName,
+			"hostname", conflict.Hostname, "endpoint", conflict.Endpoint, "owner", conflict.Owner)
+		outcome = resultSkipped
+		countReconcileError(errHostnameConflict)
+		return ctrl.Result{}, r.reportHostnameConflict(ctx, vmi, key, conflict)
+	}
+	_, buildSpan := r.tracer().Start(ctx, "buildEndpoints")
+	endpoints := buildEndpoints(hostnames, ipv4Addrs, ipv6Addrs, ttl, hostnameTTLs, typeTTLs, setIdentifier)
+	buildSpan.SetAttributes(attribute.Int("endpoints", len(endpoints)))
+	buildSpan.End()
+	recordType, recordTypeErr := parseRecordType(vmi.Annotations[settings.annotationKey(annotationRecordType)])
+	if recordTypeErr == nil {
+		endpoints, recordTypeErr = applyRecordType(endpoints, hostnames, recordType)
+	}
+	if recordTypeErr != nil {
+		logger.Info("ignoring record-type annotation", "vmi", req.NamespacedName, "error", recordTypeErr.Error())
+		countReconcileError(recordTypeErr)
+	}
+	applyProviderSpecific(endpoints, providerSpecificFor(vmi, settings, weight))
 
 	desired := &dnsendpointv1alpha1.DNSEndpoint{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      vmi.Name,
-			Namespace: vmi.Namespace,
+			Name:      key.Name,
+			Namespace: key.Namespace,
 		},
 	}
 
-	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, desired, func() error {
+	var changed bool
+	var previous []*dnsendpointv1alpha1.Endpoint
+	writeCtx, writeSpan := r.tracer().Start(ctx, "CreateOrUpdate", trace.WithAttributes(
+		attribute.String("dnsendpoint.namespace", desired.Namespace),
+		attribute.String("dnsendpoint.name", desired.Name),
+	))
+	op, err := controllerutil.CreateOrUpdate(writeCtx, r.endpoints(), desired, func() error {
+		existing := desired.DeepCopy()
+		previous = existing.Spec.Endpoints
+		// Stamp only DNSEndpoints that change otherwise, so an idle VMI's
+		// reconcile stays a no-op instead of an Update per second.
+		defer func() {
+			if changed = changedBesidesReconcileTime(existing, desired); changed {
+				setLastReconcileTime(desired, r.clock())
+			}
+		}()
+		if r.Version != "" {
+			metav1.SetMetaDataLabel(&desired.ObjectMeta, labelControllerVersion, r.Version)
+		}
 		desired.Spec = dnsendpointv1alpha1.DNSEndpointSpec{
 			Endpoints: endpoints,
 		}
+		copyLabels(desired, vmi.Labels)
+		setOwnerVMILabel(desired, vmi)
+		copyAnnotations(desired, vmi.Annotations, vmi.Annotations[settings.annotationKey(annotationPropagateAnnotations)], settings)
+		if err := setEndpointConditions(desired,
+			newCondition(vmi, conditionIPsResolved, metav1.ConditionTrue, reasonIPsAvailable, "IP addresses resolved from "+ipSource),
+			newCondition(vmi, conditionReady, metav1.ConditionTrue, reasonSynced, "DNSEndpoint is in sync with the VirtualMachineInstance"),
+		); err != nil {
+			return err
+		}
+		if key.Namespace != vmi.Namespace || r.remoteEndpoints() {
+			// Owner references cannot cross namespaces or clusters; the cleanup
+			// finalizer deletes this DNSEndpoint when the VMI goes away.
+			return setCrossNamespaceOwner(desired, vmi)
+		}
 		// Set VMI as the owner so the DNSEndpoint is garbage-collected when the VMI is deleted.
 		return controllerutil.SetControllerReference(vmi, desired, r.Scheme)
 	})
+	writeSpan.SetAttributes(attribute.String("operation", string(op)))
+	endSpan(writeSpan, err)
+	if apierrors.IsConflict(err) {
+		// The DNSEndpoint changed between the Get and the Update; retry shortly
+		// against the fresh copy instead of going through the error backoff.
+		retryAfter := r.conflictRetryAfter()
+		logger.Info("DNSEndpoint was modified concurrently, retrying", "vmi", req.NamespacedName, "requeueAfter", retryAfter)
+		outcome = resultSkipped
+		countReconcileError(err)
+		return ctrl.Result{RequeueAfter: retryAfter}, nil
+	}
+	if errors.Is(err, errEndpointOwnedByOther) {
+		// Leave the other VMI's DNSEndpoint, including its conditions, alone.
+		logger.Info("DNSEndpoint belongs to another VMI, skipping", "vmi", req.NamespacedName, "endpoint", key, "error", err.Error())
+		outcome = resultSkipped
+		countReconcileError(err)
+		r.Recorder.Eventf(vmi, corev1.EventTypeWarning, eventReasonEndpointOwnedByOther, "Not publishing: %v", err)
+		return ctrl.Result{}, nil
+	}
 	if err != nil {
+		cond := newCondition(vmi, conditionReady, metav1.ConditionFalse, reasonSyncFailed, err.Error())
+		if statusErr := r.patchEndpointConditions(ctx, key, cond); statusErr != nil {
+			logger.Error(statusErr, "failed to record Ready condition on DNSEndpoint", "vmi", req.NamespacedName)
+		}
 		return ctrl.Result{}, err
 	}
 
-	logger.Info("reconciled DNSEndpoint", "vmi", req.NamespacedName, "operation", op)
+	// Every reconcile refreshes the last-reconcile-time stamp; only report real changes.
+	r.recordEndpointWrite(ctx, vmi, desired, op, previous, op == controllerutil.OperationResultCreated || changed)
+	if op == controllerutil.OperationResultCreated {
+		// The target namespace may have changed; drop the DNSEndpoint published before.
+		if err := r.deleteEndpointsExcept(ctx, vmi, key); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	logger.Info("reconciled DNSEndpoint", "vmi", req.NamespacedName, "operation", op, "dryRun", r.DryRun)
 	return ctrl.Result{}, nil
 }
 
-// deleteEndpointIfExists deletes the DNSEndpoint with the same name/namespace as the VMI, if it exists.
-func (r *VirtualMachineInstanceReconciler) deleteEndpointIfExists(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance) error {
-	endpoint := &dnsendpointv1alpha1.DNSEndpoint{}
-	err := r.Get(ctx, client.ObjectKey{Name: vmi.Name, Namespace: vmi.Namespace}, endpoint)
-	if apierrors.IsNotFound(err) {
-		return nil
+// hostnamePolicy returns the hostname rules the reconciler publishes under.
+func (r *VirtualMachineInstanceReconciler) hostnamePolicy() hostnamePolicy {
+	return hostnamePolicy{
+		expandShort:     r.ExpandShortHostnames,
+		clusterDomain:   r.clusterDomain(),
+		zoneAllowlist:   r.ZoneAllowlist,
+		zoneDenylist:    r.ZoneDenylist,
+		rejectWildcards: r.RejectWildcards,
+		recorder:        r.Recorder,
+	}
+}
+
+// copyLabels merges labels into the object's labels, leaving labels already on
+// the object in place. controller-uid labels are skipped so the DNSEndpoint is
+// not mistaken for an object owned by the VMI's controller.
+func copyLabels(obj metav1.Object, labels map[string]string) {
+	merged := obj.GetLabels()
+	for k, v := range labels {
+		if k == "controller-uid" || strings.HasSuffix(k, "/controller-uid") {
+			continue
+		}
+		if merged == nil {
+			merged = map[string]string{}
+		}
+		merged[k] = v
 	}
+	obj.SetLabels(merged)
+}
+
+// copyAnnotations copies the VMI annotations named in the comma-separated keys
+// list onto obj. Keys missing from the VMI are ignored. Keys under the
+// controller's own prefixes are never copied, so they cannot overwrite the
+// annotations the controller manages on the DNSEndpoint.
+func copyAnnotations(obj metav1.Object, vmiAnnotations map[string]string, keys string, settings ControllerSettings) {
+	merged := obj.GetAnnotations()
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" || isManagedAnnotation(key, settings) {
+			continue
+		}
+		value, ok := vmiAnnotations[key]
+		if !ok {
+			continue
+		}
+		if merged == nil {
+			merged = map[string]string{}
+		}
+		merged[key] = value
+	}
+	obj.SetAnnotations(merged)
+}
+
+// isManagedAnnotation reports whether key belongs to the controller or to External-DNS.
+func isManagedAnnotation(key string, settings ControllerSettings) bool {
+	return strings.HasPrefix(key, defaultAnnotationPrefix) ||
+		strings.HasPrefix(key, settings.AnnotationPrefix) ||
+		strings.HasPrefix(key, managedAnnotationPrefix)
+}
+
+// resolveIPs returns the addresses to publish for the VMI and where they came
+// from. A static-ip annotation, then an ip-pool annotation with at least one
+// valid IP, take precedence over interface discovery; otherwise sources are tried in the configured priority order (guest-agent,
+// then multus-status, by default) and filtered by the IP filter annotations.
+func (r *VirtualMachineInstanceReconciler) resolveIPs(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings) (ipv4, ipv6 []string, source string) {
+	logger := log.FromContext(ctx)
+	key := client.ObjectKeyFromObject(vmi)
+
+	if raw := strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationStaticIP)]); raw != "" {
+		ipv4, ipv6, err := parseStaticIPs(raw)
+		if err != nil {
+			logger.Info("ignoring invalid entries in static-ip annotation", "vmi", key, "error", err.Error())
+			countReconcileError(err)
+		}
+		return ipv4, ipv6, staticIPSource
+	}
+
+	if raw := strings.TrimSpace(vmi.Annotations[settings.annotationKey(annotationIPPool)]); raw != "" {
+		ipv4, ipv6, err := parseStaticIPs(raw)
+		if err != nil {
+			logger.Info("ignoring invalid entries in ip-pool annotation", "vmi", key, "error", err.Error())
+			countReconcileError(err)
+		}
+		if len(ipv4) > 0 || len(ipv6) > 0 {
+			return ipv4, ipv6, ipPoolSource
+		}
+		logger.Info("ip-pool annotation has no valid IPs, falling back to interface addresses", "vmi", key)
+	}
+
+	filter, err := ipFilterFor(vmi.Annotations, settings)
 	if err != nil {
+		logger.Info("ignoring invalid entries in IP filter annotations", "vmi", key, "error", err.Error())
+		countReconcileError(err)
+	}
+	filter.publicOnly = r.PublicIPsOnly
+	_, span := r.tracer().Start(ctx, "extractBestIPs")
+	defer span.End()
+	ipv4, ipv6, source = extractBestIPs(vmi, filter, r.IPSourcePriority)
+	span.SetAttributes(attribute.String("ip.source", source), attribute.Int("ip.v4", len(ipv4)), attribute.Int("ip.v6", len(ipv6)))
+	return ipv4, ipv6, source
+}
+
+// deleteEndpointIfExists deletes every DNSEndpoint labeled as owned by the
+// VMI, whatever its name or namespace, then those predating the owner-vmi
+// label: the one named after the VMI, any it owns in another namespace, and
+// its secondary DNSEndpoints.
+func (r *VirtualMachineInstanceReconciler) deleteEndpointIfExists(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance) error {
+	if err := r.deleteLabeledEndpoints(ctx, vmi); err != nil {
+		return err
+	}
+	if err := r.deleteEndpointsExcept(ctx, vmi, client.ObjectKey{}); err != nil {
+		return err
+	}
+	if err := r.deleteHorizonEndpoints(ctx, vmi); err != nil {
 		return err
 	}
-	return r.Delete(ctx, endpoint)
+	return r.deleteInterfaceEndpointsExcept(ctx, vmi, nil)
 }
 
-// extractBestIPs returns IPv4 and IPv6 addresses for the VMI using the best
-// available infoSource. The guest-agent source is preferred because it exposes
-// the full iface.IPs list (including global IPv6 unicast). multus-status is
-// used as a fallback, reading only the single iface.IP field.
-//
-// The returned source string indicates which source was used ("guest-agent" or
-// "multus-status").
-func extractBestIPs(vmi *kubevirtv1.VirtualMachineInstance) (ipv4, ipv6 []string, source string) {
-	gaV4, gaV6 := extractGuestAgentIPs(vmi)
-	if len(gaV4) > 0 || len(gaV6) > 0 {
-		return gaV4, gaV6, guestAgentInfoSource
+// deleteLabeledEndpoints deletes all DNSEndpoints carrying the VMI's name in
+// labelOwnerVMI. Since the label holds only the name, endpoints owned by a
+// VMI of the same name in another namespace are left alone.
+func (r *VirtualMachineInstanceReconciler) deleteLabeledEndpoints(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance) error {
+	list := &dnsendpointv1alpha1.DNSEndpointList{}
+	if err := r.endpoints().List(ctx, list, client.MatchingLabels{labelOwnerVMI: vmi.Name}); err != nil {
+		return err
 	}
-	mV4, mV6 := extractMultusIPs(vmi)
-	if len(mV4) > 0 || len(mV6) > 0 {
-		return mV4, mV6, multusInfoSource
+	var owned []*dnsendpointv1alpha1.DNSEndpoint
+	for i := range list.Items {
+		if owner, _, ok := endpointOwner(&list.Items[i]); ok && owner == client.ObjectKeyFromObject(vmi) {
+			owned = append(owned, &list.Items[i])
+		}
 	}
-	return nil, nil, ""
+	return r.deleteEndpoints(ctx, vmi, owned...)
 }
 
-// extractGuestAgentIPs returns IPv4 and IPv6 addresses from interfaces whose
-// infoSource contains "guest-agent", using the full iface.IPs list.
-// Link-local IPv6 addresses (fe80::/10) are skipped.
-func extractGuestAgentIPs(vmi *kubevirtv1.VirtualMachineInstance) (ipv4, ipv6 []string) {
-	for _, iface := range vmi.Status.Interfaces {
-		if !containsInfoSource(iface.InfoSource, guestAgentInfoSource) {
+// deleteEndpointsExcept deletes the VMI's DNSEndpoints other than the one at
+// keep. This cleans up after the target-namespace annotation changes, since
+// only an endpoint in the VMI's own namespace is garbage-collected through its
+// owner reference. Secondary DNSEndpoints are left alone.
+func (r *VirtualMachineInstanceReconciler) deleteEndpointsExcept(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, keep client.ObjectKey) error {
+	var stale []*dnsendpointv1alpha1.DNSEndpoint
+	// A DNSEndpoint named after the VMI may predate --endpoint-name-format;
+	// it is only removed if the VMI owns it.
+	name, _ := r.endpointName(vmi)
+	for _, localName := range slices.Compact([]string{name, vmi.Name}) {
+		local := client.ObjectKey{Name: localName, Namespace: vmi.Namespace}
+		if local == keep {
 			continue
 		}
-		for _, addr := range iface.IPs {
-			addr = strings.TrimSpace(addr)
-			if addr == "" {
-				continue
+		endpoint := &dnsendpointv1alpha1.DNSEndpoint{}
+		err := r.endpoints().Get(ctx, local, endpoint)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err != nil {
+			continue
+		}
+		if _, uid, _ := endpointOwner(endpoint); localName == name || uid == vmi.UID {
+			stale = append(stale, endpoint)
+		}
+	}
+	if vmi.UID != "" {
+		list := &dnsendpointv1alpha1.DNSEndpointList{}
+		if err := r.endpoints().List(ctx, list, client.MatchingLabels{labelOwnerUID: string(vmi.UID)}); err != nil {
+			return err
+		}
+		for i := range list.Items {
+			if ep := &list.Items[i]; !isSecondaryEndpoint(ep) && client.ObjectKeyFromObject(ep) != keep {
+				stale = append(stale, ep)
 			}
-			ip := net.ParseIP(addr)
-			if ip == nil {
+		}
+	}
+	return r.deleteEndpoints(ctx, vmi, stale...)
+}
+
+// deleteEndpoints deletes the VMI's DNSEndpoints, skipping those already gone.
+func (r *VirtualMachineInstanceReconciler) deleteEndpoints(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, stale ...*dnsendpointv1alpha1.DNSEndpoint) error {
+	for _, endpoint := range stale {
+		if err := r.endpoints().Delete(ctx, endpoint); err != nil {
+			if apierrors.IsNotFound(err) {
 				continue
 			}
-			if ip.To4() != nil {
-				ipv4 = append(ipv4, addr)
-			} else if ip.To16() != nil && !ip.IsLinkLocalUnicast() {
-				ipv6 = append(ipv6, addr)
-			}
+			return err
 		}
+		r.recordEndpointDelete(ctx, vmi, endpoint)
 	}
-	return
+	return nil
 }
 
-// extractMultusIPs returns IPv4 and IPv6 addresses from interfaces whose
-// infoSource contains "multus-status", using the single iface.IP field.
-func extractMultusIPs(vmi *kubevirtv1.VirtualMachineInstance) (ipv4, ipv6 []string) {
-	for _, iface := range vmi.Status.Interfaces {
-		if !containsInfoSource(iface.InfoSource, multusInfoSource) {
-			continue
+// recordEndpointWrite reports a DNSEndpoint created or updated for vmi in the
+// managed-endpoints gauge, the audit log and, when announce is set, an Event.
+// With DryRun set nothing was written, so nothing is recorded; the dry-run
+// client has logged the write instead.
+func (r *VirtualMachineInstanceReconciler) recordEndpointWrite(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, endpoint *dnsendpointv1alpha1.DNSEndpoint, op controllerutil.OperationResult, previous []*dnsendpointv1alpha1.Endpoint, announce bool) {
+	if r.DryRun || op == controllerutil.OperationResultNone {
+		return
+	}
+	r.audit(ctx, vmi, previous, endpoint.Spec.Endpoints)
+	switch op {
+	case controllerutil.OperationResultCreated:
+		managedEndpoints.WithLabelValues(endpoint.Namespace).Inc()
+		if announce {
+			r.Recorder.Eventf(vmi, corev1.EventTypeNormal, eventReasonEndpointCreated, "Created DNSEndpoint %s", endpointRef(vmi, endpoint))
 		}
-		addr := strings.TrimSpace(iface.IP)
-		if addr == "" {
-			continue
+	case controllerutil.OperationResultUpdated:
+		if announce {
+			r.Recorder.Eventf(vmi, corev1.EventTypeNormal, eventReasonEndpointUpdated, "Updated DNSEndpoint %s", endpointRef(vmi, endpoint))
 		}
-		ip := net.ParseIP(addr)
-		if ip == nil {
-			continue
+	}
+}
+
+// recordEndpointDelete reports a DNSEndpoint of vmi deleted, like
+// recordEndpointWrite.
+func (r *VirtualMachineInstanceReconciler) recordEndpointDelete(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, endpoint *dnsendpointv1alpha1.DNSEndpoint) {
+	if r.DryRun {
+		return
+	}
+	if isManagedEndpoint(endpoint) {
+		managedEndpoints.WithLabelValues(endpoint.Namespace).Dec()
+	}
+	r.audit(ctx, vmi, endpoint.Spec.Endpoints, nil)
+	r.Recorder.Eventf(vmi, corev1.EventTypeNormal, eventReasonEndpointDeleted, "Deleted DNSEndpoint %s", endpointRef(vmi, endpoint))
+}
+
+// audit writes the record changes of a DNSEndpoint write to the audit log.
+// Failing to write the audit log does not fail the reconcile.
+func (r *VirtualMachineInstanceReconciler) audit(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, before, after []*dnsendpointv1alpha1.Endpoint) {
+	if err := r.Audit.recordChanges(r.clock(), vmi, before, after); err != nil {
+		log.FromContext(ctx).Error(err, "failed to write audit log", "vmi", client.ObjectKeyFromObject(vmi))
+	}
+}
+
+// endpointRef names a DNSEndpoint of the VMI in events: by name in the VMI's
+// own namespace, and as namespace/name elsewhere.
+func endpointRef(vmi *kubevirtv1.VirtualMachineInstance, endpoint *dnsendpointv1alpha1.DNSEndpoint) string {
+	if endpoint.Namespace == vmi.Namespace {
+		return endpoint.Name
+	}
+	return endpoint.Namespace + "/" + endpoint.Name
+}
+
+// noIPRequeueAfter records another no-IP reconcile for key and returns how
+// long to wait before the next one: NoIPRetryInterval doubled once per earlier
+// attempt, capped at NoIPRetryMaxInterval.
+func (r *VirtualMachineInstanceReconciler) noIPRequeueAfter(key types.NamespacedName) time.Duration {
+	if r.NoIPRetryInterval <= 0 {
+		return 0
+	}
+	attempts := 0
+	if v, ok := r.noIPAttempts.Load(key); ok {
+		attempts = v.(int)
+	}
+	r.noIPAttempts.Store(key, attempts+1)
+
+	delay := r.NoIPRetryInterval
+	for i := 0; i < attempts; i++ {
+		if r.NoIPRetryMaxInterval > 0 && delay >= r.NoIPRetryMaxInterval {
+			break
 		}
-		if ip.To4() != nil {
-			ipv4 = append(ipv4, addr)
-		} else if ip.To16() != nil {
-			ipv6 = append(ipv6, addr)
+		delay *= 2
+	}
+	if r.NoIPRetryMaxInterval > 0 && delay > r.NoIPRetryMaxInterval {
+		delay = r.NoIPRetryMaxInterval
+	}
+	return delay
+}
+
+// debounceRemaining returns how much of DebounceWindow is left since the last
+// reconcile of key finished, or zero when the reconcile may run now.
+func (r *VirtualMachineInstanceReconciler) debounceRemaining(key types.NamespacedName) time.Duration {
+	if r.DebounceWindow <= 0 {
+		return 0
+	}
+	v, ok := r.lastReconciled.Load(key)
+	if !ok {
+		return 0
+	}
+	return r.DebounceWindow - time.Since(v.(time.Time))
+}
+
+// coalesceRemaining returns how much of CoalesceWindow is left since the last
+// reconcile of key completed, or zero when the reconcile may run now.
+func (r *VirtualMachineInstanceReconciler) coalesceRemaining(key types.NamespacedName) time.Duration {
+	if r.CoalesceWindow <= 0 {
+		return 0
+	}
+	v, ok := r.lastCompleted.Load(key)
+	if !ok {
+		return 0
+	}
+	return r.CoalesceWindow - time.Since(v.(time.Time))
+}
+
+// phaseWaitRequeueAfter records another not-Running reconcile for the VMI,
+// emits a Warning event when the count reaches PhaseWarningThreshold, and
+// returns PhaseRetryInterval.
+func (r *VirtualMachineInstanceReconciler) phaseWaitRequeueAfter(vmi *kubevirtv1.VirtualMachineInstance) time.Duration {
+	key := types.NamespacedName{Namespace: vmi.Namespace, Name: vmi.Name}
+	waits := 1
+	if v, ok := r.phaseWaits.Load(key); ok {
+		waits += v.(int)
+	}
+	r.phaseWaits.Store(key, waits)
+	if r.PhaseWarningThreshold > 0 && waits == r.PhaseWarningThreshold {
+		r.Recorder.Eventf(vmi, corev1.EventTypeWarning, eventReasonVMINotRunning,
+			"VMI has been in phase %q for %d reconciles; DNS is not published until it is Running", vmi.Status.Phase, waits)
+	}
+	return r.PhaseRetryInterval
+}
+
+// clearForceReconcile removes the force-reconcile annotation from the VMI, if set.
+func (r *VirtualMachineInstanceReconciler) clearForceReconcile(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, settings ControllerSettings) error {
+	key := settings.annotationKey(annotationForceReconcile)
+	if _, ok := vmi.Annotations[key]; !ok {
+		return nil
+	}
+	patch := client.MergeFrom(vmi.DeepCopy())
+	delete(vmi.Annotations, key)
+	return client.IgnoreNotFound(r.Patch(ctx, vmi, patch))
+}
+
+// removeFinalizer drops the cleanup and DNS propagation finalizers from the
+// VMI, including those of PreviousFinalizerNames, if they are present.
+func (r *VirtualMachineInstanceReconciler) removeFinalizer(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance) error {
+	removed := false
+	for _, f := range append([]string{r.finalizerName(), r.propagationFinalizer()}, r.previousFinalizers()...) {
+		if controllerutil.RemoveFinalizer(vmi, f) {
+			removed = true
 		}
 	}
-	return
+	if !removed {
+		return nil
+	}
+	return r.Update(ctx, vmi)
+}
+
+// extractBestIPs returns IPv4 and IPv6 addresses for the VMI from the first
+// source in priority that yields any address accepted by filter. A nil
+// priority uses DefaultIPSourcePriority, which prefers guest-agent (the full
+// iface.IPs list, including global IPv6 unicast), then SR-IOV interfaces
+// (infoSource sriov or device-info), then multus-status (the single iface.IP
+// field). A source whose addresses are all filtered out falls through
+// to the next one. Only interfaces selected by the filter's interface names
+// are considered.
+//
+// The returned source string indicates which source was used.
+func extractBestIPs(vmi *kubevirtv1.VirtualMachineInstance, filter ipFilter, priority []string) (ipv4, ipv6 []string, source string) {
+	if priority == nil {
+		priority = DefaultIPSourcePriority
+	}
+	vmi = filter.selectInterfaces(vmi)
+	for _, name := range priority {
+		v4, v6 := extractIPs(vmi, lookupIPExtractor(name))
+		v4, v6 = filter.apply(deduplicateIPs(v4), deduplicateIPs(v6))
+		if len(v4) > 0 || len(v6) > 0 {
+			return v4, v6, name
+		}
+	}
+	return nil, nil, ""
+}
+
+// extractGuestAgentIPs returns the addresses of interfaces whose infoSource
+// contains "guest-agent" (see GuestAgentExtractor).
+func extractGuestAgentIPs(vmi *kubevirtv1.VirtualMachineInstance) (ipv4, ipv6 []string) {
+	return extractIPs(vmi, GuestAgentExtractor{})
+}
+
+// extractMultusIPs returns the addresses of interfaces whose infoSource
+// contains "multus-status" (see MultusExtractor).
+func extractMultusIPs(vmi *kubevirtv1.VirtualMachineInstance) (ipv4, ipv6 []string) {
+	return extractIPs(vmi, MultusExtractor{})
+}
+
+// extractSRIOVIPs returns the addresses of interfaces whose infoSource
+// contains "sriov" or "device-info" (see SRIOVExtractor).
+func extractSRIOVIPs(vmi *kubevirtv1.VirtualMachineInstance) (ipv4, ipv6 []string) {
+	return extractIPs(vmi, SRIOVExtractor{})
+}
+
+// appendIP classifies addr and appends it to ipv4 or ipv6. Empty, unparseable
+// and link-local addresses are dropped.
+func appendIP(ipv4, ipv6 []string, addr string) ([]string, []string) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return ipv4, ipv6
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.IsLinkLocalUnicast() {
+		return ipv4, ipv6
+	}
+	if ip.To4() != nil {
+		ipv4 = append(ipv4, addr)
+	} else if ip.To16() != nil {
+		ipv6 = append(ipv6, addr)
+	}
+	return ipv4, ipv6
+}
+
+// deduplicateIPs removes repeated addresses, keeping the first occurrence of
+// each. Addresses are compared in canonical form, so "2001:db8:0::1" and
+// "2001:db8::1" are duplicates.
+func deduplicateIPs(ips []string) []string {
+	if len(ips) == 0 {
+		return ips
+	}
+	seen := make(map[string]struct{}, len(ips))
+	result := make([]string, 0, len(ips))
+	for _, addr := range ips {
+		key := addr
+		if ip := net.ParseIP(addr); ip != nil {
+			key = ip.String()
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, addr)
+	}
+	return result
 }
 
 // containsInfoSource returns true if the comma-separated infoSource field
@@ -210,11 +1186,18 @@ func containsInfoSource(infoSource, source string) bool {
 	return false
 }
 
-// parseHostnames splits a comma-separated list of hostnames.
+// parseHostnames splits a comma-separated list of hostnames. Surrounding
+// whitespace and trailing dots are stripped, so "vm.example.com." and
+// "vm.example.com" yield the same record; either form may be used. Hostnames
+// are lowercased, except for template entries whose field references are
+// case-sensitive (renderHostnames lowercases those once rendered).
 func parseHostnames(raw string) []string {
 	var result []string
 	for _, h := range strings.Split(raw, ",") {
-		h = strings.TrimSpace(h)
+		h = strings.TrimRightFunc(strings.TrimSpace(h), func(r rune) bool { return r == '.' || unicode.IsSpace(r) })
+		if !isHostnameTemplate(h) {
+			h = strings.ToLower(h)
+		}
 		if h != "" {
 			result = append(result, h)
 		}
@@ -222,67 +1205,290 @@ func parseHostnames(raw string) []string {
 	return result
 }
 
+// hasUppercaseHostnames reports whether any non-template entry of the raw
+// hostname annotation contains uppercase letters that parseHostnames lowercases.
+func hasUppercaseHostnames(raw string) bool {
+	for _, h := range strings.Split(raw, ",") {
+		if !isHostnameTemplate(h) && h != strings.ToLower(h) {
+			return true
+		}
+	}
+	return false
+}
+
 // parseTTL converts the TTL annotation string to a dnsendpointv1alpha1.TTL value.
-// Falls back to defaultTTL if the value is absent or not a valid integer.
+// It accepts integer seconds ("300") or a Go duration ("5m", "1h30m"), which is
+// truncated to whole seconds. Falls back to defaultTTL if the value is absent,
+// unparseable, or not positive.
 func parseTTL(raw string) dnsendpointv1alpha1.TTL {
+	ttl, _ := ttlFromAnnotation(raw, defaultTTL)
+	return ttl
+}
+
+// errZeroTTL marks a TTL annotation of zero, which some DNS providers reject.
+// It is reported together with errInvalidAnnotation.
+var errZeroTTL = errors.New("zero TTL")
+
+// ttlFromAnnotation behaves like parseTTL with a configurable fallback, and also
+// reports an errInvalidAnnotation error when the value is present but not a
+// positive number of seconds or duration.
+func ttlFromAnnotation(raw string, fallback dnsendpointv1alpha1.TTL) (dnsendpointv1alpha1.TTL, error) {
 	if raw == "" {
-		return defaultTTL
+		return fallback, nil
+	}
+	value := strings.TrimSpace(raw)
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		d, durErr := time.ParseDuration(value)
+		if durErr == nil {
+			v, err = int64(d.Seconds()), nil
+		}
+	}
+	if err == nil && v == 0 {
+		return fallback, fmt.Errorf("%w: %s=%q: %w", errInvalidAnnotation, annotationTTL, raw, errZeroTTL)
 	}
-	v, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
 	if err != nil || v <= 0 {
-		return defaultTTL
+		return fallback, fmt.Errorf("%w: %s=%q is not a positive number of seconds or duration", errInvalidAnnotation, annotationTTL, raw)
 	}
-	return dnsendpointv1alpha1.TTL(v)
+	return dnsendpointv1alpha1.TTL(v), nil
+}
+
+// parsePerHostnameTTL collects the "ttl-<hostname>" annotations, which override
+// the TTL of a single hostname, keyed by the normalized hostname. Invalid values are dropped and reported through
+// the returned error, which wraps errInvalidAnnotation.
+func parsePerHostnameTTL(annotations map[string]string, settings ControllerSettings) (map[string]dnsendpointv1alpha1.TTL, error) {
+	prefix := settings.annotationKey(annotationTTL) + "-"
+	var ttls map[string]dnsendpointv1alpha1.TTL
+	var errs []error
+	for key, raw := range annotations {
+		hostname, ok := strings.CutPrefix(key, prefix)
+		if !ok || hostname == "" || strings.TrimSpace(raw) == "" || isRecordTypeTTLKey(key, settings) {
+			continue
+		}
+		ttl, err := ttlFromAnnotation(raw, 0)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		if ttls == nil {
+			ttls = map[string]dnsendpointv1alpha1.TTL{}
+		}
+		ttls[normalizeDNSName(hostname)] = ttl
+	}
+	return ttls, errors.Join(errs...)
+}
+
+// isRecordTypeTTLKey reports whether key is the ttl-A or ttl-AAAA annotation
+// rather than a per-hostname TTL.
+func isRecordTypeTTLKey(key string, settings ControllerSettings) bool {
+	return key == settings.annotationKey(annotationTTLA) || key == settings.annotationKey(annotationTTLAAAA)
 }
 
 // buildEndpoints creates Endpoint entries for each record type that has targets.
-func buildEndpoints(hostnames, ipv4, ipv6 []string, ttl dnsendpointv1alpha1.TTL) []*dnsendpointv1alpha1.Endpoint {
+// Hostnames found in hostnameTTLs use that TTL; all others use the TTL of their
+// record type in typeTTLs, or ttl.
+// Targets and the resulting endpoint list are sorted so that the same set of
+// inputs always yields an identical spec, regardless of interface ordering.
+func buildEndpoints(hostnames, ipv4, ipv6 []string, ttl dnsendpointv1alpha1.TTL, hostnameTTLs, typeTTLs map[string]dnsendpointv1alpha1.TTL, setIdentifier string) []*dnsendpointv1alpha1.Endpoint {
+	ipv4 = sortedCopy(ipv4)
+	ipv6 = sortedCopy(ipv6)
+
 	var endpoints []*dnsendpointv1alpha1.Endpoint
+	typeTTL := func(recordType string) dnsendpointv1alpha1.TTL {
+		if override, ok := typeTTLs[recordType]; ok {
+			return override
+		}
+		return ttl
+	}
 	for _, hostname := range hostnames {
+		ttlA, ttlAAAA := typeTTL("A"), typeTTL("AAAA")
+		if override, ok := hostnameTTLs[normalizeDNSName(hostname)]; ok {
+			ttlA, ttlAAAA = override, override
+		}
 		if len(ipv4) > 0 {
 			endpoints = append(endpoints, &dnsendpointv1alpha1.Endpoint{
-				DNSName:    hostname,
-				RecordType: "A",
-				Targets:    dnsendpointv1alpha1.Targets(ipv4),
-				RecordTTL:  ttl,
+				DNSName:       hostname,
+				RecordType:    "A",
+				Targets:       dnsendpointv1alpha1.Targets(ipv4),
+				RecordTTL:     ttlA,
+				SetIdentifier: setIdentifier,
 			})
 		}
 		if len(ipv6) > 0 {
 			endpoints = append(endpoints, &dnsendpointv1alpha1.Endpoint{
-				DNSName:    hostname,
-				RecordType: "AAAA",
-				Targets:    dnsendpointv1alpha1.Targets(ipv6),
-				RecordTTL:  ttl,
+				DNSName:       hostname,
+				RecordType:    "AAAA",
+				Targets:       dnsendpointv1alpha1.Targets(ipv6),
+				RecordTTL:     ttlAAAA,
+				SetIdentifier: setIdentifier,
 			})
 		}
 	}
+	slices.SortStableFunc(endpoints, compareEndpoints)
 	return endpoints
 }
 
-// vmiChangedPredicate filters VMI update events to those where either the
-// hostname annotation or the status.interfaces list has actually changed.
-// The full Interfaces slice comparison covers both iface.IP (multus-status)
-// and iface.IPs (guest-agent) fields. Create and delete events always pass through.
-var vmiChangedPredicate = predicate.Funcs{
-	UpdateFunc: func(e event.UpdateEvent) bool {
-		oldVMI, ok1 := e.ObjectOld.(*kubevirtv1.VirtualMachineInstance)
-		newVMI, ok2 := e.ObjectNew.(*kubevirtv1.VirtualMachineInstance)
-		if !ok1 || !ok2 {
+// compareEndpoints orders endpoints by RecordType and then DNSName.
+func compareEndpoints(a, b *dnsendpointv1alpha1.Endpoint) int {
+	if c := cmp.Compare(a.RecordType, b.RecordType); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.DNSName, b.DNSName)
+}
+
+// sortedCopy returns a sorted copy of ips, leaving the input slice untouched.
+func sortedCopy(ips []string) []string {
+	if len(ips) == 0 {
+		return ips
+	}
+	out := append([]string(nil), ips...)
+	sort.Strings(out)
+	return out
+}
+
+// watchedAnnotations lists the annotations whose changes trigger a reconcile.
+var watchedAnnotations = []string{
+	annotationHostname,
+	annotationTTL,
+	annotationTTLA,
+	annotationTTLAAAA,
+	annotationAllowedCIDRs,
+	annotationPreferredCIDR,
+	annotationInterfaceNames,
+	annotationHostnamePrefix,
+	annotationHostnameSuffix,
+	annotationStaticIP,
+	annotationIPPool,
+	annotationPropagateAnnotations,
+	annotationPaused,
+	annotationRecordType,
+	annotationTargetNamespace,
+	annotationWeight,
+	annotationSetIdentifier,
+	annotationHealthCheckID,
+	annotationInternalHostname,
+	annotationExternalHostname,
+	annotationInternalAllowedCIDRs,
+	annotationExternalAllowedCIDRs,
+	annotationInterfaceDNSMap,
+}
+
+// watchedAnnotationPrefixes lists prefixes of annotations whose changes trigger a reconcile.
+var watchedAnnotationPrefixes = []string{
+	annotationProviderSpecificPrefix,
+}
+
+// watchedAnnotationsChanged reports whether any watched annotation differs between old and new.
+func watchedAnnotationsChanged(settings ControllerSettings, oldAnnotations, newAnnotations map[string]string) bool {
+	for _, key := range watchedAnnotations {
+		key = settings.annotationKey(key)
+		if oldAnnotations[key] != newAnnotations[key] {
+			return true
+		}
+	}
+	for _, prefix := range watchedAnnotationPrefixes {
+		prefix = settings.annotationKey(prefix)
+		if prefixedAnnotationsChanged(prefix, oldAnnotations, newAnnotations) || prefixedAnnotationsChanged(prefix, newAnnotations, oldAnnotations) {
 			return true
 		}
-		annotationChanged := oldVMI.Annotations[annotationHostname] != newVMI.Annotations[annotationHostname]
-		interfacesChanged := !reflect.DeepEqual(oldVMI.Status.Interfaces, newVMI.Status.Interfaces)
-		return annotationChanged || interfacesChanged
-	},
-	CreateFunc:  func(e event.CreateEvent) bool { return true },
-	DeleteFunc:  func(e event.DeleteEvent) bool { return true },
-	GenericFunc: func(e event.GenericEvent) bool { return true },
+	}
+	return false
+}
+
+// prefixedAnnotationsChanged reports whether an annotation in a starting with
+// prefix is missing from b or has a different value there.
+func prefixedAnnotationsChanged(prefix string, a, b map[string]string) bool {
+	for key, value := range a {
+		if other, ok := b[key]; strings.HasPrefix(key, prefix) && (!ok || other != value) {
+			return true
+		}
+	}
+	return false
+}
+
+// vmiChangedPredicate filters VMI update events to those where a watched
+// annotation or the status.interfaces list has actually changed, or where the
+// VMI has just been marked for deletion. Resync events pass as well, and so
+// do label changes when TemplateBased is set.
+// The full Interfaces slice comparison covers both iface.IP (multus-status)
+// and iface.IPs (guest-agent) fields. Create and delete events always pass through.
+// Annotation keys are resolved against the current settings on every event.
+func (r *VirtualMachineInstanceReconciler) vmiChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldVMI, ok1 := e.ObjectOld.(*kubevirtv1.VirtualMachineInstance)
+			newVMI, ok2 := e.ObjectNew.(*kubevirtv1.VirtualMachineInstance)
+			if !ok1 || !ok2 {
+				return true
+			}
+			settings := r.cachedSettingsFor(newVMI.Namespace)
+			annotationChanged := watchedAnnotationsChanged(settings, oldVMI.Annotations, newVMI.Annotations)
+			interfacesChanged := !reflect.DeepEqual(oldVMI.Status.Interfaces, newVMI.Status.Interfaces)
+			deletionStarted := oldVMI.DeletionTimestamp.IsZero() && !newVMI.DeletionTimestamp.IsZero()
+			forced := newVMI.Annotations[settings.annotationKey(annotationForceReconcile)] != ""
+			// Reconcile gates on the Running phase, so a phase change must trigger it...
+			phaseChanged := oldVMI.Status.Phase != newVMI.Status.Phase
+			// ... and on the Ready condition.
+			readyChanged := isVMIReady(oldVMI) != isVMIReady(newVMI)
+			// Hostname templates may render labels.
+			labelsChanged := r.TemplateBased && !reflect.DeepEqual(oldVMI.Labels, newVMI.Labels)
+			// Periodic informer resyncs deliver the unchanged object; let them
+			// through so --resync-period can repair drifted DNSEndpoints.
+			resync := oldVMI.ResourceVersion == newVMI.ResourceVersion
+			return annotationChanged || interfacesChanged || phaseChanged || readyChanged || deletionStarted || labelsChanged || resync || forced
+		},
+		CreateFunc:  func(e event.CreateEvent) bool { return true },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return true },
+		GenericFunc: func(e event.GenericEvent) bool { return true },
+	}
 }
 
 // SetupWithManager registers the controller with the manager.
 func (r *VirtualMachineInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&kubevirtv1.VirtualMachineInstance{}, builder.WithPredicates(vmiChangedPredicate)).
-		Owns(&dnsendpointv1alpha1.DNSEndpoint{}).
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &kubevirtv1.VirtualMachineInstance{}, vmiHostnameIndex, r.indexVMIHostnames); err != nil {
+		return err
+	}
+	endpointIndexer := mgr.GetFieldIndexer()
+	if r.EndpointCache != nil {
+		endpointIndexer = r.EndpointCache
+	}
+	if err := endpointIndexer.IndexField(context.Background(), &dnsendpointv1alpha1.DNSEndpoint{}, endpointOwnerUIDIndex, indexEndpointOwnerUID); err != nil {
+		return err
+	}
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&kubevirtv1.VirtualMachineInstance{}, builder.WithPredicates(r.vmiChangedPredicate())).
+		// A succeeded live migration may have moved the VMI to new addresses.
+		Watches(&kubevirtv1.VirtualMachineInstanceMigration{}, handler.EnqueueRequestsFromMapFunc(migratedVMIRequests),
+			builder.WithPredicates(migrationSucceededPredicate()))
+	if r.EndpointCache != nil {
+		// Remote DNSEndpoints carry no owner reference; map them to their VMI
+		// through the owner label and annotation.
+		b = b.WatchesRawSource(source.Kind[client.Object](r.EndpointCache, &dnsendpointv1alpha1.DNSEndpoint{},
+			handler.EnqueueRequestsFromMapFunc(crossNamespaceOwnerRequests), predicate.GenerationChangedPredicate{}))
+	} else {
+		// Only spec changes and deletions of a DNSEndpoint need a reconcile; the
+		// controller's own metadata writes (conditions, last-reconcile-time) must
+		// not trigger another one.
+		b = b.Owns(&dnsendpointv1alpha1.DNSEndpoint{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+			// DNSEndpoints in a target namespace carry no owner reference.
+			Watches(&dnsendpointv1alpha1.DNSEndpoint{}, handler.EnqueueRequestsFromMapFunc(crossNamespaceOwnerRequests),
+				builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+	return b.WithOptions(controller.Options{
+		RateLimiter:             r.RateLimiter,
+		MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		NewQueue:                newDepthTrackingQueue(queueDepth),
+	}).
 		Complete(r)
 }
+
+// NewRateLimiter builds the controller's workqueue rate limiter: the slower of a
+// per-item exponential backoff between baseDelay and maxDelay, and an overall
+// token bucket of qps with the given burst. With baseDelay=5ms, maxDelay=1000s,
+// qps=10 and burst=100 it is equivalent to the controller-runtime default.
+func NewRateLimiter(baseDelay, maxDelay time.Duration, qps float64, burst int) workqueue.TypedRateLimiter[reconcile.Request] {
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](baseDelay, maxDelay),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+}
@@ -1,13 +1,128 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
 )
 
+// ---------- test helpers ----------
+
+// newTestScheme returns a scheme with the core, KubeVirt and DNSEndpoint types registered.
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := kubevirtv1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddDNSEndpointToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// newTestReconciler returns a reconciler backed by a fake client seeded with objs.
+func newTestReconciler(t *testing.T, objs ...client.Object) *VirtualMachineInstanceReconciler {
+	t.Helper()
+	s := newTestScheme(t)
+	r := &VirtualMachineInstanceReconciler{Scheme: s, Recorder: record.NewFakeRecorder(100)}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).
+		WithIndex(&kubevirtv1.VirtualMachineInstance{}, vmiHostnameIndex, r.indexVMIHostnames).Build()
+	return r
+}
+
+// withHostnameIndex registers the VMI hostname index SetupWithManager adds to
+// the manager's cache, computed with the default settings.
+func withHostnameIndex(b *fake.ClientBuilder) *fake.ClientBuilder {
+	return b.WithIndex(&kubevirtv1.VirtualMachineInstance{}, vmiHostnameIndex, new(VirtualMachineInstanceReconciler).indexVMIHostnames)
+}
+
+// recordedEvents drains and returns the events captured by the reconciler's fake recorder.
+func recordedEvents(r *VirtualMachineInstanceReconciler) []string {
+	events := r.Recorder.(*record.FakeRecorder).Events
+	var out []string
+	for {
+		select {
+		case e := <-events:
+			out = append(out, e)
+		default:
+			return out
+		}
+	}
+}
+
+// newTestVMI returns a running VMI in the default namespace with the given annotations and interfaces.
+func newTestVMI(name string, annotations map[string]string, ifaces ...kubevirtv1.VirtualMachineInstanceNetworkInterface) *kubevirtv1.VirtualMachineInstance {
+	return &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			UID:         types.UID(name + "-uid"),
+			Annotations: annotations,
+		},
+		Status: kubevirtv1.VirtualMachineInstanceStatus{
+			Phase:      kubevirtv1.Running,
+			Interfaces: ifaces,
+			Conditions: []kubevirtv1.VirtualMachineInstanceCondition{
+				{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+}
+
+// reconcileVMI runs a single reconcile for the named VMI in the default namespace.
+func reconcileVMI(t *testing.T, r *VirtualMachineInstanceReconciler, name string) ctrl.Result {
+	t.Helper()
+	res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}})
+	if err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	return res
+}
+
+// getEndpoint fetches the named DNSEndpoint from the default namespace.
+func getEndpoint(t *testing.T, r *VirtualMachineInstanceReconciler, name string) *dnsendpointv1alpha1.DNSEndpoint {
+	t.Helper()
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, ep); err != nil {
+		t.Fatalf("failed to get DNSEndpoint %s: %v", name, err)
+	}
+	return ep
+}
+
 // ---------- extractGuestAgentIPs ----------
 
 func TestExtractGuestAgentIPs_Empty(t *testing.T) {
@@ -169,8 +284,117 @@ func TestExtractMultusIPs_CommaSeperatedInfoSource(t *testing.T) {
 	}
 }
 
+func TestExtractMultusIPs_LinkLocalSkipped(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{IP: "169.254.10.20", InfoSource: "multus-status"},
+		{IP: "fe80::1", InfoSource: "multus-status"},
+		{IP: "192.168.1.10", InfoSource: "multus-status"},
+	}
+	v4, v6 := extractMultusIPs(vmi)
+	if len(v4) != 1 || v4[0] != "192.168.1.10" {
+		t.Errorf("expected only the routable IPv4, got %v", v4)
+	}
+	if len(v6) != 0 {
+		t.Errorf("expected link-local IPv6 to be skipped, got %v", v6)
+	}
+}
+
+func TestExtractGuestAgentIPs_IPv4LinkLocalSkipped(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{IPs: []string{"169.254.1.1", "10.0.0.1"}, InfoSource: "guest-agent"},
+	}
+	v4, _ := extractGuestAgentIPs(vmi)
+	if len(v4) != 1 || v4[0] != "10.0.0.1" {
+		t.Errorf("expected link-local IPv4 to be skipped, got %v", v4)
+	}
+}
+
+// ---------- extractSRIOVIPs ----------
+
+func TestExtractSRIOVIPs(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{IP: "10.1.0.1", InfoSource: "sriov"},
+		{IPs: []string{"10.1.0.2", "2001:db8::2", "fe80::2"}, InfoSource: "domain, device-info"},
+		{IP: "10.0.0.5", IPs: []string{"10.0.0.5"}, InfoSource: "guest-agent, multus-status"},
+	}
+	v4, v6 := extractSRIOVIPs(vmi)
+	if !reflect.DeepEqual(v4, []string{"10.1.0.1", "10.1.0.2"}) {
+		t.Errorf("unexpected v4: %v", v4)
+	}
+	if !reflect.DeepEqual(v6, []string{"2001:db8::2"}) {
+		t.Errorf("expected the link-local address to be skipped, got v6=%v", v6)
+	}
+}
+
 // ---------- extractBestIPs ----------
 
+func TestExtractBestIPs_SRIOVOnly(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{IP: "10.1.0.1", IPs: []string{"10.1.0.1", "2001:db8::1"}, InfoSource: "device-info"},
+	}
+	v4, v6, source := extractBestIPs(vmi, ipFilter{}, nil)
+	if source != sriovInfoSource {
+		t.Errorf("expected source=%q, got %q", sriovInfoSource, source)
+	}
+	if !reflect.DeepEqual(v4, []string{"10.1.0.1"}) || !reflect.DeepEqual(v6, []string{"2001:db8::1"}) {
+		t.Errorf("unexpected IPs: v4=%v v6=%v", v4, v6)
+	}
+}
+
+func TestExtractBestIPs_SRIOVMixedSources(t *testing.T) {
+	tests := []struct {
+		name       string
+		interfaces []kubevirtv1.VirtualMachineInstanceNetworkInterface
+		wantSource string
+		wantV4     []string
+	}{
+		{
+			name: "guest-agent preferred over sriov",
+			interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+				{IPs: []string{"10.0.0.5"}, InfoSource: "guest-agent"},
+				{IP: "10.1.0.1", InfoSource: "sriov"},
+			},
+			wantSource: guestAgentInfoSource,
+			wantV4:     []string{"10.0.0.5"},
+		},
+		{
+			name: "sriov preferred over multus-status",
+			interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+				{IP: "10.0.0.5", InfoSource: "multus-status"},
+				{IP: "10.1.0.1", InfoSource: "sriov"},
+			},
+			wantSource: sriovInfoSource,
+			wantV4:     []string{"10.1.0.1"},
+		},
+		{
+			name: "link-local sriov falls back to multus-status",
+			interfaces: []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+				{IP: "10.0.0.5", InfoSource: "multus-status"},
+				{IP: "169.254.0.1", InfoSource: "device-info"},
+			},
+			wantSource: multusInfoSource,
+			wantV4:     []string{"10.0.0.5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmi := &kubevirtv1.VirtualMachineInstance{}
+			vmi.Status.Interfaces = tt.interfaces
+			v4, _, source := extractBestIPs(vmi, ipFilter{}, nil)
+			if source != tt.wantSource {
+				t.Errorf("expected source=%q, got %q", tt.wantSource, source)
+			}
+			if !reflect.DeepEqual(v4, tt.wantV4) {
+				t.Errorf("unexpected v4: %v", v4)
+			}
+		})
+	}
+}
+
 func TestExtractBestIPs_GuestAgentPreferredOverMultus(t *testing.T) {
 	vmi := &kubevirtv1.VirtualMachineInstance{}
 	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
@@ -180,7 +404,7 @@ func TestExtractBestIPs_GuestAgentPreferredOverMultus(t *testing.T) {
 			InfoSource: "domain, guest-agent, multus-status",
 		},
 	}
-	v4, v6, source := extractBestIPs(vmi)
+	v4, v6, source := extractBestIPs(vmi, ipFilter{}, nil)
 	if source != guestAgentInfoSource {
 		t.Errorf("expected source=%q, got %q", guestAgentInfoSource, source)
 	}
@@ -197,7 +421,7 @@ func TestExtractBestIPs_FallsBackToMultusWhenNoGuestAgent(t *testing.T) {
 	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
 		{IP: "10.0.0.5", InfoSource: "multus-status"},
 	}
-	v4, _, source := extractBestIPs(vmi)
+	v4, _, source := extractBestIPs(vmi, ipFilter{}, nil)
 	if source != multusInfoSource {
 		t.Errorf("expected source=%q, got %q", multusInfoSource, source)
 	}
@@ -211,7 +435,7 @@ func TestExtractBestIPs_NoIPs(t *testing.T) {
 	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
 		{IP: "10.0.0.1", InfoSource: "domain"},
 	}
-	v4, v6, source := extractBestIPs(vmi)
+	v4, v6, source := extractBestIPs(vmi, ipFilter{}, nil)
 	if source != "" {
 		t.Errorf("expected empty source, got %q", source)
 	}
@@ -230,7 +454,7 @@ func TestExtractBestIPs_GuestAgentLinkLocalOnlyFallsToMultus(t *testing.T) {
 			InfoSource: "guest-agent, multus-status",
 		},
 	}
-	v4, _, source := extractBestIPs(vmi)
+	v4, _, source := extractBestIPs(vmi, ipFilter{}, nil)
 	if source != multusInfoSource {
 		t.Errorf("expected fallback to multus-status, got source=%q", source)
 	}
@@ -239,6 +463,53 @@ func TestExtractBestIPs_GuestAgentLinkLocalOnlyFallsToMultus(t *testing.T) {
 	}
 }
 
+// ---------- IP extraction benchmarks ----------
+
+// newDenseVMI returns a VMI with n interfaces, as on a dense SR-IOV host. Every
+// interface reports an IPv4 and an IPv6 address; even ones are seen by the
+// guest agent and Multus, odd ones by Multus only.
+func newDenseVMI(n int) *kubevirtv1.VirtualMachineInstance {
+	ifaces := make([]kubevirtv1.VirtualMachineInstanceNetworkInterface, n)
+	for i := range ifaces {
+		infoSource := "domain, " + guestAgentInfoSource + ", " + multusInfoSource
+		if i%2 == 1 {
+			infoSource = multusInfoSource
+		}
+		ip := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		ifaces[i] = kubevirtv1.VirtualMachineInstanceNetworkInterface{
+			Name:       fmt.Sprintf("net%d", i),
+			IP:         ip,
+			IPs:        []string{ip, fmt.Sprintf("2001:db8::%x", i+1)},
+			InfoSource: infoSource,
+		}
+	}
+	return newTestVMI("vm", nil, ifaces...)
+}
+
+func BenchmarkExtractBestIPs_32Interfaces(b *testing.B) {
+	vmi := newDenseVMI(32)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		extractBestIPs(vmi, ipFilter{}, nil)
+	}
+}
+
+func BenchmarkExtractGuestAgentIPs_32Interfaces(b *testing.B) {
+	vmi := newDenseVMI(32)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		extractGuestAgentIPs(vmi)
+	}
+}
+
+func BenchmarkExtractMultusIPs_32Interfaces(b *testing.B) {
+	vmi := newDenseVMI(32)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		extractMultusIPs(vmi)
+	}
+}
+
 // ---------- containsInfoSource ----------
 
 func TestContainsInfoSource(t *testing.T) {
@@ -267,6 +538,24 @@ func TestContainsInfoSource(t *testing.T) {
 
 // ---------- parseHostnames ----------
 
+// fuzzSeeds are edge cases shared by the annotation parser fuzz targets.
+var fuzzSeeds = []string{
+	"",
+	"-1",
+	"0",
+	"300",
+	"5m",
+	"vm.example.com",
+	" vm.example.com , other.example.com. ",
+	"VM.Example.COM",
+	"{{ .Name }}.example.com",
+	",,, . ,",
+	"vm.example.com . ",
+	strings.Repeat("a", 300),
+	"vm\x00.example.com",
+	"\x00",
+}
+
 func TestParseHostnames(t *testing.T) {
 	tests := []struct {
 		raw  string
@@ -275,6 +564,10 @@ func TestParseHostnames(t *testing.T) {
 		{"foo.example.com", []string{"foo.example.com"}},
 		{"foo.example.com,bar.example.com", []string{"foo.example.com", "bar.example.com"}},
 		{"  foo.example.com , bar.example.com  ", []string{"foo.example.com", "bar.example.com"}},
+		{"vm.example.com.", []string{"vm.example.com"}},
+		{"vm.example.com.,vm2.example.com", []string{"vm.example.com", "vm2.example.com"}},
+		{" . ,vm.example.com", []string{"vm.example.com"}},
+		{"VM.Example.Com,{{ .Name }}.Example.com", []string{"vm.example.com", "{{ .Name }}.Example.com"}},
 		{"", nil},
 	}
 	for _, tt := range tests {
@@ -291,6 +584,27 @@ func TestParseHostnames(t *testing.T) {
 	}
 }
 
+func FuzzParseHostnames(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		hostnames := parseHostnames(raw)
+		for _, h := range hostnames {
+			if h == "" || strings.Contains(h, ",") {
+				t.Fatalf("parseHostnames(%q) returned entry %q", raw, h)
+			}
+			if h != strings.TrimSpace(h) || strings.HasSuffix(h, ".") {
+				t.Fatalf("parseHostnames(%q) returned untrimmed entry %q", raw, h)
+			}
+		}
+		// Parsing is idempotent, so published names round-trip.
+		if again := parseHostnames(strings.Join(hostnames, ",")); !slices.Equal(again, hostnames) {
+			t.Fatalf("parseHostnames is not idempotent on %q: %q then %q", raw, hostnames, again)
+		}
+	})
+}
+
 // ---------- parseTTL ----------
 
 func TestParseTTL(t *testing.T) {
@@ -304,6 +618,12 @@ func TestParseTTL(t *testing.T) {
 		{"abc", defaultTTL},
 		{"-1", defaultTTL},
 		{"0", defaultTTL},
+		{"5m", 300},
+		{"1h30m", 5400},
+		{"90s", 90},
+		{"0s", defaultTTL},
+		{"500ms", defaultTTL},
+		{"-1m", defaultTTL},
 	}
 	for _, tt := range tests {
 		got := parseTTL(tt.raw)
@@ -313,6 +633,17 @@ func TestParseTTL(t *testing.T) {
 	}
 }
 
+func FuzzParseTTL(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		if ttl := parseTTL(raw); ttl <= 0 {
+			t.Fatalf("parseTTL(%q) = %d, want a positive TTL", raw, ttl)
+		}
+	})
+}
+
 // ---------- buildEndpoints ----------
 
 func TestBuildEndpoints_BothRecordTypes(t *testing.T) {
@@ -321,7 +652,7 @@ func TestBuildEndpoints_BothRecordTypes(t *testing.T) {
 	ipv6 := []string{"2001:db8::1"}
 	ttl := dnsendpointv1alpha1.TTL(300)
 
-	eps := buildEndpoints(hostnames, ipv4, ipv6, ttl)
+	eps := buildEndpoints(hostnames, ipv4, ipv6, ttl, nil, nil, "")
 	if len(eps) != 2 {
 		t.Fatalf("expected 2 endpoints, got %d", len(eps))
 	}
@@ -344,7 +675,7 @@ func TestBuildEndpoints_BothRecordTypes(t *testing.T) {
 }
 
 func TestBuildEndpoints_OnlyIPv4(t *testing.T) {
-	eps := buildEndpoints([]string{"vm.example.com"}, []string{"10.0.0.1"}, nil, defaultTTL)
+	eps := buildEndpoints([]string{"vm.example.com"}, []string{"10.0.0.1"}, nil, defaultTTL, nil, nil, "")
 	if len(eps) != 1 {
 		t.Fatalf("expected 1 endpoint, got %d", len(eps))
 	}
@@ -354,7 +685,7 @@ func TestBuildEndpoints_OnlyIPv4(t *testing.T) {
 }
 
 func TestBuildEndpoints_OnlyIPv6(t *testing.T) {
-	eps := buildEndpoints([]string{"vm.example.com"}, nil, []string{"::1"}, defaultTTL)
+	eps := buildEndpoints([]string{"vm.example.com"}, nil, []string{"::1"}, defaultTTL, nil, nil, "")
 	if len(eps) != 1 {
 		t.Fatalf("expected 1 endpoint, got %d", len(eps))
 	}
@@ -366,7 +697,7 @@ func TestBuildEndpoints_OnlyIPv6(t *testing.T) {
 func TestBuildEndpoints_MultipleHostnames(t *testing.T) {
 	hostnames := []string{"vm.example.com", "vm2.example.com"}
 	ipv4 := []string{"10.0.0.1"}
-	eps := buildEndpoints(hostnames, ipv4, nil, defaultTTL)
+	eps := buildEndpoints(hostnames, ipv4, nil, defaultTTL, nil, nil, "")
 	// 1 A record per hostname
 	if len(eps) != 2 {
 		t.Fatalf("expected 2 endpoints, got %d", len(eps))
@@ -380,7 +711,7 @@ func TestBuildEndpoints_MultipleHostnames(t *testing.T) {
 }
 
 func TestBuildEndpoints_TTL(t *testing.T) {
-	eps := buildEndpoints([]string{"vm.example.com"}, []string{"1.2.3.4"}, nil, 120)
+	eps := buildEndpoints([]string{"vm.example.com"}, []string{"1.2.3.4"}, nil, 120, nil, nil, "")
 	if len(eps) != 1 {
 		t.Fatalf("expected 1 endpoint, got %d", len(eps))
 	}
@@ -388,3 +719,1294 @@ func TestBuildEndpoints_TTL(t *testing.T) {
 		t.Errorf("expected TTL=120, got %d", eps[0].RecordTTL)
 	}
 }
+
+func TestBuildEndpoints_DeterministicOrdering(t *testing.T) {
+	hostnames := []string{"vm2.example.com", "vm.example.com"}
+	first := buildEndpoints(hostnames,
+		[]string{"10.0.0.2", "10.0.0.1"},
+		[]string{"2001:db8::2", "2001:db8::1"},
+		defaultTTL, nil, nil, "")
+	second := buildEndpoints([]string{"vm.example.com", "vm2.example.com"},
+		[]string{"10.0.0.1", "10.0.0.2"},
+		[]string{"2001:db8::1", "2001:db8::2"},
+		defaultTTL, nil, nil, "")
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("expected identical endpoints for reordered input, got %v and %v", first, second)
+	}
+
+	want := []struct{ recordType, dnsName string }{
+		{"A", "vm.example.com"},
+		{"A", "vm2.example.com"},
+		{"AAAA", "vm.example.com"},
+		{"AAAA", "vm2.example.com"},
+	}
+	if len(first) != len(want) {
+		t.Fatalf("expected %d endpoints, got %d", len(want), len(first))
+	}
+	for i, w := range want {
+		if first[i].RecordType != w.recordType || first[i].DNSName != w.dnsName {
+			t.Errorf("endpoint[%d] = %s/%s, want %s/%s", i, first[i].RecordType, first[i].DNSName, w.recordType, w.dnsName)
+		}
+	}
+	if first[0].Targets[0] != "10.0.0.1" || first[0].Targets[1] != "10.0.0.2" {
+		t.Errorf("expected sorted A targets, got %v", first[0].Targets)
+	}
+}
+
+func TestBuildEndpoints_DoesNotMutateInput(t *testing.T) {
+	ipv4 := []string{"10.0.0.2", "10.0.0.1"}
+	buildEndpoints([]string{"vm.example.com"}, ipv4, nil, defaultTTL, nil, nil, "")
+	if ipv4[0] != "10.0.0.2" {
+		t.Errorf("expected input slice to be left untouched, got %v", ipv4)
+	}
+}
+
+// BenchmarkBuildEndpoints builds endpoints from the same addresses in a
+// rotating order, as reported by VMI interfaces across reconciles, and reports
+// how many builds differ from the first one and would cause an Update.
+func BenchmarkBuildEndpoints(b *testing.B) {
+	hostnames := []string{"vm.example.com", "vm.internal.example.com"}
+	ipv4 := []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}
+	ipv6 := []string{"2001:db8::2", "2001:db8::1"}
+	initial := buildEndpoints(hostnames, ipv4, ipv6, defaultTTL, nil, nil, "")
+
+	updates := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v4 := append(slices.Clone(ipv4[i%len(ipv4):]), ipv4[:i%len(ipv4)]...)
+		v6 := append(slices.Clone(ipv6[i%len(ipv6):]), ipv6[:i%len(ipv6)]...)
+		if !reflect.DeepEqual(buildEndpoints(hostnames, v4, v6, defaultTTL, nil, nil, ""), initial) {
+			updates++
+		}
+	}
+	b.ReportMetric(float64(updates)/float64(b.N), "updates/op")
+}
+
+// ---------- Reconcile table ----------
+
+func TestReconcile_Table(t *testing.T) {
+	multus := func(ip string) kubevirtv1.VirtualMachineInstanceNetworkInterface {
+		return kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: ip, InfoSource: multusInfoSource}
+	}
+	aRecord := func(hostname, target string) []*dnsendpointv1alpha1.Endpoint {
+		return []*dnsendpointv1alpha1.Endpoint{{
+			DNSName:    hostname,
+			RecordType: "A",
+			Targets:    dnsendpointv1alpha1.Targets{target},
+			RecordTTL:  defaultTTL,
+		}}
+	}
+	existing := func(owner string, endpoints []*dnsendpointv1alpha1.Endpoint) *dnsendpointv1alpha1.DNSEndpoint {
+		ep := newOwnedEndpoint("default", owner, owner+"-uid")
+		ep.Spec.Endpoints = endpoints
+		return ep
+	}
+	other := newTestVMI("other", map[string]string{annotationHostname: "vm.example.com"}, multus("10.0.0.2"))
+
+	tests := []struct {
+		name     string
+		vmi      *kubevirtv1.VirtualMachineInstance
+		existing *dnsendpointv1alpha1.DNSEndpoint
+		objs     []client.Object
+		// primed reconciles once before the reconcile under test.
+		primed bool
+		// want is the expected spec of DNSEndpoint vm; nil means there is none.
+		want []*dnsendpointv1alpha1.Endpoint
+		// wantWrite reports whether the reconcile under test changes DNSEndpoint vm.
+		wantWrite bool
+	}{
+		{
+			name:      "create",
+			vmi:       newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"}, multus("10.0.0.1")),
+			want:      aRecord("vm.example.com", "10.0.0.1"),
+			wantWrite: true,
+		},
+		{
+			name:      "update",
+			vmi:       newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"}, multus("10.0.0.1")),
+			existing:  existing("vm", aRecord("vm.example.com", "10.0.0.9")),
+			want:      aRecord("vm.example.com", "10.0.0.1"),
+			wantWrite: true,
+		},
+		{
+			name:      "delete",
+			vmi:       newTestVMI("vm", nil, multus("10.0.0.1")),
+			existing:  existing("vm", aRecord("vm.example.com", "10.0.0.1")),
+			wantWrite: true,
+		},
+		{
+			name:   "no-op",
+			vmi:    newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"}, multus("10.0.0.1")),
+			primed: true,
+			want:   aRecord("vm.example.com", "10.0.0.1"),
+		},
+		{
+			name: "no IPs",
+			vmi:  newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"}),
+		},
+		{
+			name: "conflicting hostname",
+			vmi:  newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"}, multus("10.0.0.1")),
+			objs: []client.Object{other.DeepCopy(), existing("other", aRecord("vm.example.com", "10.0.0.2"))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.vmi.Finalizers = []string{DefaultFinalizerName}
+			objs := append([]client.Object{tt.vmi}, tt.objs...)
+			if tt.existing != nil {
+				objs = append(objs, tt.existing)
+			}
+			r := newTestReconciler(t, objs...)
+			// A fixed clock keeps the no-op case independent of second
+			// boundaries; it moves on between the two reconciles like a resync.
+			now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+			r.now = func() time.Time { return now }
+			if tt.primed {
+				reconcileVMI(t, r, "vm")
+				now = now.Add(time.Minute)
+			}
+			key := client.ObjectKey{Namespace: "default", Name: "vm"}
+			before := &dnsendpointv1alpha1.DNSEndpoint{}
+			_ = r.Get(context.Background(), key, before)
+
+			reconcileVMI(t, r, "vm")
+
+			got := &dnsendpointv1alpha1.DNSEndpoint{}
+			err := r.Get(context.Background(), key, got)
+			if tt.want == nil {
+				if !apierrors.IsNotFound(err) {
+					t.Fatalf("expected no DNSEndpoint, got %v (err=%v)", got.Spec.Endpoints, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("expected a DNSEndpoint: %v", err)
+				}
+				if !reflect.DeepEqual(got.Spec.Endpoints, tt.want) {
+					t.Errorf("endpoints = %v, want %v", got.Spec.Endpoints, tt.want)
+				}
+			}
+			if written := got.ResourceVersion != before.ResourceVersion; written != tt.wantWrite {
+				t.Errorf("DNSEndpoint written = %v, want %v", written, tt.wantWrite)
+			}
+		})
+	}
+}
+
+// ---------- events ----------
+
+func TestReconcile_EmitsLifecycleEvents(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+	assertEvents(t, recordedEvents(r), "Normal "+eventReasonEndpointCreated)
+
+	// A second reconcile with unchanged input must not emit anything.
+	reconcileVMI(t, r, "vm")
+	assertEvents(t, recordedEvents(r))
+
+	vmi = &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, vmi); err != nil {
+		t.Fatal(err)
+	}
+	vmi.Status.Interfaces[0].IP = "10.0.0.2"
+	if err := r.Update(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+	assertEvents(t, recordedEvents(r), "Normal "+eventReasonEndpointUpdated)
+
+	delete(vmi.Annotations, annotationHostname)
+	if err := r.Update(context.Background(), vmi); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+	assertEvents(t, recordedEvents(r), "Normal "+eventReasonEndpointDeleted)
+}
+
+func TestReconcile_EmitsIPsNotYetAvailableWarning(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+	assertEvents(t, recordedEvents(r), "Warning "+eventReasonIPsNotYetAvailable)
+}
+
+func TestReconcile_IPsNotYetAvailableEventsAreIdentical(t *testing.T) {
+	// The event correlator only aggregates repeats that share type, reason and
+	// message, so a VMI waiting for IPs must not vary any of them.
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+	first := recordedEvents(r)
+	for i := 0; i < 3; i++ {
+		reconcileVMI(t, r, "vm")
+		if got := recordedEvents(r); !reflect.DeepEqual(got, first) {
+			t.Fatalf("reconcile %d: expected the same events %v, got %v", i+2, first, got)
+		}
+	}
+}
+
+func TestReconcile_WarnsOnceAboutUppercaseHostnames(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "VM.Example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+	assertEvents(t, recordedEvents(r), "Warning "+eventReasonHostnameNotLowercase, "Normal "+eventReasonEndpointCreated)
+	if got := getEndpoint(t, r, "vm").Spec.Endpoints[0].DNSName; got != "vm.example.com" {
+		t.Errorf("expected a lowercased DNSName, got %q", got)
+	}
+
+	reconcileVMI(t, r, "vm")
+	assertEvents(t, recordedEvents(r))
+}
+
+func TestReconcile_PhaseGate(t *testing.T) {
+	tests := []struct {
+		phase   kubevirtv1.VirtualMachineInstancePhase
+		publish bool
+	}{
+		{kubevirtv1.Pending, false},
+		{kubevirtv1.Running, true},
+		{kubevirtv1.Failed, false},
+	}
+	for _, tt := range tests {
+		vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+			kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+		vmi.Status.Phase = tt.phase
+		r := newTestReconciler(t, vmi)
+
+		reconcileVMI(t, r, "vm")
+
+		err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, &dnsendpointv1alpha1.DNSEndpoint{})
+		if tt.publish && err != nil {
+			t.Errorf("%s: expected a DNSEndpoint, got %v", tt.phase, err)
+		}
+		if !tt.publish && !apierrors.IsNotFound(err) {
+			t.Errorf("%s: expected no DNSEndpoint, got %v", tt.phase, err)
+		}
+	}
+}
+
+func TestReconcile_PhaseWaitRequeuesAndWarns(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	vmi.Status.Phase = kubevirtv1.Scheduled
+	r := newTestReconciler(t, vmi)
+	r.PhaseRetryInterval = 10 * time.Second
+	r.PhaseWarningThreshold = 2
+
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != 10*time.Second {
+		t.Errorf("expected a 10s requeue, got %s", res.RequeueAfter)
+	}
+	assertEvents(t, recordedEvents(r))
+
+	reconcileVMI(t, r, "vm")
+	assertEvents(t, recordedEvents(r), "Warning "+eventReasonVMINotRunning)
+
+	// The warning is only emitted once the threshold is reached, not on every later reconcile.
+	reconcileVMI(t, r, "vm")
+	assertEvents(t, recordedEvents(r))
+}
+
+func TestReconcile_PhaseWaitNoRequeueForFinalPhase(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	vmi.Status.Phase = kubevirtv1.Succeeded
+	r := newTestReconciler(t, vmi)
+	r.PhaseRetryInterval = 10 * time.Second
+
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != 0 {
+		t.Errorf("expected no requeue for a finished VMI, got %s", res.RequeueAfter)
+	}
+}
+
+func TestReconcile_PhaseGateKeepsExistingEndpoint(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	reconcileVMI(t, r, "vm")
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	got.Status.Phase = kubevirtv1.Failed
+	got.Status.Interfaces = nil
+	if err := r.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+
+	if ep := getEndpoint(t, r, "vm"); len(ep.Spec.Endpoints) != 1 {
+		t.Errorf("expected the existing DNSEndpoint to be left alone, got %v", ep.Spec.Endpoints)
+	}
+}
+
+func TestIsVMIReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []kubevirtv1.VirtualMachineInstanceCondition
+		want       bool
+	}{
+		{"no conditions", nil, false},
+		{"ready true", []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionTrue}}, true},
+		{"ready false", []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionFalse}}, false},
+		{"ready unknown", []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionUnknown}}, false},
+		{"other condition only", []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstanceAgentConnected, Status: corev1.ConditionTrue}}, false},
+	}
+	for _, tt := range tests {
+		vmi := newTestVMI("vm", nil)
+		vmi.Status.Conditions = tt.conditions
+		if got := isVMIReady(vmi); got != tt.want {
+			t.Errorf("%s: isVMIReady = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReconcile_ReadinessGate(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	vmi.Status.Conditions[0].Status = corev1.ConditionFalse
+	r := newTestReconciler(t, vmi)
+	r.PhaseRetryInterval = 10 * time.Second
+
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != 10*time.Second {
+		t.Errorf("expected a 10s requeue for a VMI that is not ready, got %s", res.RequeueAfter)
+	}
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("expected no DNSEndpoint before the VMI is ready, got %v", err)
+	}
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	got.Status.Conditions[0].Status = corev1.ConditionTrue
+	if err := r.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+	if ep := getEndpoint(t, r, "vm"); len(ep.Spec.Endpoints) != 1 {
+		t.Errorf("expected a DNSEndpoint once the VMI is ready, got %v", ep.Spec.Endpoints)
+	}
+}
+
+func TestVMIChangedPredicate_ReadyChange(t *testing.T) {
+	r := newTestReconciler(t)
+	oldVMI := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"})
+	oldVMI.ResourceVersion = "100"
+	oldVMI.Status.Conditions[0].Status = corev1.ConditionFalse
+
+	ready := oldVMI.DeepCopy()
+	ready.ResourceVersion = "101"
+	ready.Status.Conditions[0].Status = corev1.ConditionTrue
+	if !r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldVMI, ObjectNew: ready}) {
+		t.Error("expected the VMI becoming ready to pass the predicate")
+	}
+}
+
+func TestReconcile_WarnsOnZeroTTL(t *testing.T) {
+	tests := []struct {
+		name  string
+		ttl   string
+		set   bool
+		warns bool
+	}{
+		{"zero", "0", true, true},
+		{"zero duration", "0s", true, true},
+		{"absent", "", false, false},
+		{"negative", "-1", true, false},
+		{"valid", "60", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{annotationHostname: "vm.example.com"}
+			if tt.set {
+				annotations[annotationTTL] = tt.ttl
+			}
+			vmi := newTestVMI("vm", annotations,
+				kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+			r := newTestReconciler(t, vmi)
+			reconcileVMI(t, r, "vm")
+
+			want := []string{"Normal " + eventReasonEndpointCreated}
+			if tt.warns {
+				want = append([]string{"Warning " + eventReasonZeroTTLIgnored}, want...)
+			}
+			assertEvents(t, recordedEvents(r), want...)
+			if tt.warns && getEndpoint(t, r, "vm").Spec.Endpoints[0].RecordTTL != defaultTTL {
+				t.Errorf("expected the default TTL for a zero TTL annotation")
+			}
+		})
+	}
+}
+
+// assertEvents checks that each recorded event starts with the matching "<type> <reason>" prefix.
+func assertEvents(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]+" ") {
+			t.Errorf("event[%d] = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+}
+
+// ---------- deleteEndpointIfExists ----------
+
+// newLabeledEndpoint returns a DNSEndpoint at key owned by the VMI and
+// labeled with its name.
+func newLabeledEndpoint(key client.ObjectKey, vmi *kubevirtv1.VirtualMachineInstance) *dnsendpointv1alpha1.DNSEndpoint {
+	ep := &dnsendpointv1alpha1.DNSEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	setCrossNamespaceOwner(ep, vmi)
+	setOwnerVMILabel(ep, vmi)
+	return ep
+}
+
+func TestDeleteEndpointIfExists_ByOwnerLabel(t *testing.T) {
+	vmi := newTestVMI("vm", nil)
+	other := newTestVMI("vm", nil)
+	other.Namespace, other.UID = "other", "other-uid"
+
+	tests := []struct {
+		name        string
+		owned, kept []client.ObjectKey
+	}{
+		{name: "none"},
+		{name: "one", owned: []client.ObjectKey{{Namespace: "default", Name: "default-vm-dns"}}},
+		{
+			name: "multiple",
+			owned: []client.ObjectKey{
+				{Namespace: "default", Name: "default-vm-dns"},
+				{Namespace: "dns", Name: "default-vm"},
+			},
+			kept: []client.ObjectKey{{Namespace: "dns", Name: "other-vm"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []client.Object
+			for _, key := range tt.owned {
+				objs = append(objs, newLabeledEndpoint(key, vmi))
+			}
+			for _, key := range tt.kept {
+				// Owned by a VMI of the same name in another namespace.
+				objs = append(objs, newLabeledEndpoint(key, other))
+			}
+			r := newTestReconciler(t, objs...)
+
+			if err := r.deleteEndpointIfExists(context.Background(), vmi); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range tt.owned {
+				if err := r.Get(context.Background(), key, &dnsendpointv1alpha1.DNSEndpoint{}); !apierrors.IsNotFound(err) {
+					t.Errorf("expected %s to be deleted, got err=%v", key, err)
+				}
+			}
+			for _, key := range tt.kept {
+				if err := r.Get(context.Background(), key, &dnsendpointv1alpha1.DNSEndpoint{}); err != nil {
+					t.Errorf("expected %s to be kept, got %v", key, err)
+				}
+			}
+			if events := recordedEvents(r); len(events) != len(tt.owned) {
+				t.Errorf("expected one event per deleted DNSEndpoint, got %v", events)
+			}
+		})
+	}
+}
+
+func TestReconcile_LabelsEndpointWithOwnerVMI(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	format, err := ParseEndpointNameFormat("{{ .Name }}-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.EndpointNameFormat = format
+	reconcileVMI(t, r, "vm")
+
+	ep := getEndpoint(t, r, "vm-dns")
+	if got := ep.Labels[labelOwnerVMI]; got != "vm" {
+		t.Errorf("label %s = %q, want %q", labelOwnerVMI, got, "vm")
+	}
+}
+
+// ---------- finalizer ----------
+
+func TestReconcile_AddsFinalizer(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, got); err != nil {
+		t.Fatal(err)
+	}
+	if !controllerutil.ContainsFinalizer(got, DefaultFinalizerName) {
+		t.Errorf("expected finalizer %q, got %v", DefaultFinalizerName, got.Finalizers)
+	}
+}
+
+func TestReconcile_DeletionRemovesEndpointAndFinalizer(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	reconcileVMI(t, r, "vm")
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, got); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+
+	reconcileVMI(t, r, "vm")
+
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, ep)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected DNSEndpoint to be deleted, got err=%v", err)
+	}
+	err = r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, got)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected VMI to be gone once the finalizer was released, got err=%v finalizers=%v", err, got.Finalizers)
+	}
+}
+
+func TestReconcile_DeletionWithEndpointAlreadyGone(t *testing.T) {
+	now := metav1.Now()
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	vmi.Finalizers = []string{DefaultFinalizerName}
+	vmi.DeletionTimestamp = &now
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm"}, got)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected VMI to be gone once the finalizer was released, got err=%v finalizers=%v", err, got.Finalizers)
+	}
+	assertEvents(t, recordedEvents(r))
+}
+
+// ---------- rate limiter ----------
+
+func TestNewRateLimiter_ExponentialBackoff(t *testing.T) {
+	rl := NewRateLimiter(10*time.Millisecond, 40*time.Millisecond, 1000, 1000)
+	item := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "vm"}}
+
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond}
+	for i, w := range want {
+		if got := rl.When(item); got != w {
+			t.Errorf("attempt %d: When() = %v, want %v", i, got, w)
+		}
+	}
+
+	rl.Forget(item)
+	if got := rl.When(item); got != 10*time.Millisecond {
+		t.Errorf("expected delay to reset to base after Forget, got %v", got)
+	}
+}
+
+// ---------- manager setup ----------
+
+func TestSetupWithManager_MaxConcurrentReconciles(t *testing.T) {
+	// The manager is never started, so an unreachable host is fine.
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "https://127.0.0.1:1"}, ctrl.Options{
+		Scheme:  newTestScheme(t),
+		Metrics: metricsserver.Options{BindAddress: "0"},
+		// Registering the field indexes needs REST mappings, which
+		// would otherwise be discovered from the unreachable host.
+		MapperProvider: func(*rest.Config, *http.Client) (meta.RESTMapper, error) {
+			m := meta.NewDefaultRESTMapper(nil)
+			m.Add(kubevirtv1.GroupVersion.WithKind("VirtualMachineInstance"), meta.RESTScopeNamespace)
+			m.Add(schema.GroupVersionKind{Group: "externaldns.k8s.io", Version: "v1alpha1", Kind: "DNSEndpoint"}, meta.RESTScopeNamespace)
+			return m, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	r := &VirtualMachineInstanceReconciler{
+		Client:                  mgr.GetClient(),
+		Scheme:                  mgr.GetScheme(),
+		Recorder:                record.NewFakeRecorder(10),
+		MaxConcurrentReconciles: 4,
+	}
+	if err := r.SetupWithManager(mgr); err != nil {
+		t.Fatalf("SetupWithManager with MaxConcurrentReconciles=4: %v", err)
+	}
+}
+
+// ---------- vmiChangedPredicate ----------
+
+func TestVMIChangedPredicate_Resync(t *testing.T) {
+	r := newTestReconciler(t)
+	oldVMI := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"})
+	oldVMI.ResourceVersion = "100"
+
+	resync := oldVMI.DeepCopy()
+	if !r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldVMI, ObjectNew: resync}) {
+		t.Error("expected a resync (same resourceVersion) to pass the predicate")
+	}
+
+	unrelated := oldVMI.DeepCopy()
+	unrelated.ResourceVersion = "101"
+	unrelated.Labels = map[string]string{"unrelated": "change"}
+	if r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldVMI, ObjectNew: unrelated}) {
+		t.Error("expected an unrelated change to be filtered out")
+	}
+}
+
+func TestVMIChangedPredicate_PhaseChange(t *testing.T) {
+	r := newTestReconciler(t)
+	oldVMI := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"})
+	oldVMI.ResourceVersion = "100"
+	oldVMI.Status.Phase = kubevirtv1.Scheduled
+
+	running := oldVMI.DeepCopy()
+	running.ResourceVersion = "101"
+	running.Status.Phase = kubevirtv1.Running
+	if !r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldVMI, ObjectNew: running}) {
+		t.Error("expected a phase change to pass the predicate")
+	}
+}
+
+func TestVMIChangedPredicate_ForceReconcile(t *testing.T) {
+	r := newTestReconciler(t)
+	oldVMI := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"})
+	oldVMI.ResourceVersion = "100"
+
+	forced := oldVMI.DeepCopy()
+	forced.ResourceVersion = "101"
+	forced.Annotations[annotationForceReconcile] = "2026-10-16T12:00:00Z"
+	if !r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldVMI, ObjectNew: forced}) {
+		t.Error("expected the force-reconcile annotation to pass the predicate")
+	}
+
+	// Removing the annotation must not trigger another reconcile.
+	cleared := oldVMI.DeepCopy()
+	cleared.ResourceVersion = "102"
+	if r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: forced, ObjectNew: cleared}) {
+		t.Error("expected removal of the force-reconcile annotation to be filtered out")
+	}
+}
+
+func TestVMIChangedPredicate_TTLChange(t *testing.T) {
+	r := newTestReconciler(t)
+	oldVMI := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com", annotationTTL: "300"})
+	oldVMI.ResourceVersion = "100"
+
+	newVMI := oldVMI.DeepCopy()
+	newVMI.ResourceVersion = "101"
+	newVMI.Annotations[annotationTTL] = "60"
+	if !r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldVMI, ObjectNew: newVMI}) {
+		t.Error("expected a TTL annotation change to pass the predicate")
+	}
+}
+
+func TestVMIChangedPredicate_LabelChange(t *testing.T) {
+	r := newTestReconciler(t)
+	oldVMI := newTestVMI("vm1", map[string]string{annotationHostname: "{{ .Labels.app }}.example.com"})
+	oldVMI.ResourceVersion = "100"
+	oldVMI.Labels = map[string]string{"app": "web"}
+
+	newVMI := oldVMI.DeepCopy()
+	newVMI.ResourceVersion = "101"
+	newVMI.Labels["app"] = "api"
+	if r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldVMI, ObjectNew: newVMI}) {
+		t.Error("expected a label change to be filtered out without TemplateBased")
+	}
+
+	r.TemplateBased = true
+	if !r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldVMI, ObjectNew: newVMI}) {
+		t.Error("expected a label change to pass the predicate with TemplateBased")
+	}
+}
+
+// ---------- paused ----------
+
+func TestReconcile_PausedLeavesEndpointUntouched(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	ctx := context.Background()
+	reconcileVMI(t, r, "vm")
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	got.Annotations[annotationPaused] = "true"
+	got.Status.Interfaces[0].IP = "10.0.0.2"
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+	if targets := getEndpoint(t, r, "vm").Spec.Endpoints[0].Targets; targets[0] != "10.0.0.1" {
+		t.Errorf("expected the paused DNSEndpoint to keep 10.0.0.1, got %v", targets)
+	}
+
+	// Unpausing publishes the new address.
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	delete(got.Annotations, annotationPaused)
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	reconcileVMI(t, r, "vm")
+	if targets := getEndpoint(t, r, "vm").Spec.Endpoints[0].Targets; targets[0] != "10.0.0.2" {
+		t.Errorf("expected the unpaused DNSEndpoint to target 10.0.0.2, got %v", targets)
+	}
+}
+
+func TestVMIChangedPredicate_Unpause(t *testing.T) {
+	r := newTestReconciler(t)
+	paused := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com", annotationPaused: "true"})
+	paused.ResourceVersion = "100"
+
+	unpaused := paused.DeepCopy()
+	unpaused.ResourceVersion = "101"
+	delete(unpaused.Annotations, annotationPaused)
+	if !r.vmiChangedPredicate().Update(event.UpdateEvent{ObjectOld: paused, ObjectNew: unpaused}) {
+		t.Error("expected unpausing to pass the predicate")
+	}
+}
+
+// ---------- force-reconcile ----------
+
+func TestReconcile_ForceReconcileRecreatesEndpointAndClearsAnnotation(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+	ctx := context.Background()
+	reconcileVMI(t, r, "vm")
+
+	// Simulate a manual deletion of the DNSEndpoint followed by a forced reconcile.
+	if err := r.Delete(ctx, getEndpoint(t, r, "vm")); err != nil {
+		t.Fatal(err)
+	}
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	got.Annotations[annotationForceReconcile] = "now"
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+
+	reconcileVMI(t, r, "vm")
+	getEndpoint(t, r, "vm")
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.Annotations[annotationForceReconcile]; ok {
+		t.Error("expected the force-reconcile annotation to be removed")
+	}
+
+	// A second reconcile without the annotation is a no-op.
+	rv := got.ResourceVersion
+	reconcileVMI(t, r, "vm")
+	if err := r.Get(ctx, client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ResourceVersion != rv {
+		t.Errorf("expected the VMI to be left untouched, resourceVersion %s -> %s", rv, got.ResourceVersion)
+	}
+}
+
+func TestReconcile_ForceReconcileKeptOnError(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com", annotationForceReconcile: "now"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	vmi.Finalizers = []string{DefaultFinalizerName}
+	s := newTestScheme(t)
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(vmi).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			return errors.New("boom")
+		},
+	}).Build()
+	r := &VirtualMachineInstanceReconciler{Client: c, Scheme: s, Recorder: record.NewFakeRecorder(100)}
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(vmi)}); err == nil {
+		t.Fatal("expected the reconcile to fail")
+	}
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Annotations[annotationForceReconcile] != "now" {
+		t.Error("expected the force-reconcile annotation to survive a failed reconcile")
+	}
+}
+
+// ---------- API round trips ----------
+
+func TestReconcile_ManyHostnamesWriteOneEndpoint(t *testing.T) {
+	hostnames := func(suffix string) string {
+		var names []string
+		for i := range 10 {
+			names = append(names, fmt.Sprintf("vm%d.%s", i, suffix))
+		}
+		return strings.Join(names, ",")
+	}
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: hostnames("example.com")},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	vmi.Finalizers = []string{DefaultFinalizerName}
+	writes := 0
+	countWrite := func(obj client.Object) {
+		if _, ok := obj.(*dnsendpointv1alpha1.DNSEndpoint); ok {
+			writes++
+		}
+	}
+	s := newTestScheme(t)
+	c := withHostnameIndex(fake.NewClientBuilder().WithScheme(s)).WithObjects(vmi).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			countWrite(obj)
+			return c.Create(ctx, obj, opts...)
+		},
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			countWrite(obj)
+			return c.Update(ctx, obj, opts...)
+		},
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			countWrite(obj)
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+	r := &VirtualMachineInstanceReconciler{Client: c, Scheme: s, Recorder: record.NewFakeRecorder(100)}
+
+	// All hostnames of a VMI are records of a single DNSEndpoint, so creating
+	// or changing them costs one write rather than one per hostname.
+	reconcileVMI(t, r, "vm")
+	if writes != 1 {
+		t.Errorf("expected 1 DNSEndpoint write to publish 10 hostnames, got %d", writes)
+	}
+
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	got.Annotations[annotationHostname] = hostnames("example.org")
+	if err := c.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	writes = 0
+	reconcileVMI(t, r, "vm")
+	if writes != 1 {
+		t.Errorf("expected 1 DNSEndpoint write to change 10 hostnames, got %d", writes)
+	}
+	if eps := getEndpoint(t, r, "vm").Spec.Endpoints; len(eps) != 10 {
+		t.Errorf("expected 10 endpoints, got %d", len(eps))
+	}
+}
+
+// ---------- reconcile timeout ----------
+
+func TestReconcile_Timeout(t *testing.T) {
+	s := newTestScheme(t)
+	c := fake.NewClientBuilder().WithScheme(s).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			<-ctx.Done() // simulate a hung API server
+			return ctx.Err()
+		},
+	}).Build()
+	r := &VirtualMachineInstanceReconciler{Client: c, Scheme: s, Recorder: record.NewFakeRecorder(10), ReconcileTimeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "vm"}})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Reconcile did not honour the timeout, took %s", elapsed)
+	}
+	if !errors.Is(err, errReconcileTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a retryable timeout error, got %v", err)
+	}
+}
+
+// ---------- deduplicateIPs ----------
+
+func TestDeduplicateIPs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{"duplicates", []string{"10.0.0.2", "10.0.0.1", "10.0.0.2", "10.0.0.1"}, []string{"10.0.0.2", "10.0.0.1"}},
+		{"all unique", []string{"10.0.0.1", "10.0.0.2"}, []string{"10.0.0.1", "10.0.0.2"}},
+		{"equivalent IPv6 forms", []string{"2001:db8::1", "2001:db8:0::1"}, []string{"2001:db8::1"}},
+		{"empty", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := deduplicateIPs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: deduplicateIPs(%v) = %v, want %v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExtractBestIPs_DeduplicatesAcrossInterfaces(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	vmi.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{
+		{IP: "10.0.0.5", InfoSource: multusInfoSource},
+		{IP: "10.0.0.5", InfoSource: multusInfoSource},
+		{IP: "10.0.0.6", InfoSource: multusInfoSource},
+	}
+	v4, _, _ := extractBestIPs(vmi, ipFilter{}, nil)
+	if !reflect.DeepEqual(v4, []string{"10.0.0.5", "10.0.0.6"}) {
+		t.Errorf("expected duplicates to be removed, got %v", v4)
+	}
+}
+
+// ---------- IP family flags ----------
+
+func TestReconcile_IPv4Only(t *testing.T) {
+	vmi := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.5", "2001:db8::5"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.IPv4Only = true
+
+	reconcileVMI(t, r, "vm1")
+
+	ep := getEndpoint(t, r, "vm1")
+	if len(ep.Spec.Endpoints) != 1 || ep.Spec.Endpoints[0].RecordType != "A" {
+		t.Errorf("expected only an A record, got %v", ep.Spec.Endpoints)
+	}
+}
+
+func TestReconcile_IPv6Only(t *testing.T) {
+	vmi := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.5", "2001:db8::5"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.IPv6Only = true
+
+	reconcileVMI(t, r, "vm1")
+
+	ep := getEndpoint(t, r, "vm1")
+	if len(ep.Spec.Endpoints) != 1 || ep.Spec.Endpoints[0].RecordType != "AAAA" {
+		t.Errorf("expected only an AAAA record, got %v", ep.Spec.Endpoints)
+	}
+}
+
+func TestReconcile_IPv6OnlyLinkLocalCreatesNothing(t *testing.T) {
+	vmi := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.5", "fe80::5"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+	r.IPv6Only = true
+
+	reconcileVMI(t, r, "vm1")
+
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "vm1"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected no DNSEndpoint, got err=%v", err)
+	}
+}
+
+// ---------- label propagation ----------
+
+func TestReconcile_PropagatesLabels(t *testing.T) {
+	vmi := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource})
+	vmi.Labels = map[string]string{"env": "prod", "controller-uid": "abc", "batch.kubernetes.io/controller-uid": "abc"}
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm1")
+
+	ep := getEndpoint(t, r, "vm1")
+	if ep.Labels["env"] != "prod" {
+		t.Errorf("expected env=prod on the DNSEndpoint, got %v", ep.Labels)
+	}
+	if _, ok := ep.Labels["controller-uid"]; ok {
+		t.Errorf("expected controller-uid to be skipped, got %v", ep.Labels)
+	}
+	if _, ok := ep.Labels["batch.kubernetes.io/controller-uid"]; ok {
+		t.Errorf("expected batch.kubernetes.io/controller-uid to be skipped, got %v", ep.Labels)
+	}
+
+	// Labels added to the endpoint by others survive; VMI label changes propagate.
+	ep.Labels["team"] = "dns"
+	if err := r.Update(context.Background(), ep); err != nil {
+		t.Fatal(err)
+	}
+	current := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(vmi), current); err != nil {
+		t.Fatal(err)
+	}
+	current.Labels["env"] = "staging"
+	if err := r.Update(context.Background(), current); err != nil {
+		t.Fatal(err)
+	}
+
+	reconcileVMI(t, r, "vm1")
+
+	ep = getEndpoint(t, r, "vm1")
+	if ep.Labels["env"] != "staging" || ep.Labels["team"] != "dns" {
+		t.Errorf("expected env=staging and team=dns, got %v", ep.Labels)
+	}
+}
+
+// ---------- annotation propagation ----------
+
+func TestCopyAnnotations(t *testing.T) {
+	vmiAnnotations := map[string]string{
+		"cost-center":        "cc-42",
+		"owner":              "team-a",
+		"empty":              "",
+		annotationTTL:        "60",
+		annotationConditions: "[]",
+	}
+	tests := []struct {
+		name string
+		keys string
+		want map[string]string
+	}{
+		{"single key", "cost-center", map[string]string{"cost-center": "cc-42"}},
+		{"multiple keys", "cost-center, owner", map[string]string{"cost-center": "cc-42", "owner": "team-a"}},
+		{"missing key ignored", "cost-center,missing", map[string]string{"cost-center": "cc-42"}},
+		{"empty value", "empty", map[string]string{"empty": ""}},
+		{"managed keys skipped", annotationTTL + "," + annotationConditions, nil},
+		{"no keys", "", nil},
+	}
+	for _, tt := range tests {
+		ep := &dnsendpointv1alpha1.DNSEndpoint{}
+		copyAnnotations(ep, vmiAnnotations, tt.keys, DefaultSettings())
+		if !reflect.DeepEqual(ep.Annotations, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, ep.Annotations, tt.want)
+		}
+	}
+}
+
+func TestReconcile_PropagatesAnnotations(t *testing.T) {
+	vmi := newTestVMI("vm1", map[string]string{
+		annotationHostname:             "vm1.example.com",
+		annotationPropagateAnnotations: "cost-center",
+		"cost-center":                  "cc-42",
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm1")
+
+	ep := getEndpoint(t, r, "vm1")
+	if ep.Annotations["cost-center"] != "cc-42" {
+		t.Errorf("expected cost-center to be copied, got %v", ep.Annotations)
+	}
+	if _, ok := ep.Annotations[annotationConditions]; !ok {
+		t.Errorf("expected the conditions annotation to remain, got %v", ep.Annotations)
+	}
+}
+
+// ---------- per-hostname TTL ----------
+
+func TestParsePerHostnameTTL(t *testing.T) {
+	ttls, err := parsePerHostnameTTL(map[string]string{
+		annotationTTL:                     "300",
+		annotationTTL + "-a.example.com":  "60",
+		annotationTTL + "-b.example.com.": " 120 ",
+		annotationTTL + "-c.example.com":  "soon",
+		annotationTTL + "-d.example.com":  "",
+		annotationTTL + "-E.Example.com.": "90",
+		annotationTTLA:                    "30",
+		annotationHostname:                "a.example.com",
+	}, DefaultSettings())
+	if !errors.Is(err, errInvalidAnnotation) {
+		t.Errorf("expected errInvalidAnnotation for the invalid value, got %v", err)
+	}
+	want := map[string]dnsendpointv1alpha1.TTL{"a.example.com": 60, "b.example.com": 120, "e.example.com": 90}
+	if !reflect.DeepEqual(ttls, want) {
+		t.Errorf("got %v, want %v", ttls, want)
+	}
+}
+
+func TestReconcile_PerHostnameTTLMixedCase(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{
+		annotationHostname:                 "vm.example.com",
+		annotationTTL + "-VM.Example.com.": "45",
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.1"}, InfoSource: guestAgentInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	if got := getEndpoint(t, r, "vm").Spec.Endpoints[0].RecordTTL; got != 45 {
+		t.Errorf("TTL = %d, want the per-hostname TTL 45 regardless of case", got)
+	}
+}
+
+func TestBuildEndpoints_PerHostnameTTL(t *testing.T) {
+	eps := buildEndpoints([]string{"a.example.com", "b.example.com."}, []string{"10.0.0.1"}, []string{"2001:db8::1"}, 300,
+		map[string]dnsendpointv1alpha1.TTL{"b.example.com": 30}, nil, "")
+	for _, ep := range eps {
+		want := dnsendpointv1alpha1.TTL(300)
+		if ep.DNSName == "b.example.com." {
+			want = 30
+		}
+		if ep.RecordTTL != want {
+			t.Errorf("%s %s: TTL = %d, want %d", ep.RecordType, ep.DNSName, ep.RecordTTL, want)
+		}
+	}
+}
+
+// ---------- no-IP requeue ----------
+
+func TestReconcile_NoIPsRequeuesWithBackoff(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	r := newTestReconciler(t, vmi)
+	r.NoIPRetryInterval = 15 * time.Second
+	r.NoIPRetryMaxInterval = 50 * time.Second
+
+	for i, want := range []time.Duration{15 * time.Second, 30 * time.Second, 50 * time.Second, 50 * time.Second} {
+		if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != want {
+			t.Errorf("attempt %d: RequeueAfter = %s, want %s", i+1, res.RequeueAfter, want)
+		}
+	}
+
+	// Once IPs appear the attempt count is reset.
+	got := &kubevirtv1.VirtualMachineInstance{}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(vmi), got); err != nil {
+		t.Fatal(err)
+	}
+	got.Status.Interfaces = []kubevirtv1.VirtualMachineInstanceNetworkInterface{{IP: "10.0.0.1", InfoSource: multusInfoSource}}
+	if err := r.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != 0 {
+		t.Errorf("expected no requeue once IPs are available, got %s", res.RequeueAfter)
+	}
+	if _, ok := r.noIPAttempts.Load(client.ObjectKeyFromObject(vmi)); ok {
+		t.Error("expected the no-IP attempt count to be reset")
+	}
+}
+
+func TestReconcile_NoIPsWithoutRetryInterval(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"})
+	r := newTestReconciler(t, vmi)
+
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != 0 {
+		t.Errorf("expected no requeue with a zero NoIPRetryInterval, got %s", res.RequeueAfter)
+	}
+}
+
+// ---------- debounce ----------
+
+func TestReconcile_DebounceCollapsesRapidUpdates(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	var endpointWrites int
+	s := newTestScheme(t)
+	c := withHostnameIndex(fake.NewClientBuilder().WithScheme(s)).WithObjects(vmi).WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if _, ok := obj.(*dnsendpointv1alpha1.DNSEndpoint); ok {
+				endpointWrites++
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+	r := &VirtualMachineInstanceReconciler{Client: c, Scheme: s, Recorder: record.NewFakeRecorder(100), DebounceWindow: 2 * time.Second}
+	ctx := context.Background()
+	key := client.ObjectKeyFromObject(vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	// A script rewrites the hostname several times in quick succession.
+	for _, hostname := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		got := &kubevirtv1.VirtualMachineInstance{}
+		if err := r.Get(ctx, key, got); err != nil {
+			t.Fatal(err)
+		}
+		got.Annotations[annotationHostname] = hostname
+		if err := r.Update(ctx, got); err != nil {
+			t.Fatal(err)
+		}
+		res := reconcileVMI(t, r, "vm")
+		if res.RequeueAfter <= 0 || res.RequeueAfter > 2*time.Second {
+			t.Errorf("expected the reconcile to be debounced, got RequeueAfter=%s", res.RequeueAfter)
+		}
+	}
+	if endpointWrites != 0 {
+		t.Fatalf("expected no DNSEndpoint writes inside the debounce window, got %d", endpointWrites)
+	}
+
+	// Once the window has passed, the latest hostname is written once.
+	r.lastReconciled.Store(key, time.Now().Add(-3*time.Second))
+	if res := reconcileVMI(t, r, "vm"); res.RequeueAfter != 0 {
+		t.Errorf("expected no requeue after the window, got %s", res.RequeueAfter)
+	}
+	if endpointWrites != 1 {
+		t.Errorf("expected a single DNSEndpoint write, got %d", endpointWrites)
+	}
+	if got := getEndpoint(t, r, "vm").Spec.Endpoints[0].DNSName; got != "c.example.com" {
+		t.Errorf("expected the last hostname to be published, got %q", got)
+	}
+}
+
+func TestReconcile_CoalesceReducesAPICalls(t *testing.T) {
+	// runBurst feeds ten watch events for one VMI, 10ms apart, through a work
+	// queue to a single worker and returns the number of API calls made.
+	runBurst := func(window time.Duration) int {
+		vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com"},
+			kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+		var calls int
+		s := newTestScheme(t)
+		c := withHostnameIndex(fake.NewClientBuilder().WithScheme(s)).WithObjects(vmi).WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				calls++
+				return c.Get(ctx, key, obj, opts...)
+			},
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				calls++
+				return c.List(ctx, list, opts...)
+			},
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				calls++
+				return c.Create(ctx, obj, opts...)
+			},
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				calls++
+				return c.Update(ctx, obj, opts...)
+			},
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				calls++
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+		r := &VirtualMachineInstanceReconciler{Client: c, Scheme: s, Recorder: record.NewFakeRecorder(1000), CoalesceWindow: window}
+
+		queue := workqueue.NewTyped[reconcile.Request]()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				req, shutdown := queue.Get()
+				if shutdown {
+					return
+				}
+				if _, err := r.Reconcile(context.Background(), req); err != nil {
+					t.Errorf("Reconcile returned error: %v", err)
+				}
+				queue.Done(req)
+			}
+		}()
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(vmi)}
+		for range 10 {
+			queue.Add(req)
+			time.Sleep(10 * time.Millisecond)
+		}
+		queue.ShutDownWithDrain()
+		<-done
+		return calls
+	}
+
+	plain := runBurst(0)
+	coalesced := runBurst(100 * time.Millisecond)
+	if coalesced >= plain {
+		t.Errorf("expected fewer API calls with a coalesce window, got %d with and %d without", coalesced, plain)
+	}
+}
@@ -0,0 +1,254 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// VirtualMachineInstanceReplicaSetReconciler publishes one DNSEndpoint per
+// annotated VirtualMachineInstanceReplicaSet, targeting the IPs of all its
+// ready replicas.
+type VirtualMachineInstanceReplicaSetReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// Config supplies runtime-tunable settings. When nil, DefaultSettings are used.
+	Config *ControllerConfig
+	// PublicIPsOnly drops RFC 1918, RFC 4193 and loopback addresses.
+	PublicIPsOnly bool
+	// IPSourcePriority lists infoSource names in the order they are tried.
+	// Nil uses DefaultIPSourcePriority.
+	IPSourcePriority []string
+	// IPv4Only suppresses AAAA records; IPv6Only suppresses A records.
+	IPv4Only bool
+	IPv6Only bool
+	// ZoneAllowlist, when non-empty, skips hostnames outside these zones;
+	// ZoneDenylist skips hostnames inside them.
+	ZoneAllowlist []string
+	ZoneDenylist  []string
+	// MinTTL and MaxTTL bound every published TTL. Zero leaves that side
+	// unbounded.
+	MinTTL dnsendpointv1alpha1.TTL
+	MaxTTL dnsendpointv1alpha1.TTL
+	// RejectWildcards skips wildcard hostnames such as *.example.com.
+	RejectWildcards bool
+	// ExpandShortHostnames expands single-label hostnames to
+	// <name>.<namespace>.svc.<ClusterDomain>.
+	ExpandShortHostnames bool
+	// ClusterDomain is the domain short hostnames are expanded under. Empty
+	// uses DefaultClusterDomain.
+	ClusterDomain string
+	// DryRun reports that Client comes from NewDryRunClient; the writes it
+	// only logs get no Events.
+	DryRun bool
+}
+
+// hostnamePolicy returns the hostname rules the reconciler publishes under,
+// the same as the VirtualMachineInstanceReconciler's.
+func (r *VirtualMachineInstanceReplicaSetReconciler) hostnamePolicy() hostnamePolicy {
+	clusterDomain := r.ClusterDomain
+	if clusterDomain == "" {
+		clusterDomain = DefaultClusterDomain
+	}
+	return hostnamePolicy{
+		expandShort:     r.ExpandShortHostnames,
+		clusterDomain:   clusterDomain,
+		zoneAllowlist:   r.ZoneAllowlist,
+		zoneDenylist:    r.ZoneDenylist,
+		rejectWildcards: r.RejectWildcards,
+		recorder:        r.Recorder,
+	}
+}
+
+// settings returns the settings to use for the current reconcile.
+func (r *VirtualMachineInstanceReplicaSetReconciler) settings() ControllerSettings {
+	if r.Config == nil {
+		return DefaultSettings()
+	}
+	return r.Config.Get()
+}
+
+// +kubebuilder:rbac:groups=kubevirt.io,resources=virtualmachineinstancereplicasets,verbs=get;list;watch
+
+// Reconcile creates, updates or deletes the DNSEndpoint for a replica set.
+func (r *VirtualMachineInstanceReplicaSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	rs := &kubevirtv1.VirtualMachineInstanceReplicaSet{}
+	if err := r.Get(ctx, req.NamespacedName, rs); err != nil {
+		// A deleted replica set's DNSEndpoint is garbage-collected through its OwnerReference.
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	settings := r.settings()
+	hostname := strings.TrimSpace(rs.Annotations[settings.annotationKey(annotationHostname)])
+	if hostname == "" || !rs.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.deleteOwnedEndpoint(ctx, rs)
+	}
+
+	replicas, live, err := r.readyReplicas(ctx, rs)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if live == 0 {
+		// Scaled to zero: no replica is left to keep addresses for.
+		logger.Info("replica set has no replicas, deleting its DNSEndpoint", "vmirs", req.NamespacedName)
+		return ctrl.Result{}, r.deleteOwnedEndpoint(ctx, rs)
+	}
+
+	filter, filterErr := ipFilterFor(rs.Annotations, settings)
+	if filterErr != nil {
+		logger.Info("ignoring invalid entries in IP filter annotations", "vmirs", req.NamespacedName, "error", filterErr.Error())
+		countReconcileError(filterErr)
+	}
+	filter.publicOnly = r.PublicIPsOnly
+	var ipv4, ipv6 []string
+	for i := range replicas {
+		v4, v6, _ := extractBestIPs(&replicas[i], filter, r.IPSourcePriority)
+		ipv4 = append(ipv4, v4...)
+		ipv6 = append(ipv6, v6...)
+	}
+	ipv4, ipv6 = settings.filterIPFamily(deduplicateIPs(ipv4), deduplicateIPs(ipv6))
+	if r.IPv4Only && len(ipv6) > 0 {
+		logger.V(1).Info("suppressing IPv6 addresses because --ipv4-only is set", "vmirs", req.NamespacedName, "ipv6", ipv6)
+		ipv6 = nil
+	}
+	if r.IPv6Only && len(ipv4) > 0 {
+		logger.V(1).Info("suppressing IPv4 addresses because --ipv6-only is set", "vmirs", req.NamespacedName, "ipv4", ipv4)
+		ipv4 = nil
+	}
+
+	if len(ipv4) == 0 && len(ipv6) == 0 {
+		// Replicas are starting or restarting; keep the existing records until
+		// at least one reports an address again.
+		logger.Info("no ready replica reports IPs yet, skipping", "vmirs", req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	ttl, ttlErr := ttlFromAnnotation(rs.Annotations[settings.annotationKey(annotationTTL)], settings.DefaultTTL)
+	if ttlErr != nil {
+		logger.Info("ignoring TTL annotation, using default", "vmirs", req.NamespacedName, "error", ttlErr.Error(), "default", settings.DefaultTTL)
+		countReconcileError(ttlErr)
+	}
+	if clamped, ok := r.clampTTL(ttl); ok {
+		logger.Info("clamping TTL to the allowed range", "vmirs", req.NamespacedName, "requested", ttl, "clamped", clamped, "min", r.MinTTL, "max", r.MaxTTL)
+		ttl = clamped
+	}
+	hostnames := r.hostnamePolicy().filter(ctx, rs, "vmirs", parseHostnames(hostname),
+		strings.TrimSpace(rs.Annotations[settings.annotationKey(annotationHostnamePrefix)]),
+		strings.TrimSpace(rs.Annotations[settings.annotationKey(annotationHostnameSuffix)]))
+	hostnameTTLs, hostnameTTLErr := parsePerHostnameTTL(rs.Annotations, settings)
+	if hostnameTTLErr != nil {
+		logger.Info("ignoring invalid per-hostname TTL annotations", "vmirs", req.NamespacedName, "error", hostnameTTLErr.Error())
+		countReconcileError(hostnameTTLErr)
+	}
+	for h, hostTTL := range hostnameTTLs {
+		if clamped, ok := r.clampTTL(hostTTL); ok {
+			logger.Info("clamping per-hostname TTL to the allowed range", "vmirs", req.NamespacedName, "hostname", h, "requested", hostTTL, "clamped", clamped, "min", r.MinTTL, "max", r.MaxTTL)
+			hostnameTTLs[h] = clamped
+		}
+	}
+	endpoints := buildEndpoints(hostnames, ipv4, ipv6, ttl, hostnameTTLs, nil, "")
+
+	desired := &dnsendpointv1alpha1.DNSEndpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: rs.Name, Namespace: rs.Namespace},
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, desired, func() error {
+		desired.Spec = dnsendpointv1alpha1.DNSEndpointSpec{Endpoints: endpoints}
+		return controllerutil.SetControllerReference(rs, desired, r.Scheme)
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	switch {
+	case r.DryRun:
+		// Nothing was written; the dry-run client logged the write.
+	case op == controllerutil.OperationResultCreated:
+		r.Recorder.Eventf(rs, corev1.EventTypeNormal, eventReasonEndpointCreated, "Created DNSEndpoint %s", desired.Name)
+	case op == controllerutil.OperationResultUpdated:
+		r.Recorder.Eventf(rs, corev1.EventTypeNormal, eventReasonEndpointUpdated, "Updated DNSEndpoint %s", desired.Name)
+	}
+
+	logger.Info("reconciled DNSEndpoint", "vmirs", req.NamespacedName, "replicas", len(replicas), "operation", op)
+	return ctrl.Result{}, nil
+}
+
+// readyReplicas returns the VMIs controlled by rs whose Ready condition is
+// true, and the number of its replicas not being deleted, ready or not.
+func (r *VirtualMachineInstanceReplicaSetReconciler) readyReplicas(ctx context.Context, rs *kubevirtv1.VirtualMachineInstanceReplicaSet) ([]kubevirtv1.VirtualMachineInstance, int, error) {
+	list := &kubevirtv1.VirtualMachineInstanceList{}
+	if err := r.List(ctx, list, client.InNamespace(rs.Namespace)); err != nil {
+		return nil, 0, err
+	}
+	var ready []kubevirtv1.VirtualMachineInstance
+	live := 0
+	for _, vmi := range list.Items {
+		if !metav1.IsControlledBy(&vmi, rs) || !vmi.DeletionTimestamp.IsZero() {
+			continue
+		}
+		live++
+		if isVMIReady(&vmi) {
+			ready = append(ready, vmi)
+		}
+	}
+	return ready, live, nil
+}
+
+// isVMIReady reports whether the VMI's Ready condition is true.
+func isVMIReady(vmi *kubevirtv1.VirtualMachineInstance) bool {
+	for _, c := range vmi.Status.Conditions {
+		if c.Type == kubevirtv1.VirtualMachineInstanceReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// deleteOwnedEndpoint deletes the replica set's DNSEndpoint if the replica set controls it.
+func (r *VirtualMachineInstanceReplicaSetReconciler) deleteOwnedEndpoint(ctx context.Context, rs *kubevirtv1.VirtualMachineInstanceReplicaSet) error {
+	endpoint := &dnsendpointv1alpha1.DNSEndpoint{}
+	err := r.Get(ctx, client.ObjectKey{Name: rs.Name, Namespace: rs.Namespace}, endpoint)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(endpoint, rs) {
+		return nil
+	}
+	if err := client.IgnoreNotFound(r.Delete(ctx, endpoint)); err != nil {
+		return err
+	}
+	if r.DryRun {
+		return nil
+	}
+	r.Recorder.Eventf(rs, corev1.EventTypeNormal, eventReasonEndpointDeleted, "Deleted DNSEndpoint %s", endpoint.Name)
+	return nil
+}
+
+// SetupWithManager registers the replica set controller with the manager.
+// Replica VMI changes (IP updates, readiness, scale-down) requeue their replica set.
+func (r *VirtualMachineInstanceReplicaSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kubevirtv1.VirtualMachineInstanceReplicaSet{}).
+		Owns(&dnsendpointv1alpha1.DNSEndpoint{}).
+		Watches(&kubevirtv1.VirtualMachineInstance{},
+			handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(), &kubevirtv1.VirtualMachineInstanceReplicaSet{}, handler.OnlyControllerOwner())).
+		Complete(r)
+}
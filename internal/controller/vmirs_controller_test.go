@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// newTestReplicaSet returns a replica set in the default namespace.
+func newTestReplicaSet(name string, annotations map[string]string) *kubevirtv1.VirtualMachineInstanceReplicaSet {
+	return &kubevirtv1.VirtualMachineInstanceReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			UID:         types.UID(name + "-uid"),
+			Annotations: annotations,
+		},
+	}
+}
+
+// newTestReplica returns a VMI controlled by rs with the given multus IP.
+func newTestReplica(t *testing.T, rs *kubevirtv1.VirtualMachineInstanceReplicaSet, name, ip string, ready bool) *kubevirtv1.VirtualMachineInstance {
+	t.Helper()
+	vmi := newTestVMI(name, nil, kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: ip, InfoSource: multusInfoSource})
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	vmi.Status.Conditions = []kubevirtv1.VirtualMachineInstanceCondition{{Type: kubevirtv1.VirtualMachineInstanceReady, Status: status}}
+	if err := controllerutil.SetControllerReference(rs, vmi, newTestScheme(t)); err != nil {
+		t.Fatal(err)
+	}
+	return vmi
+}
+
+func newTestReplicaSetReconciler(t *testing.T, objs ...client.Object) *VirtualMachineInstanceReplicaSetReconciler {
+	t.Helper()
+	s := newTestScheme(t)
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+	return &VirtualMachineInstanceReplicaSetReconciler{Client: c, Scheme: s, Recorder: record.NewFakeRecorder(100)}
+}
+
+func reconcileReplicaSet(t *testing.T, r *VirtualMachineInstanceReplicaSetReconciler, name string) {
+	t.Helper()
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}}); err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+}
+
+// replicaSetTargets returns the A record targets of the replica set's DNSEndpoint.
+func replicaSetTargets(t *testing.T, r *VirtualMachineInstanceReplicaSetReconciler, name string) []string {
+	t.Helper()
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, ep); err != nil {
+		t.Fatalf("failed to get DNSEndpoint %s: %v", name, err)
+	}
+	if len(ep.Spec.Endpoints) != 1 {
+		t.Fatalf("expected one endpoint, got %v", ep.Spec.Endpoints)
+	}
+	return ep.Spec.Endpoints[0].Targets
+}
+
+func TestReplicaSetReconcile_AggregatesReadyReplicas(t *testing.T) {
+	rs := newTestReplicaSet("web", map[string]string{annotationHostname: "web.example.com"})
+	other := newTestReplicaSet("other", nil)
+	r := newTestReplicaSetReconciler(t, rs,
+		newTestReplica(t, rs, "web-a", "10.0.0.1", true),
+		newTestReplica(t, rs, "web-b", "10.0.0.2", true),
+		newTestReplica(t, rs, "web-c", "10.0.0.3", false),
+		newTestReplica(t, other, "other-a", "10.0.0.9", true),
+	)
+
+	reconcileReplicaSet(t, r, "web")
+
+	if got := replicaSetTargets(t, r, "web"); !slices.Equal(got, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("expected only ready replicas of web, got %v", got)
+	}
+}
+
+func TestReplicaSetReconcile_ScaleDown(t *testing.T) {
+	rs := newTestReplicaSet("web", map[string]string{annotationHostname: "web.example.com"})
+	gone := newTestReplica(t, rs, "web-b", "10.0.0.2", true)
+	r := newTestReplicaSetReconciler(t, rs, newTestReplica(t, rs, "web-a", "10.0.0.1", true), gone)
+
+	reconcileReplicaSet(t, r, "web")
+	if err := r.Delete(context.Background(), gone); err != nil {
+		t.Fatal(err)
+	}
+	reconcileReplicaSet(t, r, "web")
+
+	if got := replicaSetTargets(t, r, "web"); !slices.Equal(got, []string{"10.0.0.1"}) {
+		t.Errorf("expected the deleted replica's IP to be removed, got %v", got)
+	}
+}
+
+func TestReplicaSetReconcile_ScaleToZero(t *testing.T) {
+	rs := newTestReplicaSet("web", map[string]string{annotationHostname: "web.example.com"})
+	replica := newTestReplica(t, rs, "web-a", "10.0.0.1", true)
+	r := newTestReplicaSetReconciler(t, rs, replica)
+	reconcileReplicaSet(t, r, "web")
+
+	// A replica that is restarting keeps the records in place.
+	replica.Status.Conditions[0].Status = corev1.ConditionFalse
+	if err := r.Update(context.Background(), replica); err != nil {
+		t.Fatal(err)
+	}
+	reconcileReplicaSet(t, r, "web")
+	if got := replicaSetTargets(t, r, "web"); !slices.Equal(got, []string{"10.0.0.1"}) {
+		t.Errorf("expected the records to be kept while a replica remains, got %v", got)
+	}
+
+	if err := r.Delete(context.Background(), replica); err != nil {
+		t.Fatal(err)
+	}
+	reconcileReplicaSet(t, r, "web")
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the DNSEndpoint to be deleted once no replicas remain, got err=%v", err)
+	}
+}
+
+func TestReplicaSetReconcile_AnnotationRemoved(t *testing.T) {
+	rs := newTestReplicaSet("web", map[string]string{annotationHostname: "web.example.com"})
+	r := newTestReplicaSetReconciler(t, rs, newTestReplica(t, rs, "web-a", "10.0.0.1", true))
+
+	reconcileReplicaSet(t, r, "web")
+	current := &kubevirtv1.VirtualMachineInstanceReplicaSet{}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(rs), current); err != nil {
+		t.Fatal(err)
+	}
+	current.Annotations = nil
+	if err := r.Update(context.Background(), current); err != nil {
+		t.Fatal(err)
+	}
+	reconcileReplicaSet(t, r, "web")
+
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the DNSEndpoint to be deleted, got err=%v", err)
+	}
+}
+
+// getReplicaSetEndpoint returns the replica set's DNSEndpoint.
+func getReplicaSetEndpoint(t *testing.T, r *VirtualMachineInstanceReplicaSetReconciler, name string) *dnsendpointv1alpha1.DNSEndpoint {
+	t.Helper()
+	ep := &dnsendpointv1alpha1.DNSEndpoint{}
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, ep); err != nil {
+		t.Fatalf("failed to get DNSEndpoint %s: %v", name, err)
+	}
+	return ep
+}
+
+func TestReplicaSetReconcile_ZoneDenylist(t *testing.T) {
+	rs := newTestReplicaSet("web", map[string]string{annotationHostname: "web.example.com,web.internal.example.com"})
+	r := newTestReplicaSetReconciler(t, rs, newTestReplica(t, rs, "web-a", "10.0.0.1", true))
+	r.ZoneDenylist = []string{"internal.example.com"}
+
+	reconcileReplicaSet(t, r, "web")
+
+	var names []string
+	for _, e := range getReplicaSetEndpoint(t, r, "web").Spec.Endpoints {
+		names = append(names, e.DNSName)
+	}
+	if want := []string{"web.example.com"}; !slices.Equal(names, want) {
+		t.Errorf("DNS names = %v, want %v", names, want)
+	}
+}
+
+func TestReplicaSetReconcile_ClampsTTL(t *testing.T) {
+	rs := newTestReplicaSet("web", map[string]string{
+		annotationHostname:                 "web.example.com,api.example.com",
+		annotationTTL:                      "5",
+		annotationTTL + "-api.example.com": "604800",
+	})
+	r := newTestReplicaSetReconciler(t, rs, newTestReplica(t, rs, "web-a", "10.0.0.1", true))
+	r.MinTTL, r.MaxTTL = 30, 3600
+
+	reconcileReplicaSet(t, r, "web")
+
+	got := map[string]dnsendpointv1alpha1.TTL{}
+	for _, e := range getReplicaSetEndpoint(t, r, "web").Spec.Endpoints {
+		got[e.DNSName] = e.RecordTTL
+	}
+	if got["web.example.com"] != 30 || got["api.example.com"] != 3600 {
+		t.Errorf("expected the TTLs to be clamped to [30, 3600], got %v", got)
+	}
+}
+
+func TestReplicaSetReconcile_IPv6Only(t *testing.T) {
+	rs := newTestReplicaSet("web", map[string]string{annotationHostname: "web.example.com"})
+	r := newTestReplicaSetReconciler(t, rs, newTestReplica(t, rs, "web-a", "10.0.0.1", true))
+	r.IPv6Only = true
+
+	reconcileReplicaSet(t, r, "web")
+
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, &dnsendpointv1alpha1.DNSEndpoint{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected no DNSEndpoint without IPv6 addresses, got err=%v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/michaeltrip/external-dns-kubevirt/internal/feature"
+)
+
+// HostnameWebhookPath is the path the hostname normalizing webhook is served on.
+const HostnameWebhookPath = "/mutate-kubevirt-io-v1-virtualmachineinstance"
+
+// +kubebuilder:webhook:path=/mutate-kubevirt-io-v1-virtualmachineinstance,mutating=true,failurePolicy=ignore,sideEffects=None,groups=kubevirt.io,resources=virtualmachineinstances,verbs=create;update,versions=v1,name=hostname.external-dns.kubevirt.io,admissionReviewVersions=v1
+
+// HostnameNormalizer is a mutating admission handler that rewrites the hostname
+// annotation of VMIs into the form the controller publishes.
+type HostnameNormalizer struct {
+	// Config supplies the annotation prefix. When nil, DefaultSettings are used.
+	Config *ControllerConfig
+
+	decoder admission.Decoder
+}
+
+// NewHostnameNormalizer returns a HostnameNormalizer decoding objects with scheme.
+func NewHostnameNormalizer(scheme *runtime.Scheme, config *ControllerConfig) *HostnameNormalizer {
+	return &HostnameNormalizer{Config: config, decoder: admission.NewDecoder(scheme)}
+}
+
+// Handle patches the hostname annotation when normalizing changes it.
+func (h *HostnameNormalizer) Handle(_ context.Context, req admission.Request) admission.Response {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := h.decoder.Decode(req, vmi); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	settings := DefaultSettings()
+	if h.Config != nil {
+		settings = h.Config.Get()
+	}
+	key := settings.annotationKey(annotationHostname)
+	raw, ok := vmi.Annotations[key]
+	if !ok {
+		return admission.Allowed("no hostname annotation")
+	}
+	normalized := normalizeHostnames(raw)
+	if normalized == raw {
+		return admission.Allowed("hostname annotation already normalized")
+	}
+	return admission.Patched("normalized hostname annotation", jsonpatch.JsonPatchOperation{
+		Operation: "replace",
+		Path:      "/metadata/annotations/" + escapeJSONPointer(key),
+		Value:     normalized,
+	})
+}
+
+// normalizeHostnames rewrites the comma-separated hostnames in the form
+// parseHostnames produces (trimmed, lowercased, no trailing dots) and drops
+// duplicate entries. Template entries are not lowercased, since that would
+// break field references like {{ .Name }}.
+func normalizeHostnames(raw string) string {
+	var result []string
+	seen := map[string]bool{}
+	for _, h := range parseHostnames(raw) {
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		result = append(result, h)
+	}
+	return strings.Join(result, ",")
+}
+
+// HostnameValidatorWebhookPath is the path the hostname validating webhook is served on.
+const HostnameValidatorWebhookPath = "/validate-kubevirt-io-v1-virtualmachineinstance"
+
+// +kubebuilder:webhook:path=/validate-kubevirt-io-v1-virtualmachineinstance,mutating=false,failurePolicy=ignore,sideEffects=None,groups=kubevirt.io,resources=virtualmachineinstances,verbs=create;update,versions=v1,name=validate-hostname.external-dns.kubevirt.io,admissionReviewVersions=v1
+
+// HostnameValidator is a validating admission handler that rejects VMIs whose
+// hostname annotation the controller would not publish in full: entries that
+// are not valid FQDNs, duplicate entries, and entries in a denied zone.
+type HostnameValidator struct {
+	// Config supplies the annotation prefix. When nil, DefaultSettings are used.
+	Config *ControllerConfig
+	// ZoneDenylist rejects hostnames in these zones.
+	ZoneDenylist []string
+	// Features gates hostname templates. Nil enables the feature defaults.
+	Features *feature.FeatureGate
+
+	decoder admission.Decoder
+}
+
+// NewHostnameValidator returns a HostnameValidator decoding objects with scheme.
+func NewHostnameValidator(scheme *runtime.Scheme, config *ControllerConfig, zoneDenylist []string) *HostnameValidator {
+	return &HostnameValidator{Config: config, ZoneDenylist: zoneDenylist, decoder: admission.NewDecoder(scheme)}
+}
+
+// Handle denies the request when the hostname annotation has invalid entries.
+// Updates that leave the annotation unchanged, and objects being deleted, are
+// always allowed, so a VMI whose annotation became invalid under newer rules
+// can still be updated and its finalizers removed.
+func (h *HostnameValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	vmi := &kubevirtv1.VirtualMachineInstance{}
+	if err := h.decoder.Decode(req, vmi); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !vmi.DeletionTimestamp.IsZero() {
+		return admission.Allowed("object is being deleted")
+	}
+
+	settings := DefaultSettings()
+	if h.Config != nil {
+		settings = h.Config.Get()
+	}
+	key := settings.annotationKey(annotationHostname)
+	raw, ok := vmi.Annotations[key]
+	if !ok {
+		return admission.Allowed("no hostname annotation")
+	}
+	if req.Operation == admissionv1.Update {
+		old := &kubevirtv1.VirtualMachineInstance{}
+		if err := h.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if oldRaw, ok := old.Annotations[key]; ok && oldRaw == raw {
+			return admission.Allowed("hostname annotation unchanged")
+		}
+	}
+	if problems := h.validate(raw, vmi); len(problems) > 0 {
+		return admission.Denied(fmt.Sprintf("invalid %s annotation: %s", key, strings.Join(problems, "; ")))
+	}
+	return admission.Allowed("hostname annotation is valid")
+}
+
+// validate returns a description of every problem with the hostname entries
+// in raw. Template entries are rendered against vmi first.
+func (h *HostnameValidator) validate(raw string, vmi *kubevirtv1.VirtualMachineInstance) []string {
+	var problems []string
+	seen := map[string]bool{}
+	for _, entry := range parseHostnames(raw) {
+		hostname := entry
+		if isHostnameTemplate(entry) {
+			if !h.Features.Enabled(feature.FeatureTemplateHostnames) {
+				problems = append(problems, fmt.Sprintf("%q: %v", entry, errHostnameTemplatesDisabled))
+				continue
+			}
+			rendered, err := renderHostnameTemplate(entry, vmi)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%q: %v", entry, err))
+				continue
+			}
+			hostname = strings.ToLower(strings.TrimRight(rendered, "."))
+		}
+		switch err := validateFQDN(hostname); {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%q: %v", hostname, err))
+		case seen[hostname]:
+			problems = append(problems, fmt.Sprintf("%q: duplicate entry", hostname))
+		case inAnyZone(hostname, h.ZoneDenylist):
+			problems = append(problems, fmt.Sprintf("%q: in a denied zone", hostname))
+		}
+		seen[hostname] = true
+	}
+	return problems
+}
+
+// escapeJSONPointer escapes a map key for use in a JSON pointer (RFC 6901).
+func escapeJSONPointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
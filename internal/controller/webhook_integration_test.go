@@ -0,0 +1,153 @@
+//go:build integration
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// hostnameWebhookConfiguration registers HostnameNormalizer with envtest's API server.
+func hostnameWebhookConfiguration() *admissionregistrationv1.MutatingWebhookConfiguration {
+	return &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "external-dns-kubevirt"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{{
+			Name: "hostname.external-dns.kubevirt.io",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{Path: ptr.To(HostnameWebhookPath)},
+			},
+			Rules: []admissionregistrationv1.RuleWithOperations{{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{"kubevirt.io"},
+					APIVersions: []string{"v1"},
+					Resources:   []string{"virtualmachineinstances"},
+				},
+			}},
+			FailurePolicy:           ptr.To(admissionregistrationv1.Fail),
+			SideEffects:             ptr.To(admissionregistrationv1.SideEffectClassNone),
+			AdmissionReviewVersions: []string{"v1"},
+		}},
+	}
+}
+
+// hostnameValidatingWebhookConfiguration registers HostnameValidator with envtest's API server.
+func hostnameValidatingWebhookConfiguration() *admissionregistrationv1.ValidatingWebhookConfiguration {
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "external-dns-kubevirt"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name: "validate-hostname.external-dns.kubevirt.io",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{Path: ptr.To(HostnameValidatorWebhookPath)},
+			},
+			Rules: []admissionregistrationv1.RuleWithOperations{{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{"kubevirt.io"},
+					APIVersions: []string{"v1"},
+					Resources:   []string{"virtualmachineinstances"},
+				},
+			}},
+			FailurePolicy:           ptr.To(admissionregistrationv1.Fail),
+			SideEffects:             ptr.To(admissionregistrationv1.SideEffectClassNone),
+			AdmissionReviewVersions: []string{"v1"},
+		}},
+	}
+}
+
+func TestHostnameWebhook_Integration(t *testing.T) {
+	env := &envtest.Environment{
+		CRDs: []*apiextensionsv1.CustomResourceDefinition{vmiCRD()},
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			MutatingWebhooks: []*admissionregistrationv1.MutatingWebhookConfiguration{hostnameWebhookConfiguration()},
+		},
+	}
+	cfg := startTestEnv(t, env)
+
+	scheme := newTestScheme(t)
+	server := webhook.NewServer(webhook.Options{
+		Host:    env.WebhookInstallOptions.LocalServingHost,
+		Port:    env.WebhookInstallOptions.LocalServingPort,
+		CertDir: env.WebhookInstallOptions.LocalServingCertDir,
+	})
+	server.Register(HostnameWebhookPath, &webhook.Admission{Handler: NewHostnameNormalizer(scheme, nil)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = server.Start(ctx) }()
+	waitFor(t, 10*time.Second, "webhook server did not start", func() bool {
+		return server.StartedChecker()(nil) == nil
+	})
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vmi := newTestVMI("vm1", map[string]string{annotationHostname: " VM1.Example.com., vm1.example.com "})
+	vmi.UID = ""
+	if err := c.Create(ctx, vmi); err != nil {
+		t.Fatalf("failed to create VMI: %v", err)
+	}
+
+	if got := vmi.Annotations[annotationHostname]; got != "vm1.example.com" {
+		t.Errorf("expected normalized hostname annotation, got %q", got)
+	}
+}
+
+func TestHostnameValidatingWebhook_Integration(t *testing.T) {
+	env := &envtest.Environment{
+		CRDs: []*apiextensionsv1.CustomResourceDefinition{vmiCRD()},
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			ValidatingWebhooks: []*admissionregistrationv1.ValidatingWebhookConfiguration{hostnameValidatingWebhookConfiguration()},
+		},
+	}
+	cfg := startTestEnv(t, env)
+
+	scheme := newTestScheme(t)
+	server := webhook.NewServer(webhook.Options{
+		Host:    env.WebhookInstallOptions.LocalServingHost,
+		Port:    env.WebhookInstallOptions.LocalServingPort,
+		CertDir: env.WebhookInstallOptions.LocalServingCertDir,
+	})
+	server.Register(HostnameValidatorWebhookPath, &webhook.Admission{
+		Handler: NewHostnameValidator(scheme, nil, []string{"internal.example.com"}),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = server.Start(ctx) }()
+	waitFor(t, 10*time.Second, "webhook server did not start", func() bool {
+		return server.StartedChecker()(nil) == nil
+	})
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, hostname := range map[string]string{
+		"invalid":   "vm_1.example.com",
+		"duplicate": "vm1.example.com,vm1.example.com",
+		"denied":    "vm1.internal.example.com",
+	} {
+		vmi := newTestVMI(name, map[string]string{annotationHostname: hostname})
+		vmi.UID = ""
+		if err := c.Create(ctx, vmi); err == nil {
+			t.Errorf("%s: expected the VMI to be rejected", name)
+		}
+	}
+
+	vmi := newTestVMI("valid", map[string]string{annotationHostname: "vm1.example.com"})
+	vmi.UID = ""
+	if err := c.Create(ctx, vmi); err != nil {
+		t.Errorf("expected a valid hostname annotation to be admitted, got %v", err)
+	}
+}
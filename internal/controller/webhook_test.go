@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestNormalizeHostnames(t *testing.T) {
+	tests := []struct {
+		raw, want string
+	}{
+		{"vm.example.com", "vm.example.com"},
+		{" VM.Example.com. ", "vm.example.com"},
+		{"a.example.com, A.example.com., b.example.com", "a.example.com,b.example.com"},
+		{"a.example.com,,  ,", "a.example.com"},
+		{"{{ .Name }}.Example.com.", "{{ .Name }}.Example.com"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeHostnames(tt.raw); got != tt.want {
+			t.Errorf("normalizeHostnames(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+// admissionRequest builds a CREATE admission request for vmiObj.
+func admissionRequest(t *testing.T, vmiObj runtime.Object) admission.Request {
+	t.Helper()
+	raw, err := json.Marshal(vmiObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func TestHostnameNormalizer_Patches(t *testing.T) {
+	h := NewHostnameNormalizer(newTestScheme(t), nil)
+	vmi := newTestVMI("vm1", map[string]string{annotationHostname: "VM1.example.com., vm1.example.com"})
+
+	resp := h.Handle(context.Background(), admissionRequest(t, vmi))
+	if !resp.Allowed {
+		t.Fatalf("expected request to be allowed, got %v", resp.Result)
+	}
+	if len(resp.Patches) != 1 {
+		t.Fatalf("expected one patch, got %v", resp.Patches)
+	}
+	p := resp.Patches[0]
+	if p.Operation != "replace" || p.Path != "/metadata/annotations/external-dns.alpha.kubernetes.io~1hostname" || p.Value != "vm1.example.com" {
+		t.Errorf("unexpected patch: %+v", p)
+	}
+}
+
+func TestHostnameNormalizer_NoPatch(t *testing.T) {
+	h := NewHostnameNormalizer(newTestScheme(t), nil)
+
+	for _, annotations := range []map[string]string{
+		nil,
+		{annotationHostname: "vm1.example.com"},
+	} {
+		resp := h.Handle(context.Background(), admissionRequest(t, newTestVMI("vm1", annotations)))
+		if !resp.Allowed || len(resp.Patches) != 0 {
+			t.Errorf("annotations %v: expected allowed without patches, got allowed=%v patches=%v", annotations, resp.Allowed, resp.Patches)
+		}
+	}
+}
+
+func TestHostnameNormalizer_CustomPrefix(t *testing.T) {
+	settings := DefaultSettings()
+	settings.AnnotationPrefix = "dns.example.org/"
+	h := NewHostnameNormalizer(newTestScheme(t), NewControllerConfig(settings))
+	vmi := newTestVMI("vm1", map[string]string{"dns.example.org/hostname": "VM1.example.com"})
+
+	resp := h.Handle(context.Background(), admissionRequest(t, vmi))
+	if len(resp.Patches) != 1 || resp.Patches[0].Path != "/metadata/annotations/dns.example.org~1hostname" {
+		t.Errorf("unexpected patches: %v", resp.Patches)
+	}
+}
+
+func TestHostnameValidator(t *testing.T) {
+	h := NewHostnameValidator(newTestScheme(t), nil, []string{"internal.example.com"})
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		allowed     bool
+	}{
+		{"no annotation", nil, true},
+		{"valid", map[string]string{annotationHostname: "vm1.example.com, vm1.example.org."}, true},
+		{"template", map[string]string{annotationHostname: "{{ .Name }}.example.com"}, true},
+		{"invalid FQDN", map[string]string{annotationHostname: "vm_1.example.com"}, false},
+		{"duplicate", map[string]string{annotationHostname: "vm1.example.com,VM1.example.com."}, false},
+		{"duplicate after rendering", map[string]string{annotationHostname: "vm1.example.com,{{ .Name }}.example.com"}, false},
+		{"denied zone", map[string]string{annotationHostname: "vm1.internal.example.com"}, false},
+		{"template error", map[string]string{annotationHostname: "{{ .Labels.missing }}.example.com"}, false},
+	}
+	for _, tt := range tests {
+		resp := h.Handle(context.Background(), admissionRequest(t, newTestVMI("vm1", tt.annotations)))
+		if resp.Allowed != tt.allowed {
+			t.Errorf("%s: allowed = %v, want %v (%v)", tt.name, resp.Allowed, tt.allowed, resp.Result)
+		}
+	}
+}
+
+func TestHostnameValidator_ReportsEveryProblem(t *testing.T) {
+	h := NewHostnameValidator(newTestScheme(t), nil, []string{"internal.example.com"})
+	vmi := newTestVMI("vm1", map[string]string{annotationHostname: "-bad.example.com,vm1.internal.example.com"})
+
+	resp := h.Handle(context.Background(), admissionRequest(t, vmi))
+	if resp.Allowed {
+		t.Fatal("expected the request to be denied")
+	}
+	msg := resp.Result.Message
+	for _, want := range []string{`"-bad.example.com"`, `"vm1.internal.example.com": in a denied zone`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("denial message %q does not mention %s", msg, want)
+		}
+	}
+}
+
+// updateRequest builds an UPDATE admission request from oldObj to newObj.
+func updateRequest(t *testing.T, oldObj, newObj runtime.Object) admission.Request {
+	t.Helper()
+	req := admissionRequest(t, newObj)
+	raw, err := json.Marshal(oldObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Operation = admissionv1.Update
+	req.OldObject = runtime.RawExtension{Raw: raw}
+	return req
+}
+
+func TestHostnameValidator_Update(t *testing.T) {
+	h := NewHostnameValidator(newTestScheme(t), nil, []string{"internal.example.com"})
+	// Written before the webhook was enabled, or under an older denylist.
+	old := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.internal.example.com"})
+	old.Finalizers = []string{DefaultFinalizerName}
+
+	unchanged := old.DeepCopy()
+	unchanged.Finalizers = nil
+	if resp := h.Handle(context.Background(), updateRequest(t, old, unchanged)); !resp.Allowed {
+		t.Errorf("expected an update leaving the annotation unchanged to be allowed, got %v", resp.Result)
+	}
+
+	changed := old.DeepCopy()
+	changed.Annotations[annotationHostname] = "vm1.internal.example.com,vm_1.example.com"
+	if resp := h.Handle(context.Background(), updateRequest(t, old, changed)); resp.Allowed {
+		t.Error("expected an update changing the annotation to an invalid value to be denied")
+	}
+}
+
+func TestHostnameValidator_AllowsDeletingObjects(t *testing.T) {
+	h := NewHostnameValidator(newTestScheme(t), nil, nil)
+	old := newTestVMI("vm1", map[string]string{annotationHostname: "vm1.example.com"})
+	old.Finalizers = []string{DefaultFinalizerName}
+	deleting := newTestVMI("vm1", map[string]string{annotationHostname: "vm_1.example.com"})
+	deleting.DeletionTimestamp = ptr.To(metav1.Now())
+
+	if resp := h.Handle(context.Background(), updateRequest(t, old, deleting)); !resp.Allowed {
+		t.Errorf("expected objects being deleted to be allowed, got %v", resp.Result)
+	}
+}
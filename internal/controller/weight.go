@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	// annotationWeight publishes the VMI's records as weighted records with this
+	// weight (0-255), for providers such as Route 53 that support weighted routing.
+	annotationWeight = defaultAnnotationPrefix + "weight"
+	// providerSpecificWeight is the External-DNS provider-specific property
+	// holding the record weight.
+	providerSpecificWeight = "aws/weight"
+	// maxWeight is the largest accepted weight.
+	maxWeight = 255
+	// annotationSetIdentifier sets the External-DNS set identifier of the VMI's
+	// records, which tells apart record sets sharing a name under a routing policy.
+	annotationSetIdentifier = defaultAnnotationPrefix + "set-identifier"
+)
+
+// parseWeight validates the weight annotation and returns it in canonical
+// form. An empty value means the records are not weighted. Values that are not
+// integers between 0 and maxWeight yield an error wrapping errInvalidAnnotation.
+func parseWeight(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	weight, err := strconv.Atoi(raw)
+	if err != nil || weight < 0 || weight > maxWeight {
+		return "", fmt.Errorf("%w: weight %q is not an integer between 0 and %d", errInvalidAnnotation, raw, maxWeight)
+	}
+	return strconv.Itoa(weight), nil
+}
+
+// parseSetIdentifier returns the VMI's set-identifier annotation. A present but
+// empty annotation yields an error wrapping errInvalidAnnotation.
+func parseSetIdentifier(annotations map[string]string, settings ControllerSettings) (string, error) {
+	raw, ok := annotations[settings.annotationKey(annotationSetIdentifier)]
+	if !ok {
+		return "", nil
+	}
+	if raw = strings.TrimSpace(raw); raw == "" {
+		return "", fmt.Errorf("%w: empty set identifier", errInvalidAnnotation)
+	}
+	return raw, nil
+}
+
+// setIdentifierFor returns the set identifier of the VMI's records: the
+// set-identifier annotation if given, else the VMI's namespace/name for
+// weighted records, which need one to share a name with other weighted
+// records, else none.
+func setIdentifierFor(vmi *kubevirtv1.VirtualMachineInstance, setIdentifier, weight string) string {
+	if setIdentifier == "" && weight != "" {
+		return vmi.Namespace + "/" + vmi.Name
+	}
+	return setIdentifier
+}
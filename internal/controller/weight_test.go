@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestParseWeight(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "", want: ""},
+		{raw: "0", want: "0"},
+		{raw: " 100 ", want: "100"},
+		{raw: "255", want: "255"},
+		{raw: "007", want: "7"},
+		{raw: "256", wantErr: true},
+		{raw: "-1", wantErr: true},
+		{raw: "heavy", wantErr: true},
+		{raw: "1.5", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseWeight(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseWeight(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, errInvalidAnnotation) {
+			t.Errorf("parseWeight(%q): expected errInvalidAnnotation, got %v", tt.raw, err)
+		}
+		if got != tt.want {
+			t.Errorf("parseWeight(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+// endpointWeight returns the aws/weight property of ep, if any.
+func endpointWeight(ep *dnsendpointv1alpha1.Endpoint) (string, bool) {
+	return ep.GetProviderSpecificProperty(providerSpecificWeight)
+}
+
+func TestReconcile_Weight(t *testing.T) {
+	iface := kubevirtv1.VirtualMachineInstanceNetworkInterface{IPs: []string{"10.0.0.1", "2001:db8::1"}, InfoSource: guestAgentInfoSource}
+	vmi := newTestVMI("vm", map[string]string{annotationHostname: "vm.example.com", annotationWeight: "20"}, iface)
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	ep := getEndpoint(t, r, "vm")
+	if len(ep.Spec.Endpoints) != 2 {
+		t.Fatalf("expected A and AAAA endpoints, got %v", ep.Spec.Endpoints)
+	}
+	for _, e := range ep.Spec.Endpoints {
+		if weight, ok := endpointWeight(e); !ok || weight != "20" {
+			t.Errorf("%s %s: weight = %q, %v; want 20", e.DNSName, e.RecordType, weight, ok)
+		}
+		if e.SetIdentifier != "default/vm" {
+			t.Errorf("%s %s: set identifier = %q, want default/vm", e.DNSName, e.RecordType, e.SetIdentifier)
+		}
+	}
+}
+
+func TestReconcile_WeightInvalidOrMissing(t *testing.T) {
+	for _, annotations := range []map[string]string{
+		{annotationHostname: "vm.example.com"},
+		{annotationHostname: "vm.example.com", annotationWeight: "300"},
+	} {
+		vmi := newTestVMI("vm", annotations, kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+		r := newTestReconciler(t, vmi)
+
+		reconcileVMI(t, r, "vm")
+
+		for _, e := range getEndpoint(t, r, "vm").Spec.Endpoints {
+			if weight, ok := endpointWeight(e); ok || e.SetIdentifier != "" {
+				t.Errorf("weight annotation %q: expected an unweighted record, got weight %q and set identifier %q",
+					annotations[annotationWeight], weight, e.SetIdentifier)
+			}
+		}
+	}
+}
+
+func TestReconcile_WeightedRecordsShareHostname(t *testing.T) {
+	blue := newTestVMI("blue", map[string]string{annotationHostname: "app.example.com", annotationWeight: "90"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	green := newTestVMI("green", map[string]string{annotationHostname: "app.example.com", annotationWeight: "10"},
+		kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.2", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, blue, green)
+
+	reconcileVMI(t, r, "blue")
+	reconcileVMI(t, r, "green")
+
+	for name, want := range map[string]string{"blue": "90", "green": "10"} {
+		eps := getEndpoint(t, r, name).Spec.Endpoints
+		if len(eps) != 1 {
+			t.Fatalf("%s: expected one endpoint, got %v", name, eps)
+		}
+		if weight, _ := endpointWeight(eps[0]); weight != want {
+			t.Errorf("%s: weight = %q, want %q", name, weight, want)
+		}
+	}
+}
+
+func TestBuildEndpoints_SetIdentifier(t *testing.T) {
+	eps := buildEndpoints([]string{"vm.example.com"}, []string{"10.0.0.1"}, []string{"2001:db8::1"}, 300, nil, nil, "eu-west")
+	if len(eps) != 2 {
+		t.Fatalf("expected A and AAAA endpoints, got %v", eps)
+	}
+	for _, e := range eps {
+		if e.SetIdentifier != "eu-west" {
+			t.Errorf("%s %s: set identifier = %q, want eu-west", e.DNSName, e.RecordType, e.SetIdentifier)
+		}
+	}
+}
+
+func TestParseSetIdentifier(t *testing.T) {
+	settings := DefaultSettings()
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+		wantErr     bool
+	}{
+		{name: "missing"},
+		{name: "set", annotations: map[string]string{annotationSetIdentifier: " eu-west "}, want: "eu-west"},
+		{name: "empty", annotations: map[string]string{annotationSetIdentifier: " "}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSetIdentifier(tt.annotations, settings)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, errInvalidAnnotation) {
+			t.Errorf("%s: expected errInvalidAnnotation, got %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReconcile_SetIdentifier(t *testing.T) {
+	vmi := newTestVMI("vm", map[string]string{
+		annotationHostname:      "vm.example.com",
+		annotationSetIdentifier: "primary",
+		annotationWeight:        "10",
+	}, kubevirtv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.1", InfoSource: multusInfoSource})
+	r := newTestReconciler(t, vmi)
+
+	reconcileVMI(t, r, "vm")
+
+	for _, e := range getEndpoint(t, r, "vm").Spec.Endpoints {
+		if e.SetIdentifier != "primary" {
+			t.Errorf("%s %s: set identifier = %q, want the annotation value", e.DNSName, e.RecordType, e.SetIdentifier)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package feature gates controller features behind FEATURE_<NAME>
+// environment variables, so they can be switched on or off per deployment
+// without new flags.
+package feature
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Feature names. Each is toggled by FEATURE_<name>, e.g.
+// FEATURE_TEMPLATE_HOSTNAMES=true.
+const (
+	// FeatureTemplateHostnames renders Go templates in hostname entries. When
+	// disabled, template entries are skipped as invalid.
+	FeatureTemplateHostnames = "TEMPLATE_HOSTNAMES"
+	// FeatureSRIOVExtraction reads addresses from SR-IOV interfaces. When
+	// disabled, "sriov" is dropped from --ip-source-priority.
+	FeatureSRIOVExtraction = "SRIOV_EXTRACTION"
+	// FeaturePerNamespaceConfig merges the config ConfigMap in a VMI's
+	// namespace over the global settings.
+	FeaturePerNamespaceConfig = "PER_NAMESPACE_CONFIG"
+)
+
+// envPrefix prefixes the environment variable of every feature.
+const envPrefix = "FEATURE_"
+
+// defaults holds the state of each known feature when its variable is unset.
+var defaults = map[string]bool{
+	FeatureTemplateHostnames:  true,
+	FeatureSRIOVExtraction:    true,
+	FeaturePerNamespaceConfig: true,
+}
+
+// Known returns the names of the known features, sorted.
+func Known() []string {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// FeatureGate reports which features are enabled. The nil FeatureGate
+// enables the defaults.
+type FeatureGate struct {
+	overrides map[string]bool
+}
+
+// FromEnv builds a FeatureGate from environment entries in the "KEY=value"
+// form of os.Environ. FEATURE_<NAME> entries for unknown features, or whose
+// value strconv.ParseBool does not accept, are ignored and reported in the
+// returned warnings, so a stray variable cannot stop the controller.
+func FromEnv(environ []string) (*FeatureGate, []error) {
+	g := &FeatureGate{overrides: map[string]bool{}}
+	var warnings []error
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		name, isFeature := strings.CutPrefix(key, envPrefix)
+		if !ok || !isFeature || name == "" {
+			continue
+		}
+		if _, known := defaults[name]; !known {
+			warnings = append(warnings, fmt.Errorf("%s: unknown feature, ignored", key))
+			continue
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("%s=%q is not a boolean, using the default %t", key, value, defaults[name]))
+			continue
+		}
+		g.overrides[name] = enabled
+	}
+	return g, warnings
+}
+
+// Enabled reports whether featureName is enabled: its FEATURE_<NAME>
+// variable if set, else its default. Unknown features are disabled.
+func (g *FeatureGate) Enabled(featureName string) bool {
+	if g != nil {
+		if enabled, ok := g.overrides[featureName]; ok {
+			return enabled
+		}
+	}
+	return defaults[featureName]
+}
@@ -0,0 +1,94 @@
+package feature
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		environ  []string
+		want     map[string]bool
+		warnings int
+	}{
+		{
+			name: "defaults",
+			want: map[string]bool{
+				FeatureTemplateHostnames:  true,
+				FeatureSRIOVExtraction:    true,
+				FeaturePerNamespaceConfig: true,
+			},
+		},
+		{
+			name: "overrides",
+			environ: []string{
+				"FEATURE_TEMPLATE_HOSTNAMES=false",
+				"FEATURE_SRIOV_EXTRACTION=false",
+				"FEATURE_PER_NAMESPACE_CONFIG=0",
+			},
+			want: map[string]bool{
+				FeatureTemplateHostnames:  false,
+				FeatureSRIOVExtraction:    false,
+				FeaturePerNamespaceConfig: false,
+			},
+		},
+		{
+			name:    "unrelated variables are ignored",
+			environ: []string{"HOME=/root", "FEATURE_=true", "FEATURES=x", "SRIOV_EXTRACTION=false"},
+			want:    map[string]bool{FeatureSRIOVExtraction: true},
+		},
+		{
+			name:     "unknown features are ignored with a warning",
+			environ:  []string{"FEATURE_SOMETHING_NEW=true", "FEATURE_SRIOV_EXTRACTION=false"},
+			want:     map[string]bool{"SOMETHING_NEW": false, FeatureSRIOVExtraction: false},
+			warnings: 1,
+		},
+		{
+			name:    "surrounding space is trimmed",
+			environ: []string{"FEATURE_TEMPLATE_HOSTNAMES= False "},
+			want:    map[string]bool{FeatureTemplateHostnames: false},
+		},
+		{
+			name:     "non-boolean value keeps the default",
+			environ:  []string{"FEATURE_TEMPLATE_HOSTNAMES=yes"},
+			want:     map[string]bool{FeatureTemplateHostnames: true},
+			warnings: 1,
+		},
+		{
+			name:     "empty value keeps the default",
+			environ:  []string{"FEATURE_SRIOV_EXTRACTION=", "FEATURE_PER_NAMESPACE_CONFIG=off"},
+			want:     map[string]bool{FeatureSRIOVExtraction: true, FeaturePerNamespaceConfig: true},
+			warnings: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, warnings := FromEnv(tt.environ)
+			if len(warnings) != tt.warnings {
+				t.Errorf("expected %d warnings, got %v", tt.warnings, warnings)
+			}
+			for name, want := range tt.want {
+				if got := g.Enabled(name); got != want {
+					t.Errorf("Enabled(%q) = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFeatureGate_NilUsesDefaults(t *testing.T) {
+	var g *FeatureGate
+	for _, name := range Known() {
+		if got := g.Enabled(name); got != defaults[name] {
+			t.Errorf("Enabled(%q) = %v, want the default %v", name, got, defaults[name])
+		}
+	}
+}
+
+func TestKnown(t *testing.T) {
+	want := []string{FeaturePerNamespaceConfig, FeatureSRIOVExtraction, FeatureTemplateHostnames}
+	if got := Known(); !slices.Equal(got, want) {
+		t.Errorf("Known() = %v, want %v", got, want)
+	}
+}
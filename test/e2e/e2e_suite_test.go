@@ -0,0 +1,45 @@
+//go:build e2e
+
+// Package e2e tests the controller deployed in a real cluster with KubeVirt
+// and External-DNS installed. Run it through `make e2e`, which provisions a
+// kind cluster for it.
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/michaeltrip/external-dns-kubevirt/internal/controller"
+)
+
+// k8sClient talks to the cluster of the current kubeconfig context.
+var k8sClient client.Client
+
+func TestE2E(t *testing.T) {
+	RegisterFailHandler(Fail)
+	// Booting under emulation is slow; poll every few seconds.
+	SetDefaultEventuallyPollingInterval(5 * time.Second)
+	RunSpecs(t, "external-dns-kubevirt e2e")
+}
+
+var _ = BeforeSuite(func() {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(kubevirtv1.AddToScheme(scheme)).To(Succeed())
+	Expect(controller.AddDNSEndpointToScheme(scheme)).To(Succeed())
+
+	cfg, err := ctrl.GetConfig()
+	Expect(err).NotTo(HaveOccurred(), "failed to load kubeconfig")
+	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme})
+	Expect(err).NotTo(HaveOccurred())
+})
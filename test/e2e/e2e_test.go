@@ -0,0 +1,145 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	dnsendpointv1alpha1 "sigs.k8s.io/external-dns/endpoint"
+)
+
+// defaultVMIImage is a container disk that runs the QEMU guest agent, so the
+// VMI reports its addresses with the guest-agent infoSource.
+const defaultVMIImage = "quay.io/kubevirt/fedora-with-test-tooling-container-disk:v1.4.0"
+
+const hostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+
+// newVMI returns a small VMI on the pod network, booting image.
+func newVMI(namespace, name, image, hostname string) *kubevirtv1.VirtualMachineInstance {
+	return &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: map[string]string{hostnameAnnotation: hostname},
+		},
+		Spec: kubevirtv1.VirtualMachineInstanceSpec{
+			Domain: kubevirtv1.DomainSpec{
+				Resources: kubevirtv1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				},
+				Devices: kubevirtv1.Devices{
+					Disks: []kubevirtv1.Disk{{
+						Name:       "containerdisk",
+						DiskDevice: kubevirtv1.DiskDevice{Disk: &kubevirtv1.DiskTarget{Bus: kubevirtv1.DiskBusVirtio}},
+					}},
+					Interfaces: []kubevirtv1.Interface{*kubevirtv1.DefaultMasqueradeNetworkInterface()},
+				},
+			},
+			Networks: []kubevirtv1.Network{*kubevirtv1.DefaultPodNetwork()},
+			Volumes: []kubevirtv1.Volume{{
+				Name:         "containerdisk",
+				VolumeSource: kubevirtv1.VolumeSource{ContainerDisk: &kubevirtv1.ContainerDiskSource{Image: image}},
+			}},
+		},
+	}
+}
+
+// guestAgentIPv4 returns the sorted IPv4 addresses the guest agent reports
+// for the VMI.
+func guestAgentIPv4(vmi *kubevirtv1.VirtualMachineInstance) []string {
+	var ips []string
+	for _, iface := range vmi.Status.Interfaces {
+		if !strings.Contains(iface.InfoSource, "guest-agent") {
+			continue
+		}
+		for _, ip := range append([]string{iface.IP}, iface.IPs...) {
+			if ip != "" && !strings.Contains(ip, ":") && !slices.Contains(ips, ip) {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	slices.Sort(ips)
+	return ips
+}
+
+// aTargets returns the sorted targets of the A record for dnsName in ep.
+func aTargets(ep *dnsendpointv1alpha1.DNSEndpoint, dnsName string) []string {
+	for _, e := range ep.Spec.Endpoints {
+		if e.DNSName == dnsName && e.RecordType == "A" {
+			return slices.Sorted(slices.Values(e.Targets))
+		}
+	}
+	return nil
+}
+
+var _ = Describe("An annotated VMI", func() {
+	var (
+		ctx context.Context
+		ns  string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "e2e-"}}
+		Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+		ns = namespace.Name
+		DeferCleanup(func() {
+			_ = k8sClient.Delete(context.Background(), namespace)
+		})
+	})
+
+	It("is published to External-DNS and unpublished when deleted", func() {
+		image := os.Getenv("E2E_VMI_IMAGE")
+		if image == "" {
+			image = defaultVMIImage
+		}
+		vmi := newVMI(ns, "vm1", image, "vm1.e2e.example.com")
+		Expect(k8sClient.Create(ctx, vmi)).To(Succeed())
+		key := client.ObjectKeyFromObject(vmi)
+
+		By("waiting for the guest agent to report addresses")
+		var want []string
+		// Booting under emulation takes a while.
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, key, vmi)).To(Succeed())
+			g.Expect(vmi.Status.Phase).To(Equal(kubevirtv1.Running))
+			want = guestAgentIPv4(vmi)
+			g.Expect(want).NotTo(BeEmpty())
+		}).WithTimeout(15 * time.Minute).Should(Succeed())
+
+		By("waiting for the DNSEndpoint to publish them")
+		ep := &dnsendpointv1alpha1.DNSEndpoint{}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, key, ep)).To(Succeed())
+			g.Expect(aTargets(ep, "vm1.e2e.example.com")).To(Equal(want))
+		}).WithTimeout(2 * time.Minute).Should(Succeed())
+
+		By("waiting for External-DNS to process it")
+		// External-DNS records the generation it has processed.
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, key, ep)).To(Succeed())
+			g.Expect(ep.Status.ObservedGeneration).To(Equal(ep.Generation))
+		}).WithTimeout(2 * time.Minute).Should(Succeed())
+
+		By("deleting the VMI")
+		Expect(k8sClient.Delete(ctx, vmi)).To(Succeed())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, key, &dnsendpointv1alpha1.DNSEndpoint{}))
+		}).WithTimeout(5*time.Minute).Should(BeTrue(), "DNSEndpoint was not deleted with the VMI")
+	})
+})
@@ -0,0 +1,41 @@
+#!/usr/bin/env bash
+# Runs the e2e tests in a throwaway kind cluster with KubeVirt and External-DNS.
+# Set E2E_KEEP_CLUSTER=1 to keep the cluster for debugging.
+set -euo pipefail
+
+ROOT=$(cd "$(dirname "$0")/../.." && pwd)
+CLUSTER=${KIND_CLUSTER:-external-dns-kubevirt-e2e}
+KUBEVIRT_VERSION=${KUBEVIRT_VERSION:-v1.4.0}
+EXTERNAL_DNS_VERSION=${EXTERNAL_DNS_VERSION:-v0.15.1}
+IMG=external-dns-kubevirt:e2e
+
+cleanup() {
+	if [ -z "${E2E_KEEP_CLUSTER:-}" ]; then
+		kind delete cluster --name "$CLUSTER"
+	fi
+}
+trap cleanup EXIT
+
+kind create cluster --name "$CLUSTER" --wait 2m
+
+# kind nodes have no /dev/kvm, so KubeVirt runs VMIs under software emulation.
+kubectl apply -f "https://github.com/kubevirt/kubevirt/releases/download/${KUBEVIRT_VERSION}/kubevirt-operator.yaml"
+kubectl apply -f "https://github.com/kubevirt/kubevirt/releases/download/${KUBEVIRT_VERSION}/kubevirt-cr.yaml"
+kubectl -n kubevirt patch kubevirt kubevirt --type=merge \
+	-p '{"spec":{"configuration":{"developerConfiguration":{"useEmulation":true}}}}'
+kubectl -n kubevirt wait kubevirt/kubevirt --for=condition=Available --timeout=15m
+
+kubectl apply -f "https://raw.githubusercontent.com/kubernetes-sigs/external-dns/${EXTERNAL_DNS_VERSION}/docs/contributing/crd-source/crd-manifest.yaml"
+kubectl apply -f "$ROOT/test/e2e/external-dns.yaml"
+kubectl -n external-dns rollout status deployment/external-dns --timeout=5m
+
+docker build --build-arg VERSION=e2e -t "$IMG" "$ROOT"
+kind load docker-image "$IMG" --name "$CLUSTER"
+kubectl create namespace external-dns-kubevirt --dry-run=client -o yaml | kubectl apply -f -
+kubectl apply -f "$ROOT/deploy/rbac.yaml"
+kubectl apply -f "$ROOT/deploy/deployment.yaml"
+kubectl -n external-dns-kubevirt set image deployment/external-dns-kubevirt controller="$IMG"
+kubectl -n external-dns-kubevirt rollout status deployment/external-dns-kubevirt --timeout=5m
+
+cd "$ROOT"
+go test -tags e2e ./test/e2e -count=1 -timeout 30m -ginkgo.v